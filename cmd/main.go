@@ -2,30 +2,70 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+	"unicode"
 
 	"github.com/dzibukalexander/file-processing/internal/config"
 	"github.com/dzibukalexander/file-processing/internal/core"
-	"github.com/dzibukalexander/file-processing/internal/encryption/aes"
-	"github.com/dzibukalexander/file-processing/internal/encryption/rsa"
+	"github.com/dzibukalexander/file-processing/internal/detect"
 	"github.com/dzibukalexander/file-processing/internal/logger"
+	"github.com/dzibukalexander/file-processing/internal/secmem"
+	"github.com/dzibukalexander/file-processing/pkg/fileprocessing"
 )
 
 func main() {
-	if err := config.LoadConfig("config.json"); err != nil {
-		fmt.Printf("Error loading config: %v\n", err)
+	configPath := flag.String("config", "", "config file (JSON, YAML or TOML); defaults to $"+config.EnvPath+", then config.* in the user config directory and the current directory")
+	pipelinePath := flag.String("pipeline", "", "run this pipeline file or saved pipeline name non-interactively instead of starting the shell")
+	inputPath := flag.String("in", core.StdStream, "input file for -pipeline, or - for stdin")
+	outputPath := flag.String("out", core.StdStream, "output file for -pipeline, or - for stdout")
+	varsFile := flag.String("vars", "", "file with values for pipeline variables (JSON, YAML or NAME=value lines)")
+	vars := varFlags{}
+	flag.Var(vars, "var", "set a pipeline variable as NAME=value (repeatable)")
+	flag.Parse()
+
+	if err := config.LoadConfig(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if err := logger.SetupLogger(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error setting up logging: %v\n", err)
 		os.Exit(1)
 	}
-	logger.SetupLogger()
 	log := logger.GetInstance()
 
 	appCore := core.NewCore()
+	if *pipelinePath != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		err := runOnce(ctx, appCore, *pipelinePath, *inputPath, *outputPath, *varsFile, vars)
+		stop()
+		appCore.Close()
+		if err != nil {
+			log.Errorf("Run failed: %v", err)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	log.Info("Application started")
 	fmt.Println("File Processing CLI. Type 'exit' to quit.")
-	fmt.Println("Commands: load, apply, process, save-pipeline, load-pipeline, gen-key, exit")
+	fmt.Println("Commands: load, apply, list, remove, insert, move, edit, undo, redo, clear, process, run, step, peek, save-pipeline, load-pipeline, pipelines, checksum, encrypt-file, decrypt-file, gen-key, keys, rekey, clear-data, config, help, exit")
 	scanner := bufio.NewScanner(os.Stdin)
+	interrupts := newInterruptHandler()
 
 	for {
 		fmt.Print("> ")
@@ -38,7 +78,10 @@ func main() {
 		}
 
 		log.Infof("Executing command: %s", line)
-		if err := handleCommand(appCore, line); err != nil {
+		ctx, done := interrupts.commandContext()
+		err := handleCommand(ctx, appCore, line)
+		done()
+		if err != nil {
 			log.Errorf("Command failed: %v", err)
 			fmt.Printf("Error: %v\n", err)
 		}
@@ -50,11 +93,131 @@ func main() {
 	}
 
 	log.Info("Application shutting down")
+	appCore.Close()
 	fmt.Println("Exiting.")
 }
 
-func handleCommand(appCore *core.Core, line string) error {
-	parts := strings.Fields(line)
+// runOnce processes a single input with a saved pipeline so the tool can be
+// used as a filter in shell pipes.
+func runOnce(ctx context.Context, appCore *core.Core, pipelinePath, inputPath, outputPath, varsFile string, vars varFlags) error {
+	if outputPath == core.StdStream && config.AppConfig.EnableLogging && logsToStdout(config.AppConfig.Logging) {
+		// Keep stdout clean for the processed data.
+		logger.GetInstance().SetOutput(os.Stderr)
+	}
+	values, err := mergeVars(varsFile, vars)
+	if err != nil {
+		return err
+	}
+	return appCore.Run(ctx, pipelinePath, inputPath, outputPath, values)
+}
+
+// logsToStdout reports whether cfg sends logs to standard output.
+func logsToStdout(cfg config.LoggingConfig) bool {
+	return cfg.Output == "" || strings.EqualFold(cfg.Output, "stdout")
+}
+
+// varFlags collects repeated -var NAME=value flags.
+type varFlags map[string]string
+
+func (v varFlags) String() string {
+	return formatParams(v)
+}
+
+func (v varFlags) Set(value string) error {
+	name, val, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected NAME=value, got %q", value)
+	}
+	v[name] = val
+	return nil
+}
+
+// mergeVars combines values from a vars file with explicitly set variables,
+// which take precedence.
+func mergeVars(varsFile string, vars map[string]string) (map[string]string, error) {
+	values := map[string]string{}
+	if varsFile != "" {
+		fromFile, err := fileprocessing.ReadVarsFile(varsFile)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range fromFile {
+			values[k] = v
+		}
+	}
+	for k, v := range vars {
+		values[k] = v
+	}
+	return values, nil
+}
+
+// takeVars removes --vars <file> and --var NAME=value arguments from args and
+// returns the resulting variable values.
+func takeVars(args []string) (map[string]string, []string, error) {
+	varsFile, args, err := takeFlag(args, "--vars")
+	if err != nil {
+		return nil, nil, err
+	}
+	vars := varFlags{}
+	for slices.Contains(args, "--var") {
+		var value string
+		value, args, err = takeFlag(args, "--var")
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := vars.Set(value); err != nil {
+			return nil, nil, err
+		}
+	}
+	values, err := mergeVars(varsFile, vars)
+	return values, args, err
+}
+
+// interruptHandler turns Ctrl-C into cancellation of the running command
+// instead of terminating the shell.
+type interruptHandler struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+func newInterruptHandler() *interruptHandler {
+	h := &interruptHandler{}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		for range signals {
+			h.mu.Lock()
+			if h.cancel != nil {
+				h.cancel()
+				fmt.Println("\nInterrupting current command...")
+			} else {
+				fmt.Print("\n(type 'exit' to quit)\n> ")
+			}
+			h.mu.Unlock()
+		}
+	}()
+	return h
+}
+
+// commandContext returns a context that is cancelled by Ctrl-C until done is called.
+func (h *interruptHandler) commandContext() (ctx context.Context, done func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.mu.Lock()
+	h.cancel = cancel
+	h.mu.Unlock()
+	return ctx, func() {
+		h.mu.Lock()
+		h.cancel = nil
+		h.mu.Unlock()
+		cancel()
+	}
+}
+
+func handleCommand(ctx context.Context, appCore *core.Core, line string) error {
+	parts, err := splitArgs(line)
+	if err != nil {
+		return err
+	}
 	if len(parts) == 0 {
 		return nil
 	}
@@ -70,71 +233,870 @@ func handleCommand(appCore *core.Core, line string) error {
 		if len(args) != 1 {
 			return fmt.Errorf("load command requires a file path")
 		}
+		if args[0] == core.StdStream {
+			return fmt.Errorf("stdin is used for commands in the shell; use the -pipeline and -in flags to read stdin")
+		}
 		return appCore.Load(args[0])
 	case "process":
-		if len(args) != 1 {
+		keepDir, args, err := takeFlag(args, "--keep-intermediate")
+		if err != nil {
+			return err
+		}
+		if len(args) < 1 {
 			return fmt.Errorf("process command requires an output file path")
 		}
-		return appCore.ProcessFile(args[0])
+		params, err := parseParams(args[1:])
+		if err != nil {
+			return err
+		}
+		if keepDir != "" {
+			params[core.KeepIntermediateParam] = keepDir
+		}
+		return appCore.ProcessFile(ctx, args[0], params)
+	case "step":
+		if len(args) == 1 && strings.ToLower(args[0]) == "reset" {
+			appCore.ResetStepping()
+			fmt.Println("Stepping reset to the loaded data.")
+			return nil
+		}
+		if len(args) != 0 {
+			return fmt.Errorf("usage: step [reset]")
+		}
+		result, err := appCore.Step(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Step %d/%d %s %v: %d -> %d bytes\n", result.Index, result.Total,
+			result.Operation.Name, result.Operation.Params, result.InputSize, result.OutputSize)
+		return nil
+	case "peek":
+		limit := 256
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("peek takes a positive number of bytes to preview")
+			}
+			limit = n
+		} else if len(args) > 1 {
+			return fmt.Errorf("usage: peek [bytes]")
+		}
+		info, err := appCore.Peek()
+		if err != nil {
+			return err
+		}
+		printPeek(info, limit)
+		return nil
 	case "save-pipeline":
-		if len(args) != 1 {
+		if len(args) < 1 {
 			return fmt.Errorf("save-pipeline command requires a file path")
 		}
-		return appCore.SavePipeline(args[0])
+		params, err := parseParams(args[1:])
+		if err != nil {
+			return err
+		}
+		return appCore.SavePipeline(args[0], params)
 	case "load-pipeline":
+		vars, args, err := takeVars(args)
+		if err != nil {
+			return err
+		}
 		if len(args) != 1 {
 			return fmt.Errorf("load-pipeline command requires a file path")
 		}
-		return appCore.LoadPipeline(args[0])
+		return appCore.LoadPipeline(args[0], vars)
+	case "run":
+		vars, args, err := takeVars(args)
+		if err != nil {
+			return err
+		}
+		if len(args) != 3 {
+			return fmt.Errorf("usage: run <pipeline_file|name> <input_path> <output_path> [--vars file] [--var NAME=value ...]")
+		}
+		if args[1] == core.StdStream {
+			return fmt.Errorf("stdin is used for commands in the shell; use the -pipeline and -in flags to read stdin")
+		}
+		return appCore.Run(ctx, args[0], args[1], args[2], vars)
+	case "pipelines":
+		return handlePipelines(appCore, args)
+	case "checksum":
+		return handleChecksum(ctx, args)
+	case "encrypt-file", "decrypt-file":
+		return handleStreamFile(ctx, command, args)
+	case "keys":
+		return handleKeys(appCore, args)
+	case "rekey":
+		return handleRekey(ctx, appCore, args)
+	case "config":
+		return handleConfig(appCore, args)
 	case "apply":
 		if len(args) < 1 {
 			return fmt.Errorf("apply command requires an operation type")
 		}
 		op := strings.ToLower(args[0])
-		params := make(map[string]string)
-		for _, arg := range args[1:] {
-			parts := strings.SplitN(arg, "=", 2)
-			if len(parts) != 2 {
-				return fmt.Errorf("invalid parameter format: %s", arg)
-			}
-			params[parts[0]] = parts[1]
+		params, err := parseParams(args[1:])
+		if err != nil {
+			return err
 		}
 		return appCore.Apply(op, params)
+	case "list":
+		info := appCore.PipelineInfo()
+		if info.Name != "" {
+			fmt.Printf("Pipeline: %s\n", info.Name)
+		}
+		if info.Description != "" {
+			fmt.Printf("          %s\n", info.Description)
+		}
+		if len(info.Tags) > 0 {
+			fmt.Printf("Tags:     %s\n", strings.Join(info.Tags, ", "))
+		}
+		printSteps(appCore.Steps())
+		return nil
+	case "remove":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: remove <n>")
+		}
+		n, err := parsePosition(args[0])
+		if err != nil {
+			return err
+		}
+		return appCore.RemoveStep(n)
+	case "insert":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: insert <n> <operation> [params...]")
+		}
+		n, err := parsePosition(args[0])
+		if err != nil {
+			return err
+		}
+		params, err := parseParams(args[2:])
+		if err != nil {
+			return err
+		}
+		return appCore.InsertStep(n, strings.ToLower(args[1]), params)
+	case "move":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: move <from> <to>")
+		}
+		from, err := parsePosition(args[0])
+		if err != nil {
+			return err
+		}
+		to, err := parsePosition(args[1])
+		if err != nil {
+			return err
+		}
+		return appCore.MoveStep(from, to)
+	case "edit":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: edit <n> key=value [key=value...]")
+		}
+		n, err := parsePosition(args[0])
+		if err != nil {
+			return err
+		}
+		params, err := parseParams(args[1:])
+		if err != nil {
+			return err
+		}
+		return appCore.EditStep(n, params)
+	case "undo":
+		return appCore.Undo()
+	case "redo":
+		return appCore.Redo()
+	case "clear":
+		appCore.ClearPipeline()
+		return nil
+	case "clear-data":
+		appCore.ClearData()
+		fmt.Println("Loaded data wiped from memory.")
+		return nil
 	case "gen-key":
+		if len(args) < 2 {
+			return fmt.Errorf("gen-key command requires algorithm and path")
+		}
+		name := strings.ToLower(args[0])
+		if name == string(fileprocessing.RSA) && len(args) > 2 {
+			return handleGenRSAKey(args[1], args[2:])
+		}
 		if len(args) != 2 {
 			return fmt.Errorf("gen-key command requires algorithm and path")
 		}
-		alg := strings.ToLower(args[0])
-		path := args[1]
+		if sig, err := fileprocessing.ParseSignature(name); err == nil {
+			return fileprocessing.GenerateSigningKey(sig, args[1])
+		}
+		alg, err := fileprocessing.ParseEncryption(name)
+		if err != nil {
+			return fmt.Errorf("unsupported algorithm for key generation: %s", args[0])
+		}
+		return fileprocessing.GenerateKey(alg, args[1])
+	default:
+		return fmt.Errorf("unknown command: %s", command)
+	}
+}
 
-		switch alg {
-		case "aes":
-			generator := aes.AESEncryptor{}
-			return generator.GenerateKey(path)
-		case "rsa":
-			generator := rsa.RSAEncryptor{}
-			return generator.GenerateKey(path)
+// handleGenRSAKey generates an RSA key pair with the bits= and
+// passphrase_file= options of gen-key rsa.
+func handleGenRSAKey(path string, args []string) error {
+	params, err := parseParams(args)
+	if err != nil {
+		return err
+	}
+	bits := 3072
+	var passphrase []byte
+	defer func() { secmem.Wipe(passphrase) }()
+	for key, value := range params {
+		switch key {
+		case "bits":
+			if bits, err = strconv.Atoi(value); err != nil {
+				return fmt.Errorf("invalid bits: %s", value)
+			}
+		case "passphrase_file":
+			if passphrase, err = os.ReadFile(value); err != nil {
+				return fmt.Errorf("failed to read passphrase file: %w", err)
+			}
 		default:
-			return fmt.Errorf("unsupported algorithm for key generation: %s", alg)
+			return fmt.Errorf("unknown gen-key rsa option: %s", key)
+		}
+	}
+	return fileprocessing.GenerateRSAKey(path, bits, passphrase)
+}
+
+// handlePipelines runs the "pipelines" subcommands that manage the catalog
+// of saved pipelines.
+func handlePipelines(appCore *core.Core, args []string) error {
+	cat := appCore.Catalog()
+	if cat == nil {
+		return fmt.Errorf("no pipeline catalog is available")
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("usage: pipelines <list|show|save|delete> [args...]")
+	}
+
+	sub, args := strings.ToLower(args[0]), args[1:]
+	switch sub {
+	case "list":
+		tag, query := "", []string{}
+		for _, arg := range args {
+			if value, ok := strings.CutPrefix(arg, "tag="); ok {
+				tag = value
+			} else {
+				query = append(query, arg)
+			}
+		}
+		entries, err := cat.Search(tag, strings.Join(query, " "))
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Printf("No saved pipelines found in %s.\n", cat.Dir())
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tTAGS\tDESCRIPTION")
+		for _, entry := range entries {
+			if entry.Err != nil {
+				fmt.Fprintf(w, "%s\t\t(unreadable: %v)\n", entry.Name, entry.Err)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", entry.Name, strings.Join(entry.Document.Tags, ","), entry.Document.Description)
+		}
+		return w.Flush()
+	case "show":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: pipelines show <name>")
+		}
+		path, err := cat.Lookup(args[0])
+		if err != nil {
+			return err
+		}
+		doc, err := fileprocessing.ReadDocument(path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Name:        %s\n", doc.Name)
+		fmt.Printf("Path:        %s\n", path)
+		if doc.Description != "" {
+			fmt.Printf("Description: %s\n", doc.Description)
+		}
+		if doc.Author != "" {
+			fmt.Printf("Author:      %s\n", doc.Author)
+		}
+		if len(doc.Tags) > 0 {
+			fmt.Printf("Tags:        %s\n", strings.Join(doc.Tags, ", "))
+		}
+		if !doc.Created.IsZero() {
+			fmt.Printf("Created:     %s\n", doc.Created.Format(time.RFC3339))
+		}
+		for _, v := range doc.Variables {
+			line := "Variable:    " + v.Name
+			if v.Default != nil {
+				line += " (default " + strconv.Quote(*v.Default) + ")"
+			}
+			if v.Description != "" {
+				line += " - " + v.Description
+			}
+			fmt.Println(line)
+		}
+		printSteps(doc.Operations)
+		return nil
+	case "save":
+		if len(args) < 1 {
+			return fmt.Errorf("usage: pipelines save <name> [description=...] [author=...] [tags=a,b]")
+		}
+		params, err := parseParams(args[1:])
+		if err != nil {
+			return err
+		}
+		if err := appCore.SaveToCatalog(args[0], params); err != nil {
+			return err
+		}
+		fmt.Printf("Saved pipeline %s.\n", args[0])
+		return nil
+	case "delete":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: pipelines delete <name>")
+		}
+		if err := cat.Delete(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted pipeline %s.\n", args[0])
+		return nil
+	default:
+		return fmt.Errorf("unknown pipelines command: %s", sub)
+	}
+}
+
+// handleKeys runs the "keys" subcommands that manage the keyring of named
+// keys.
+func handleKeys(appCore *core.Core, args []string) error {
+	kr := appCore.Keyring()
+	if kr == nil {
+		return fmt.Errorf("no keyring is available")
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("usage: keys <list|show|generate|import|export|delete|split|combine> [args...]")
+	}
+
+	sub, args := strings.ToLower(args[0]), args[1:]
+	switch sub {
+	case "list":
+		keys, err := kr.List()
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			fmt.Printf("No keys found in %s.\n", kr.Dir())
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tALGORITHM\tKIND\tFINGERPRINT\tCREATED")
+		for _, key := range keys {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", key.Name, key.Algorithm, key.Kind(), key.Fingerprint, key.Created.Format(time.RFC3339))
+		}
+		return w.Flush()
+	case "show":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: keys show <name>")
+		}
+		key, err := kr.Get(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Name:        %s\n", key.Name)
+		fmt.Printf("Algorithm:   %s\n", key.Algorithm)
+		fmt.Printf("Kind:        %s\n", key.Kind())
+		fmt.Printf("Fingerprint: %s\n", key.Fingerprint)
+		fmt.Printf("Created:     %s\n", key.Created.Format(time.RFC3339))
+		if key.Public != nil {
+			fmt.Printf("Public key:\n%s", key.Public)
+		}
+		return nil
+	case "generate":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: keys generate <name> <algorithm>")
+		}
+		key, err := appCore.GenerateKey(args[0], strings.ToLower(args[1]))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Generated %s key %s (%s).\n", key.Algorithm, key.Name, key.Fingerprint)
+		return nil
+	case "import":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: keys import <name> <algorithm> <path>")
+		}
+		key, err := appCore.ImportKey(args[0], strings.ToLower(args[1]), args[2])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %s key %s (%s, %s).\n", key.Algorithm, key.Name, key.Kind(), key.Fingerprint)
+		return nil
+	case "export":
+		public := slices.Contains(args, "--public")
+		args = slices.DeleteFunc(slices.Clone(args), func(arg string) bool { return arg == "--public" })
+		if len(args) != 2 {
+			return fmt.Errorf("usage: keys export <name> <path> [--public]")
+		}
+		files, err := appCore.ExportKey(args[0], args[1], public)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Exported key %s to %s.\n", args[0], strings.Join(files, ", "))
+		return nil
+	case "delete":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: keys delete <name>")
+		}
+		if err := kr.Delete(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted key %s.\n", args[0])
+		return nil
+	case "split":
+		if len(args) < 1 {
+			return fmt.Errorf("usage: keys split <name|key_file> n=<shares> k=<threshold> [out=<dir>] [type=<algorithm>]")
 		}
+		params, err := parseParams(args[1:])
+		if err != nil {
+			return err
+		}
+		n, errN := strconv.Atoi(params["n"])
+		k, errK := strconv.Atoi(params["k"])
+		if errN != nil || errK != nil {
+			return fmt.Errorf("keys split requires numeric n=<shares> and k=<threshold>")
+		}
+		dir := params["out"]
+		if dir == "" {
+			dir = "."
+		}
+		files, err := appCore.SplitKey(args[0], n, k, dir, strings.ToLower(params["type"]))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Split %s into %d shares; any %d of them rebuild it:\n", args[0], n, k)
+		for _, file := range files {
+			fmt.Printf("  %s\n", file)
+		}
+		return nil
+	case "combine":
+		var files []string
+		params := map[string]string{}
+		for _, arg := range args {
+			if key, value, ok := strings.Cut(arg, "="); ok && (key == "out" || key == "name") {
+				params[key] = value
+			} else {
+				files = append(files, arg)
+			}
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("usage: keys combine <share files...> [name=<name>] [out=<key_file>]")
+		}
+		key, err := appCore.CombineShares(files, params["out"], params["name"])
+		if err != nil {
+			return err
+		}
+		if params["out"] != "" {
+			fmt.Printf("Rebuilt %s key (%s) into %s.\n", key.Algorithm, key.Fingerprint, params["out"])
+		}
+		if params["out"] == "" || params["name"] != "" {
+			fmt.Printf("Rebuilt %s key %s (%s) into the keyring.\n", key.Algorithm, key.Name, key.Fingerprint)
+		}
+		return nil
 	default:
-		return fmt.Errorf("unknown command: %s", command)
+		return fmt.Errorf("unknown keys command: %s", sub)
+	}
+}
+
+// handleRekey decrypts files with one keyring key and encrypts them again
+// with another.
+func handleRekey(ctx context.Context, appCore *core.Core, args []string) error {
+	var from, to string
+	var files []string
+	for _, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "from="); ok {
+			from = value
+		} else if value, ok := strings.CutPrefix(arg, "to="); ok {
+			to = value
+		} else {
+			files = append(files, arg)
+		}
+	}
+	if from == "" || to == "" || len(files) == 0 {
+		return fmt.Errorf("usage: rekey from=<key> to=<key> <file...>")
+	}
+	done, err := appCore.Rekey(ctx, files, from, to)
+	fmt.Printf("Rekeyed %d of %d files from %s to %s.\n", done, len(files), from, to)
+	return err
+}
+
+// handleConfig shows the effective configuration and where each value came
+// from.
+func handleConfig(appCore *core.Core, args []string) error {
+	if len(args) != 1 || args[0] != "show" {
+		return fmt.Errorf("usage: config show")
+	}
+	cfg := config.AppConfig
+	if cfg.Path != "" {
+		fmt.Printf("Config file: %s\n", cfg.Path)
+	} else {
+		fmt.Println("Config file: none")
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE")
+	for _, s := range cfg.Settings() {
+		value := s.Value
+		if value == "" {
+			value = "(not set)"
+			if s.Key == "defaults.key_dir" && appCore.Keyring() != nil {
+				value = appCore.Keyring().Dir()
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, value, s.Source)
+	}
+	return w.Flush()
+}
+
+// handleChecksum prints digests of files in sha256sum format or, with -c,
+// checks the files listed in such a checksum file.
+func handleChecksum(ctx context.Context, args []string) error {
+	check := slices.Contains(args, "-c")
+	args = slices.DeleteFunc(args, func(arg string) bool { return arg == "-c" })
+	var algo fileprocessing.HashAlgorithm
+	var files []string
+	for _, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "algo="); ok {
+			parsed, err := fileprocessing.ParseHashAlgorithm(strings.ToLower(value))
+			if err != nil {
+				return err
+			}
+			algo = parsed
+		} else {
+			files = append(files, arg)
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("usage: checksum [algo=sha256|sha512|blake2b|crc32] <file...> | checksum -c <checksum_file>")
+	}
+
+	if !check {
+		for _, file := range files {
+			line, err := fileprocessing.Checksum(ctx, algo, file)
+			if err != nil {
+				return err
+			}
+			fmt.Print(line)
+		}
+		return nil
+	}
+
+	failed := 0
+	for _, file := range files {
+		results, err := fileprocessing.VerifyChecksums(ctx, algo, file)
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			if result.OK() {
+				fmt.Printf("%s: OK\n", result.Name)
+				continue
+			}
+			failed++
+			if errors.Is(result.Err, fileprocessing.ErrChecksumMismatch) {
+				fmt.Printf("%s: FAILED\n", result.Name)
+			} else {
+				fmt.Printf("%s: FAILED open or read (%v)\n", result.Name, result.Err)
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d computed checksum(s) did NOT match", failed)
+	}
+	return nil
+}
+
+// handleStreamFile encrypts or decrypts a file chunk by chunk in the
+// aes-gcm-stream format, so files larger than memory can be processed.
+func handleStreamFile(ctx context.Context, command string, args []string) error {
+	params, err := parseParams(args[min(len(args), 3):])
+	if len(args) < 3 || err != nil {
+		return fmt.Errorf("usage: %s <key_file> <input> <output> [chunk_size=<bytes>]", command)
+	}
+	key, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+	defer secmem.Wipe(key)
+	if command == "decrypt-file" {
+		return fileprocessing.DecryptFile(ctx, args[1], args[2], key)
+	}
+	chunkSize := 0
+	if value, ok := params["chunk_size"]; ok {
+		if chunkSize, err = strconv.Atoi(value); err != nil || chunkSize < 1 {
+			return fmt.Errorf("invalid chunk_size: %s", value)
+		}
+	}
+	return fileprocessing.EncryptFile(ctx, args[1], args[2], key, chunkSize)
+}
+
+// takeFlag removes "name <value>" from args and returns the value, or "" if
+// the flag is absent.
+func takeFlag(args []string, name string) (string, []string, error) {
+	for i, arg := range args {
+		if arg != name {
+			continue
+		}
+		if i+1 >= len(args) {
+			return "", nil, fmt.Errorf("%s requires a value", name)
+		}
+		rest := append(append([]string{}, args[:i]...), args[i+2:]...)
+		return args[i+1], rest, nil
+	}
+	return "", args, nil
+}
+
+func printPeek(info *core.PeekInfo, limit int) {
+	if info.Step == 0 {
+		fmt.Println("Buffer:  loaded data (no steps executed)")
+	} else {
+		fmt.Printf("Buffer:  after step %d of %d\n", info.Step, info.Total)
+	}
+	fmt.Printf("Size:    %d bytes\n", info.Size)
+	fmt.Printf("Entropy: %.3f bits/byte\n", info.Entropy)
+	fmt.Printf("Type:    %s\n", info.Type)
+
+	preview := info.Data
+	if len(preview) > limit {
+		preview = preview[:limit]
+	}
+	if detect.IsText(preview) {
+		fmt.Println(string(preview))
+	} else {
+		fmt.Print(hex.Dump(preview))
+	}
+	if len(info.Data) > limit {
+		fmt.Printf("... %d more bytes\n", len(info.Data)-limit)
+	}
+}
+
+func parsePosition(arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid step number: %s", arg)
+	}
+	return n, nil
+}
+
+// printSteps renders the pipeline as a numbered table.
+func printSteps(steps []*core.Operation) {
+	if len(steps) == 0 {
+		fmt.Println("Pipeline is empty.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tOPERATION\tPARAMS")
+	for i, op := range steps {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", i+1, op.Name, formatParams(op.Params))
+		printNested(w, op, 1)
+	}
+	w.Flush()
+}
+
+// printNested lists the steps inside a control-flow operation, indented by depth.
+func printNested(w io.Writer, op *core.Operation, depth int) {
+	printBranch(w, op.Steps, depth)
+	if len(op.Else) > 0 {
+		label := "else:"
+		if op.Name == "try" {
+			label = "fallback:"
+		}
+		fmt.Fprintf(w, "\t%s%s\t\n", strings.Repeat("  ", depth-1), label)
+		printBranch(w, op.Else, depth)
+	}
+}
+
+func printBranch(w io.Writer, ops []*core.Operation, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, child := range ops {
+		fmt.Fprintf(w, "\t%s%s\t%s\n", indent, child.Name, formatParams(child.Params))
+		printNested(w, child, depth+1)
+	}
+}
+
+// formatParams renders params as sorted key=value pairs.
+func formatParams(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + params[k]
+	}
+	return strings.Join(pairs, " ")
+}
+
+// splitArgs splits a command line on whitespace, keeping text inside single
+// or double quotes together so values such as descriptions can contain spaces.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in command")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// parseParams converts "key=value" arguments into a parameter map.
+func parseParams(args []string) (map[string]string, error) {
+	params := make(map[string]string)
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid parameter format: %s", arg)
+		}
+		params[parts[0]] = parts[1]
 	}
+	return params, nil
 }
 
 func printHelp() {
 	fmt.Println("Available commands:")
 	fmt.Println("  load <file_path>              - Load a file to process.")
 	fmt.Println("  apply <operation> [params...] - Add a processing step to the pipeline.")
-	fmt.Println("    compress type=<zip|gzip>")
+	fmt.Println("  apply if <predicates...>    - Start a block run only when all predicates hold: type=json, compression=gzip|zip|none, size_gt=N, size_lt=N, match=<regex>, not=true.")
+	fmt.Println("  apply try                   - Start a block whose failure runs the fallback branch on the original data.")
+	fmt.Println("  apply else | apply fallback - Switch the open if/try block to its alternative branch.")
+	fmt.Println("  apply end                   - Close the innermost if/try block.")
+	fmt.Println("  apply tee path=<file>       - Write the current data to a file and pass it on unchanged.")
+	fmt.Println("  apply hash algo=<sha256|sha512|blake2b|crc32> sidecar=<file.sha256> | embed=true")
+	fmt.Println("                              - Write the digest to a sha256sum-style file or embed it in front of the data.")
+	fmt.Println("  apply verify sidecar=<file> | embed=true | digest=<hex> [algo=...]")
+	fmt.Println("                              - Fail unless the data matches its digest; embedded digests are removed.")
+	fmt.Println("  apply sign type=<ed25519|ecdsa|rsa-pss> key_file=<key.priv> [signature=<file.sig>]")
+	fmt.Println("                              - Sign the data; the signature is attached unless a detached signature file is given.")
+	fmt.Println("  apply verify-signature type=<...> key_file=<key.pub> [signature=<file.sig>]")
+	fmt.Println("                              - Fail unless the signature is valid; attached signatures are removed.")
+	fmt.Println("  apply include path=<file> [VAR=value...] - Run the steps of another pipeline file, passing params as its variables.")
+	fmt.Println("  apply use name=<pipeline> [VAR=value...]  - Like include, looking the pipeline up by name next to the current one.")
+	fmt.Println("    compress type=<zip|gzip> [level=<1-9>] (1 is fastest, 9 smallest)")
 	fmt.Println("    decompress type=<zip|gzip>")
-	fmt.Println("    encrypt type=<aes|rsa> key_file=<path>")
-	fmt.Println("    decrypt type=<aes|rsa> key_file=<path>")
+	fmt.Println("    encrypt type=<aes|rsa|chacha20-poly1305|xchacha20-poly1305|aes-siv|aes-ctr-hmac|aes-gcm-stream> key_file=<path>")
+	fmt.Println("            [chunk_size=<bytes>] (aes-gcm-stream only, default 65536)")
+	fmt.Println("            [aad=<context>] (aes only; the same aad is required to decrypt)")
+	fmt.Println("    encrypt type=<x25519|p256> key_file=<key.pub>")
+	fmt.Println("            ECDH with an ephemeral key, HKDF-SHA256 and ChaCha20-Poly1305")
+	fmt.Println("    encrypt [type=multi] recipients=<a.pub,b.pub,...>")
+	fmt.Println("            encrypt once for several RSA or X25519 public keys; any matching private key decrypts")
+	fmt.Println("            with 'decrypt type=multi key_file=<key.priv>'")
+	fmt.Println("    encrypt type=<age|pgp> key_file=<recipients>|recipients=<a.pub,...> [armor=true]")
+	fmt.Println("    encrypt type=<age|pgp> key_file=<passphrase_file> passphrase=true [armor=true]")
+	fmt.Println("            interoperable with the age and gpg tools")
+	fmt.Println("    decrypt type=<age|pgp> key_file=<identity|key.priv|passphrase_file> [passphrase=true]")
+	fmt.Println("            [key_passphrase_file=<file>] (pgp: unlocks a protected private key)")
+	fmt.Println("    decrypt type=<...same as encrypt> key_file=<path>")
+	fmt.Println("            [key_passphrase_file=<file>] (rsa, multi, x25519, p256: unlocks an encrypted private key)")
+	fmt.Println("            [padding=pkcs1v15] (rsa only: data encrypted before OAEP became the default)")
+	fmt.Println("    encrypt|decrypt|sign|verify-signature key=<name> [type=...]")
+	fmt.Println("            use a keyring key instead of key_file; type defaults to the key's algorithm")
 	fmt.Println("    calculate type=<library|parser|regex>")
-	fmt.Println("  process <output_path>           - Run the pipeline and save the result.")
-	fmt.Println("  save-pipeline <file_path>     - Save the current pipeline to a file.")
-	fmt.Println("  load-pipeline <file_path>     - Load a pipeline from a file.")
-	fmt.Println("  gen-key <aes|rsa> <path>      - Generate a new encryption key.")
+	fmt.Println("    defaults.compression_level and defaults.calculation_method in the config fill in level and type")
+	fmt.Println("    any operation also accepts timeout=<duration>, e.g. timeout=30s")
+	fmt.Println("  process <output_path> [options] - Run the pipeline and save the result ('-' for stdout).")
+	fmt.Println("                                  Press Ctrl-C to abort a running process.")
+	fmt.Println("    --keep-intermediate <dir>   also write each step's output to <dir>")
+	fmt.Println("  step [reset]                  - Execute the next pipeline step on the current buffer.")
+	fmt.Println("  peek [bytes]                  - Show size, entropy, type and a preview of the current buffer.")
+	fmt.Println("    indent=<n> sort_keys=true canonical=true compact=true (JSON/YAML/XML output)")
+	fmt.Println("  list                          - Show the pipeline as a numbered table.")
+	fmt.Println("  remove <n>                    - Remove step n.")
+	fmt.Println("  insert <n> <operation> [params...] - Insert a step before step n.")
+	fmt.Println("  move <from> <to>              - Move a step to a new position.")
+	fmt.Println("  edit <n> key=value [...]      - Change params of step n ('key=' removes a param).")
+	fmt.Println("  undo | redo                   - Undo or redo the last pipeline edit.")
+	fmt.Println("  clear                         - Remove all steps from the pipeline.")
+	fmt.Println("  clear-data                    - Wipe the loaded data and step output from memory.")
+	fmt.Println("  save-pipeline <file_path> [name=... description=\"...\" author=... tags=a,b]")
+	fmt.Println("                                - Save the current pipeline (.json or .yaml) with metadata.")
+	fmt.Println("  load-pipeline <file_path|name> [--vars file] [--var NAME=value ...]")
+	fmt.Println("                                - Load a pipeline, filling in ${NAME} and ${NAME:-default} variables.")
+	fmt.Println("  run <pipeline_file|name> <input> <output> [--vars file] [--var NAME=value ...]")
+	fmt.Println("                                - Load an input and a pipeline, then process in one go.")
+	fmt.Println("  pipelines list [tag=<tag>] [text]  - List saved pipelines, filtered by tag and name/description text.")
+	fmt.Println("  pipelines show <name>              - Show the metadata and steps of a saved pipeline.")
+	fmt.Println("  pipelines save <name> [description=\"...\" author=... tags=a,b]")
+	fmt.Println("                                - Save the current pipeline in the catalog.")
+	fmt.Println("  pipelines delete <name>            - Delete a saved pipeline.")
+	fmt.Println("                                  Saved pipelines live in $XDG_CONFIG_HOME/file-processing/pipelines.")
+	fmt.Println("  checksum [algo=<sha256|sha512|blake2b|crc32>] <file...>")
+	fmt.Println("                                - Print file digests in sha256sum format.")
+	fmt.Println("  checksum -c <checksum_file>   - Check the files listed in a checksum file (as sha256sum -c does).")
+	fmt.Println("  encrypt-file <key_file> <input> <output> [chunk_size=<bytes>]")
+	fmt.Println("                                - Encrypt a file chunk by chunk (aes-gcm-stream) without loading it into memory.")
+	fmt.Println("  decrypt-file <key_file> <input> <output>")
+	fmt.Println("                                - Decrypt an aes-gcm-stream file; the output is only written if it is intact.")
+	fmt.Println("  gen-key <aes|rsa|chacha20-poly1305|xchacha20-poly1305|aes-siv|aes-ctr-hmac|aes-gcm-stream> <path>")
+	fmt.Println("                                - Generate a new encryption key.")
+	fmt.Println("  gen-key rsa <path> [bits=<3072|4096>] [passphrase_file=<file>]")
+	fmt.Println("                                - Generate an RSA key pair; the PKCS#8 <path>.priv is encrypted with the passphrase.")
+	fmt.Println("  gen-key age <path>              - Generate an age identity at <path> and its recipient at <path>.pub.")
+	fmt.Println("  gen-key pgp <path>              - Generate an OpenPGP key as armored <path>.priv and <path>.pub.")
+	fmt.Println("  gen-key <x25519|p256> <path>    - Generate an ECIES key pair as <path>.priv and <path>.pub;")
+	fmt.Println("                                  x25519 keys are also multi recipients.")
+	fmt.Println("  gen-key <ed25519|ecdsa|rsa-pss> <path>")
+	fmt.Println("                                - Generate a signing key pair as <path>.priv and <path>.pub.")
+	fmt.Println("  keys list                       - List keyring keys with their algorithm, fingerprint and creation date.")
+	fmt.Println("  keys show <name>                - Show a key's details and public key.")
+	fmt.Println("  keys generate <name> <algorithm> - Generate a key (any gen-key algorithm) into the keyring.")
+	fmt.Println("  keys import <name> <algorithm> <path>")
+	fmt.Println("                                - Import a key written by gen-key; a <path>.pub imports only the public key.")
+	fmt.Println("  keys export <name> <path> [--public]")
+	fmt.Println("                                - Write a key to disk in the gen-key layout.")
+	fmt.Println("  keys delete <name>              - Delete a key.")
+	fmt.Println("  keys split <name|key_file> n=<shares> k=<threshold> [out=<dir>] [type=<algorithm>]")
+	fmt.Println("                                - Split a key into Shamir shares for escrow; any k of the n shares rebuild it.")
+	fmt.Println("  keys combine <share files...> [name=<name>] [out=<key_file>]")
+	fmt.Println("                                - Rebuild a key from shares into the keyring, or into a key file with out=.")
+	fmt.Println("                                  Keys live in defaults.key_dir, or $XDG_CONFIG_HOME/file-processing/keys.")
+	fmt.Println("  rekey from=<key> to=<key> <file...>")
+	fmt.Println("                                - Decrypt files with one keyring key and encrypt them again with another,")
+	fmt.Println("                                  defaults.workers files at a time.")
+	fmt.Println("  config show                   - Show the effective configuration and where each value came from.")
+	fmt.Println("                                  Settings are read from the -config file, $FILE_PROCESSING_CONFIG,")
+	fmt.Println("                                  $XDG_CONFIG_HOME/file-processing/config.{yaml,yml,toml,json} or")
+	fmt.Println("                                  config.{yaml,yml,toml,json} in the current directory, in that order.")
+	fmt.Println("                                  FILE_PROCESSING_<SETTING> variables override them, e.g.")
+	fmt.Println("                                  FILE_PROCESSING_LOGGING_LEVEL=debug for logging.level.")
 	fmt.Println("  help                            - Show this help message.")
 	fmt.Println("  exit                            - Exit the application.")
+	fmt.Println()
+	fmt.Println("Non-interactive use:")
+	fmt.Println("  file-processing [-config file] -pipeline <file|name> [-in <path|->] [-out <path|->] [-vars file] [-var NAME=value ...]")
 }
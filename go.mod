@@ -3,22 +3,27 @@ module github.com/dzibukalexander/file-processing
 go 1.24.1
 
 require (
+	filippo.io/age v1.2.1
+	github.com/BurntSushi/toml v1.6.0
 	github.com/Knetic/govaluate v3.0.0+incompatible
+	github.com/ProtonMail/go-crypto v1.3.0
 	github.com/ozontech/allure-go/pkg/framework v0.6.33
 	github.com/sirupsen/logrus v1.9.3
+	golang.org/x/crypto v0.40.0
+	golang.org/x/sys v0.34.0
 )
 
 require (
+	github.com/cloudflare/circl v1.6.0 // indirect
 	github.com/google/uuid v1.3.0 // indirect
 	github.com/ozontech/allure-go/pkg/allure v0.6.14 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
-	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/testify v1.10.0
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
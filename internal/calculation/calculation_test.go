@@ -1,6 +1,7 @@
 package calculation
 
 import (
+	"context"
 	"testing"
 
 	"github.com/dzibukalexander/file-processing/internal/calculation/library"
@@ -23,7 +24,7 @@ func TestLibraryCalculator(t *testing.T) {
 		for input, expected := range testCases {
 			in, exp := input, expected
 			t.WithNewStep(in, func(s provider.StepCtx) {
-				result, err := calc.Calculate(in)
+				result, err := calc.Calculate(context.Background(), in)
 				if exp == "invalid expression" {
 					s.Assert().Contains(result, "invalid")
 				} else {
@@ -60,7 +61,7 @@ func TestParserCalculator(t *testing.T) {
 		for input, expected := range testCases {
 			in, exp := input, expected
 			t.WithNewStep(in, func(s provider.StepCtx) {
-				result, err := calc.Calculate(in)
+				result, err := calc.Calculate(context.Background(), in)
 				s.Require().NoError(err)
 				s.Assert().Equal(exp, result)
 			})
@@ -80,7 +81,7 @@ func TestRegexCalculator(t *testing.T) {
 		for input, expected := range testCases {
 			in, exp := input, expected
 			t.WithNewStep(in, func(s provider.StepCtx) {
-				result, err := calc.Calculate(in)
+				result, err := calc.Calculate(context.Background(), in)
 				s.Require().NoError(err)
 				s.Assert().Equal(exp, result)
 			})
@@ -1,6 +1,8 @@
 package calculation
 
 import (
+	"context"
+
 	"github.com/dzibukalexander/file-processing/internal/calculation/constants"
 	"github.com/dzibukalexander/file-processing/internal/calculation/library"
 	"github.com/dzibukalexander/file-processing/internal/calculation/parser"
@@ -8,7 +10,7 @@ import (
 )
 
 type Calculator interface {
-	Calculate(content string) (string, error)
+	Calculate(ctx context.Context, content string) (string, error)
 }
 
 func NewCalculator(method constants.CalculationMethod) Calculator {
@@ -1,6 +1,7 @@
 package library
 
 import (
+	"context"
 	"strconv"
 	"strings"
 
@@ -9,9 +10,12 @@ import (
 
 type LibraryCalculator struct{}
 
-func (c *LibraryCalculator) Calculate(content string) (string, error) {
+func (c *LibraryCalculator) Calculate(ctx context.Context, content string) (string, error) {
 	lines := strings.Split(content, "\n")
 	for i, line := range lines {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
 		expression, err := govaluate.NewEvaluableExpression(line)
 		if err != nil {
 			continue
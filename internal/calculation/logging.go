@@ -1,6 +1,7 @@
 package calculation
 
 import (
+	"context"
 	"time"
 
 	"github.com/dzibukalexander/file-processing/internal/logger"
@@ -14,8 +15,8 @@ func NewLoggingCalculator(calculator Calculator) Calculator {
 	return &loggingCalculator{calculator: calculator}
 }
 
-func (l *loggingCalculator) Calculate(content string) (result string, err error) {
-	log := logger.GetInstance().WithField("input_size", len(content))
+func (l *loggingCalculator) Calculate(ctx context.Context, content string) (result string, err error) {
+	log := logger.For("calculation").WithField("input_size", len(content))
 	log.Info("Starting calculation")
 
 	defer func(begin time.Time) {
@@ -26,5 +27,5 @@ func (l *loggingCalculator) Calculate(content string) (result string, err error)
 		}
 	}(time.Now())
 
-	return l.calculator.Calculate(content)
+	return l.calculator.Calculate(ctx, content)
 }
@@ -2,6 +2,7 @@ package calculation
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"testing"
 
@@ -17,8 +18,8 @@ type MockCalculator struct {
 	mock.Mock
 }
 
-func (m *MockCalculator) Calculate(content string) (string, error) {
-	args := m.Called(content)
+func (m *MockCalculator) Calculate(ctx context.Context, content string) (string, error) {
+	args := m.Called(ctx, content)
 	return args.String(0), args.Error(1)
 }
 
@@ -41,10 +42,10 @@ func TestLoggingCalculator_Success(t *testing.T) {
 
 			input := "2 + 2"
 			expectedResult := "4"
-			mockCalc.On("Calculate", input).Return(expectedResult, nil)
+			mockCalc.On("Calculate", mock.Anything, input).Return(expectedResult, nil)
 
 			loggingCalc := NewLoggingCalculator(mockCalc)
-			result, err := loggingCalc.Calculate(input)
+			result, err := loggingCalc.Calculate(context.Background(), input)
 
 			s.Assert().NoError(err)
 			s.Assert().Equal(expectedResult, result)
@@ -64,10 +65,10 @@ func TestLoggingCalculator_Error(t *testing.T) {
 
 			input := "invalid"
 			expectedError := errors.New("calculation error")
-			mockCalc.On("Calculate", input).Return("", expectedError)
+			mockCalc.On("Calculate", mock.Anything, input).Return("", expectedError)
 
 			loggingCalc := NewLoggingCalculator(mockCalc)
-			_, err := loggingCalc.Calculate(input)
+			_, err := loggingCalc.Calculate(context.Background(), input)
 
 			s.Assert().Error(err)
 			s.Assert().Equal(expectedError, err)
@@ -2,6 +2,7 @@
 package parser
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -122,9 +123,12 @@ func tokenize(expr string) []string {
 	return tokens
 }
 
-func (c *ParserCalculator) Calculate(content string) (string, error) {
+func (c *ParserCalculator) Calculate(ctx context.Context, content string) (string, error) {
 	lines := strings.Split(content, "\n")
 	for i, line := range lines {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
 		tokens := tokenize(line)
 		if len(tokens) == 0 {
 			continue
@@ -1,15 +1,19 @@
 package regex
 
 import (
+	"context"
 	"regexp"
 	"strconv"
 )
 
 type RegexCalculator struct{}
 
-func (c *RegexCalculator) Calculate(content string) (string, error) {
+func (c *RegexCalculator) Calculate(ctx context.Context, content string) (string, error) {
 	re := regexp.MustCompile(`\b(\d+)\s*([+\-*\/])\s*(\d+)\b`)
 	result := re.ReplaceAllStringFunc(content, func(match string) string {
+		if ctx.Err() != nil {
+			return match
+		}
 		parts := re.FindStringSubmatch(match)
 		if len(parts) != 4 {
 			return match
@@ -35,5 +39,8 @@ func (c *RegexCalculator) Calculate(content string) (string, error) {
 		}
 		return strconv.Itoa(res)
 	})
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 	return result, nil
 }
@@ -0,0 +1,210 @@
+// Package catalog stores named pipelines in a directory, by default
+// $XDG_CONFIG_HOME/file-processing/pipelines.
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dzibukalexander/file-processing/pkg/fileprocessing"
+)
+
+// extensions are the pipeline file types, in lookup order.
+var extensions = []string{".json", ".yaml", ".yml"}
+
+// validName keeps catalog names usable as file names on every platform.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ErrNotFound is returned for names that are not in the catalog.
+var ErrNotFound = errors.New("pipeline not found in catalog")
+
+// Catalog is a directory of pipeline documents addressed by name.
+type Catalog struct {
+	dir string
+}
+
+// Entry describes a pipeline stored in the catalog. Err is set if the file
+// could not be read, in which case Document is nil.
+type Entry struct {
+	Name     string
+	Path     string
+	Document *fileprocessing.Document
+	Err      error
+}
+
+// New returns a catalog stored in dir. The directory is created on first save.
+func New(dir string) *Catalog {
+	return &Catalog{dir: dir}
+}
+
+// DefaultDir returns the user's catalog directory, which honours
+// $XDG_CONFIG_HOME on Unix systems.
+func DefaultDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "file-processing", "pipelines"), nil
+}
+
+// Default returns the catalog in DefaultDir.
+func Default() (*Catalog, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return New(dir), nil
+}
+
+// Dir returns the directory holding the catalog.
+func (c *Catalog) Dir() string {
+	return c.dir
+}
+
+// Lookup returns the path of the saved pipeline called name.
+func (c *Catalog) Lookup(name string) (string, error) {
+	files, err := fileNames(name)
+	if err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		path := filepath.Join(c.dir, file)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// SavePath returns the path to save the pipeline called name to: the
+// existing file if there is one, otherwise <name>.json. The catalog
+// directory is created if needed.
+func (c *Catalog) SavePath(name string) (string, error) {
+	if path, err := c.Lookup(name); err == nil {
+		return path, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create catalog directory: %w", err)
+	}
+	files, _ := fileNames(name)
+	return filepath.Join(c.dir, files[0]), nil
+}
+
+// Delete removes the saved pipeline called name.
+func (c *Catalog) Delete(name string) error {
+	path, err := c.Lookup(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// List returns every pipeline in the catalog sorted by name. A missing
+// catalog directory is treated as empty.
+func (c *Catalog) List() ([]Entry, error) {
+	files, err := os.ReadDir(c.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, file := range files {
+		ext := strings.ToLower(filepath.Ext(file.Name()))
+		if file.IsDir() || !isPipelineExt(ext) {
+			continue
+		}
+		entry := Entry{
+			Name: strings.TrimSuffix(file.Name(), filepath.Ext(file.Name())),
+			Path: filepath.Join(c.dir, file.Name()),
+		}
+		entry.Document, entry.Err = fileprocessing.ReadDocument(entry.Path)
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// Search lists the pipelines tagged with tag (if not empty) whose name,
+// description or tags contain query (if not empty). Both are case-insensitive.
+func (c *Catalog) Search(tag, query string) ([]Entry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	tag, query = strings.ToLower(tag), strings.ToLower(query)
+
+	var matches []Entry
+	for _, entry := range entries {
+		if entry.Matches(tag, query) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches, nil
+}
+
+// Matches reports whether the entry has the lower-case tag and contains the
+// lower-case query; empty values match everything. Unreadable entries only
+// match on name.
+func (e Entry) Matches(tag, query string) bool {
+	var tags []string
+	description := ""
+	if e.Document != nil {
+		tags = e.Document.Tags
+		description = e.Document.Description
+	}
+
+	if tag != "" {
+		found := false
+		for _, t := range tags {
+			if strings.ToLower(t) == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if query == "" {
+		return true
+	}
+	haystack := strings.ToLower(strings.Join(append([]string{e.Name, description}, tags...), "\n"))
+	return strings.Contains(haystack, query)
+}
+
+// fileNames lists the files name may be stored under. A name may carry a
+// pipeline extension to choose the format of a new file.
+func fileNames(name string) ([]string, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("invalid pipeline name %q: use letters, digits, '.', '-' and '_'", name)
+	}
+	if isPipelineExt(strings.ToLower(filepath.Ext(name))) {
+		return []string{name}, nil
+	}
+	names := make([]string, len(extensions))
+	for i, ext := range extensions {
+		names[i] = name + ext
+	}
+	return names, nil
+}
+
+func isPipelineExt(ext string) bool {
+	for _, e := range extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
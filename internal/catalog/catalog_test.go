@@ -0,0 +1,79 @@
+package catalog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dzibukalexander/file-processing/pkg/fileprocessing"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+)
+
+func TestCatalog(t *testing.T) {
+	runner.Run(t, "Pipeline catalog", func(t provider.T) {
+		t.WithNewStep("default dir follows XDG_CONFIG_HOME", func(s provider.StepCtx) {
+			t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg-test")
+			dir, err := DefaultDir()
+			s.Require().NoError(err)
+			s.Assert().Equal(filepath.Join("/tmp/xdg-test", "file-processing", "pipelines"), dir)
+		})
+
+		t.WithNewStep("save, list, search and delete", func(s provider.StepCtx) {
+			cat := New(filepath.Join(t.TempDir(), "pipelines"))
+			entries, err := cat.List()
+			s.Require().NoError(err)
+			s.Assert().Empty(entries)
+
+			save := func(name string, doc *fileprocessing.Document) {
+				path, err := cat.SavePath(name)
+				s.Require().NoError(err)
+				s.Require().NoError(doc.WriteFile(path))
+			}
+			save("secure-archive", &fileprocessing.Document{Description: "Compress and encrypt", Tags: []string{"Security", "archive"}})
+			save("report.yaml", &fileprocessing.Document{Description: "Sum the numbers", Tags: []string{"calc"}})
+			s.Require().NoError(os.WriteFile(filepath.Join(cat.Dir(), "broken.json"), []byte("{"), 0644))
+			s.Require().NoError(os.WriteFile(filepath.Join(cat.Dir(), "notes.txt"), []byte("ignored"), 0644))
+
+			entries, err = cat.List()
+			s.Require().NoError(err)
+			s.Require().Len(entries, 3)
+			s.Assert().Equal("broken", entries[0].Name)
+			s.Assert().Error(entries[0].Err)
+			s.Assert().Equal("report", entries[1].Name)
+
+			path, err := cat.Lookup("report")
+			s.Require().NoError(err)
+			s.Assert().Equal("report.yaml", filepath.Base(path))
+			path, err = cat.SavePath("report")
+			s.Require().NoError(err)
+			s.Assert().Equal("report.yaml", filepath.Base(path), "saving again keeps the existing file")
+
+			matches, err := cat.Search("security", "")
+			s.Require().NoError(err)
+			s.Require().Len(matches, 1)
+			s.Assert().Equal("secure-archive", matches[0].Name)
+			matches, err = cat.Search("", "NUMBERS")
+			s.Require().NoError(err)
+			s.Require().Len(matches, 1)
+			s.Assert().Equal("report", matches[0].Name)
+			matches, err = cat.Search("calc", "encrypt")
+			s.Require().NoError(err)
+			s.Assert().Empty(matches)
+
+			s.Require().NoError(cat.Delete("secure-archive"))
+			_, err = cat.Lookup("secure-archive")
+			s.Assert().True(errors.Is(err, ErrNotFound))
+			s.Assert().Error(cat.Delete("secure-archive"))
+		})
+
+		t.WithNewStep("invalid names", func(s provider.StepCtx) {
+			cat := New(t.TempDir())
+			for _, name := range []string{"", "../x", "a/b", ".hidden"} {
+				_, err := cat.SavePath(name)
+				s.Assert().Error(err, name)
+			}
+		})
+	})
+}
@@ -1,8 +1,11 @@
 package compression
 
 import (
+	"context"
+	"strings"
 	"testing"
 
+	. "github.com/dzibukalexander/file-processing/internal/compression/constants"
 	"github.com/dzibukalexander/file-processing/internal/compression/gzip"
 	"github.com/dzibukalexander/file-processing/internal/compression/zip"
 	"github.com/ozontech/allure-go/pkg/framework/provider"
@@ -17,11 +20,11 @@ func TestGzipCompressDecompress_String(t *testing.T) {
 			original := "hello gzip"
 			originalData := []byte(original)
 
-			compressed, err := compressor.Compress(originalData)
+			compressed, err := compressor.Compress(context.Background(), originalData)
 			s.Require().NoError(err)
 			s.Assert().NotEqual(originalData, compressed)
 
-			decompressed, err := decompressor.Decompress(compressed)
+			decompressed, err := decompressor.Decompress(context.Background(), compressed)
 			s.Require().NoError(err)
 			decompressedStr := string(decompressed)
 			s.Assert().Equal(original, decompressedStr)
@@ -37,14 +40,42 @@ func TestZipCompressDecompress_String(t *testing.T) {
 			original := "hello zip"
 			originalData := []byte(original)
 
-			compressed, err := compressor.Compress(originalData)
+			compressed, err := compressor.Compress(context.Background(), originalData)
 			s.Require().NoError(err)
 			s.Assert().NotEqual(originalData, compressed)
 
-			decompressed, err := decompressor.Decompress(compressed)
+			decompressed, err := decompressor.Decompress(context.Background(), compressed)
 			s.Require().NoError(err)
 			decompressedStr := string(decompressed)
 			s.Assert().Equal(original, decompressedStr)
 		})
 	})
 }
+
+func TestCompressionLevels(t *testing.T) {
+	runner.Run(t, "Compression levels", func(t provider.T) {
+		t.WithNewStep("higher levels compress smaller", func(s provider.StepCtx) {
+			original := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog 0123456789\n", 2000))
+			for _, compType := range []CompressionType{GZIP, ZIP} {
+				fast, err := NewCompressorWithLevel(compType, MinLevel).Compress(context.Background(), original)
+				s.Require().NoError(err)
+				best, err := NewCompressorWithLevel(compType, MaxLevel).Compress(context.Background(), original)
+				s.Require().NoError(err)
+				s.Assert().Less(len(best), len(fast), string(compType))
+
+				for _, compressed := range [][]byte{fast, best} {
+					out, err := NewDecompressor(compType).Decompress(context.Background(), compressed)
+					s.Require().NoError(err)
+					s.Assert().Equal(original, out)
+				}
+			}
+		})
+
+		t.WithNewStep("invalid level", func(s provider.StepCtx) {
+			_, err := NewCompressorWithLevel(GZIP, 12).Compress(context.Background(), []byte("x"))
+			s.Assert().Error(err)
+			_, err = NewCompressorWithLevel(ZIP, 12).Compress(context.Background(), []byte("x"))
+			s.Assert().Error(err)
+		})
+	})
+}
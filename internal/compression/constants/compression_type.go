@@ -11,6 +11,12 @@ const (
 	ZIP  CompressionType = "ZIP"
 )
 
+// MinLevel and MaxLevel bound compression levels, from fastest to smallest.
+const (
+	MinLevel = 1
+	MaxLevel = 9
+)
+
 func CompressionTypeFromString(s string) (CompressionType, error) {
 	switch s {
 	case "NONE":
@@ -3,15 +3,29 @@ package gzip
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"io"
+
+	"github.com/dzibukalexander/file-processing/internal/ctxio"
 )
 
-type GzipCompressor struct{}
+// GzipCompressor compresses at Level, from 1 (fastest) to 9 (smallest); 0
+// uses the default level.
+type GzipCompressor struct {
+	Level int
+}
 
-func (c *GzipCompressor) Compress(data []byte) ([]byte, error) {
+func (c *GzipCompressor) Compress(ctx context.Context, data []byte) ([]byte, error) {
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
 	var buf bytes.Buffer
-	w := gzip.NewWriter(&buf)
-	if _, err := w.Write(data); err != nil {
+	w, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctxio.Write(ctx, w, data); err != nil {
 		return nil, err
 	}
 	if err := w.Close(); err != nil {
@@ -22,11 +36,11 @@ func (c *GzipCompressor) Compress(data []byte) ([]byte, error) {
 
 type GzipDecompressor struct{}
 
-func (d *GzipDecompressor) Decompress(data []byte) ([]byte, error) {
+func (d *GzipDecompressor) Decompress(ctx context.Context, data []byte) ([]byte, error) {
 	r, err := gzip.NewReader(bytes.NewReader(data))
 	if err != nil {
 		return nil, err
 	}
 	defer r.Close()
-	return io.ReadAll(r)
+	return io.ReadAll(ctxio.NewReader(ctx, r))
 }
@@ -2,26 +2,34 @@
 package compression
 
 import (
+	"context"
+
 	. "github.com/dzibukalexander/file-processing/internal/compression/constants"
 	"github.com/dzibukalexander/file-processing/internal/compression/gzip"
 	"github.com/dzibukalexander/file-processing/internal/compression/zip"
 )
 
 type Compressor interface {
-	Compress(data []byte) ([]byte, error)
+	Compress(ctx context.Context, data []byte) ([]byte, error)
 }
 
 type Decompressor interface {
-	Decompress(data []byte) ([]byte, error)
+	Decompress(ctx context.Context, data []byte) ([]byte, error)
 }
 
 func NewCompressor(compType CompressionType) Compressor {
+	return NewCompressorWithLevel(compType, 0)
+}
+
+// NewCompressorWithLevel returns a compressor for compType that compresses at
+// level, from MinLevel to MaxLevel; 0 uses the default level.
+func NewCompressorWithLevel(compType CompressionType, level int) Compressor {
 	var compressor Compressor
 	switch compType {
 	case GZIP:
-		compressor = &gzip.GzipCompressor{}
+		compressor = &gzip.GzipCompressor{Level: level}
 	case ZIP:
-		compressor = &zip.ZipCompressor{}
+		compressor = &zip.ZipCompressor{Level: level}
 	default:
 		return nil
 	}
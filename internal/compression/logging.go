@@ -1,6 +1,7 @@
 package compression
 
 import (
+	"context"
 	"time"
 
 	"github.com/dzibukalexander/file-processing/internal/logger"
@@ -14,8 +15,8 @@ func NewLoggingCompressor(compressor Compressor) Compressor {
 	return &loggingCompressor{compressor: compressor}
 }
 
-func (l *loggingCompressor) Compress(data []byte) (result []byte, err error) {
-	log := logger.GetInstance().WithField("input_size", len(data))
+func (l *loggingCompressor) Compress(ctx context.Context, data []byte) (result []byte, err error) {
+	log := logger.For("compression").WithField("input_size", len(data))
 	log.Info("Starting compression")
 
 	defer func(begin time.Time) {
@@ -30,7 +31,7 @@ func (l *loggingCompressor) Compress(data []byte) (result []byte, err error) {
 		}
 	}(time.Now())
 
-	return l.compressor.Compress(data)
+	return l.compressor.Compress(ctx, data)
 }
 
 type loggingDecompressor struct {
@@ -41,8 +42,8 @@ func NewLoggingDecompressor(decompressor Decompressor) Decompressor {
 	return &loggingDecompressor{decompressor: decompressor}
 }
 
-func (l *loggingDecompressor) Decompress(data []byte) (result []byte, err error) {
-	log := logger.GetInstance().WithField("input_size", len(data))
+func (l *loggingDecompressor) Decompress(ctx context.Context, data []byte) (result []byte, err error) {
+	log := logger.For("compression").WithField("input_size", len(data))
 	log.Info("Starting decompression")
 
 	defer func(begin time.Time) {
@@ -56,5 +57,5 @@ func (l *loggingDecompressor) Decompress(data []byte) (result []byte, err error)
 		}
 	}(time.Now())
 
-	return l.decompressor.Decompress(data)
+	return l.decompressor.Decompress(ctx, data)
 }
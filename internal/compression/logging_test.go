@@ -2,6 +2,7 @@ package compression
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"testing"
 
@@ -17,8 +18,8 @@ type MockCompressor struct {
 	mock.Mock
 }
 
-func (m *MockCompressor) Compress(data []byte) ([]byte, error) {
-	args := m.Called(data)
+func (m *MockCompressor) Compress(ctx context.Context, data []byte) ([]byte, error) {
+	args := m.Called(ctx, data)
 	return args.Get(0).([]byte), args.Error(1)
 }
 
@@ -27,8 +28,8 @@ type MockDecompressor struct {
 	mock.Mock
 }
 
-func (m *MockDecompressor) Decompress(data []byte) ([]byte, error) {
-	args := m.Called(data)
+func (m *MockDecompressor) Decompress(ctx context.Context, data []byte) ([]byte, error) {
+	args := m.Called(ctx, data)
 	return args.Get(0).([]byte), args.Error(1)
 }
 
@@ -50,10 +51,10 @@ func TestLoggingCompressor(t *testing.T) {
 
 			mockComp := new(MockCompressor)
 			input := []byte("data")
-			mockComp.On("Compress", input).Return([]byte("compressed"), nil)
+			mockComp.On("Compress", mock.Anything, input).Return([]byte("compressed"), nil)
 
 			loggingComp := NewLoggingCompressor(mockComp)
-			_, err := loggingComp.Compress(input)
+			_, err := loggingComp.Compress(context.Background(), input)
 
 			s.Assert().NoError(err)
 			s.Assert().Contains(logOutput.String(), "Starting compression")
@@ -73,10 +74,10 @@ func TestLoggingDecompressor_Error(t *testing.T) {
 			mockDecomp := new(MockDecompressor)
 			input := []byte("data")
 			expectedErr := errors.New("decompression error")
-			mockDecomp.On("Decompress", input).Return([]byte(nil), expectedErr)
+			mockDecomp.On("Decompress", mock.Anything, input).Return([]byte(nil), expectedErr)
 
 			loggingDecomp := NewLoggingDecompressor(mockDecomp)
-			_, err := loggingDecomp.Decompress(input)
+			_, err := loggingDecomp.Decompress(context.Background(), input)
 
 			s.Assert().Error(err)
 			s.Assert().Equal(expectedErr, err)
@@ -4,20 +4,33 @@ package zip
 import (
 	"archive/zip"
 	"bytes"
+	"compress/flate"
+	"context"
 	"fmt"
 	"io"
+
+	"github.com/dzibukalexander/file-processing/internal/ctxio"
 )
 
-type ZipCompressor struct{}
+// ZipCompressor deflates at Level, from 1 (fastest) to 9 (smallest); 0 uses
+// the default level.
+type ZipCompressor struct {
+	Level int
+}
 
-func (c *ZipCompressor) Compress(data []byte) ([]byte, error) {
+func (c *ZipCompressor) Compress(ctx context.Context, data []byte) ([]byte, error) {
 	var buf bytes.Buffer
 	w := zip.NewWriter(&buf)
+	if c.Level != 0 {
+		w.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, c.Level)
+		})
+	}
 	f, err := w.Create("data")
 	if err != nil {
 		return nil, err
 	}
-	if _, err := f.Write(data); err != nil {
+	if err := ctxio.Write(ctx, f, data); err != nil {
 		return nil, err
 	}
 	if err := w.Close(); err != nil {
@@ -28,7 +41,7 @@ func (c *ZipCompressor) Compress(data []byte) ([]byte, error) {
 
 type ZipDecompressor struct{}
 
-func (d *ZipDecompressor) Decompress(data []byte) ([]byte, error) {
+func (d *ZipDecompressor) Decompress(ctx context.Context, data []byte) ([]byte, error) {
 	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
 	if err != nil {
 		return nil, err
@@ -41,5 +54,5 @@ func (d *ZipDecompressor) Decompress(data []byte) ([]byte, error) {
 		return nil, err
 	}
 	defer rc.Close()
-	return io.ReadAll(rc)
+	return io.ReadAll(ctxio.NewReader(ctx, rc))
 }
@@ -1,36 +1,299 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
+// EnvPath is the environment variable naming the config file, used when no
+// path is given on the command line.
+const EnvPath = "FILE_PROCESSING_CONFIG"
+
+// EnvPrefix starts the environment variables that override single settings,
+// e.g. FILE_PROCESSING_LOGGING_LEVEL=debug for logging.level.
+const EnvPrefix = "FILE_PROCESSING_"
+
 // Config holds the application's configuration settings.
 type Config struct {
-	EnableLogging bool `json:"enable_logging"`
+	EnableLogging bool           `json:"enable_logging"`
+	Logging       LoggingConfig  `json:"logging"`
+	Defaults      DefaultsConfig `json:"defaults"`
+
+	// Path is the file the config was read from, or empty if none was found.
+	Path string `json:"-"`
+
+	// sources records where each setting that is not a default came from.
+	sources map[string]string
+}
+
+// LoggingConfig controls where logs go and how much is written. It applies
+// only when EnableLogging is set; zero values select the defaults.
+type LoggingConfig struct {
+	// Level is the minimum level written: debug, info (default), warn or error.
+	Level string `json:"level"`
+	// Format is text (default), json or logfmt.
+	Format string `json:"format"`
+	// Output is stdout (default), stderr or a file path.
+	Output string `json:"output"`
+	// MaxSizeMB rotates a log file once it would grow past this size; 0
+	// never rotates.
+	MaxSizeMB int `json:"max_size_mb"`
+	// MaxBackups is the number of rotated files kept as <output>.1 to
+	// <output>.N; 0 keeps one.
+	MaxBackups int `json:"max_backups"`
+	// Packages overrides Level for individual packages, such as
+	// {"compression": "debug"}.
+	Packages map[string]string `json:"packages"`
+}
+
+// DefaultsConfig holds values used by operations that leave them unset.
+type DefaultsConfig struct {
+	// CompressionLevel is the level param of compress steps, 1 to 9; 0 uses
+	// the library default.
+	CompressionLevel int `json:"compression_level"`
+	// KeyDir is the keyring directory that key=<name> params refer to;
+	// empty uses the keyring in the user config directory.
+	KeyDir string `json:"key_dir"`
+	// CalculationMethod is the type param of calculate steps.
+	CalculationMethod string `json:"calculation_method"`
+	// Workers is the number of files processed at once by commands that
+	// take several files.
+	Workers int `json:"workers"`
+}
+
+// Setting is one effective configuration value.
+type Setting struct {
+	Key    string
+	Value  string
+	Source string // "default", the config file path, or the environment variable
 }
 
 // AppConfig is the global configuration instance.
 var AppConfig *Config
 
-// LoadConfig loads configuration from a file or uses defaults.
-func LoadConfig(path string) error {
-	// Default config
-	AppConfig = &Config{
+// Default returns the configuration used when nothing is set.
+func Default() *Config {
+	return &Config{
 		EnableLogging: false,
+		Defaults:      DefaultsConfig{Workers: runtime.NumCPU()},
+		sources:       map[string]string{},
 	}
+}
 
-	file, err := os.Open(path)
+// LoadConfig loads the configuration into AppConfig. path is the file given
+// on the command line; if it is empty the file is discovered as described by
+// Discover, and the defaults are used when there is none.
+func LoadConfig(path string) error {
+	cfg, err := Load(path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			// Config file doesn't exist, use defaults.
-			return nil
+		return err
+	}
+	AppConfig = cfg
+	return nil
+}
+
+// Load reads the configuration from path, or the discovered config file if
+// path is empty, and applies environment overrides on top.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	path, err := Discover(path)
+	if err != nil {
+		return nil, err
+	}
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return nil, err
+		}
+		cfg.Path = path
+	}
+	if err := cfg.applyEnv(os.Environ()); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Discover returns the config file to read, the first of:
+//
+//   - path, as given with the -config flag
+//   - $FILE_PROCESSING_CONFIG
+//   - config.yaml, config.yml, config.toml or config.json in
+//     $XDG_CONFIG_HOME/file-processing (or the platform's config directory)
+//   - the same names in the current directory
+//
+// A file named by the flag or the environment must exist. An empty result
+// means there is no config file.
+func Discover(path string) (string, error) {
+	if path == "" {
+		path = os.Getenv(EnvPath)
+	}
+	if path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("config file: %w", err)
+		}
+		return path, nil
+	}
+
+	var dirs []string
+	if configDir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(configDir, "file-processing"))
+	}
+	dirs = append(dirs, ".")
+	for _, dir := range dirs {
+		for _, name := range []string{"config.yaml", "config.yml", "config.toml", "config.json"} {
+			candidate := filepath.Join(dir, name)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, nil
+			}
 		}
+	}
+	return "", nil
+}
+
+// readFile applies the settings in a JSON, YAML or TOML file.
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
 		return err
 	}
-	defer file.Close()
+	values := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&values)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config file %s: unknown format (want .json, .yaml, .yml or .toml)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	flat := map[string]string{}
+	if err := flatten("", values, flat); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	for key, value := range flat {
+		if err := c.Set(key, value, path); err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	}
+	return nil
+}
 
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(AppConfig)
-	return err
+// flatten turns nested tables into dotted keys, e.g. logging.level.
+func flatten(prefix string, values map[string]interface{}, out map[string]string) error {
+	for k, v := range values {
+		key := prefix + k
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if err := flatten(key+".", v, out); err != nil {
+				return err
+			}
+		case []interface{}:
+			return fmt.Errorf("%s: lists are not supported", key)
+		case nil:
+			out[key] = ""
+		default:
+			out[key] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
+
+// applyEnv applies FILE_PROCESSING_* variables from env, given as
+// NAME=value; empty variables are ignored. FILE_PROCESSING_LOGGING_PACKAGES
+// takes a list such as compression=debug,core=warn.
+func (c *Config) applyEnv(env []string) error {
+	for _, entry := range env {
+		name, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(name, EnvPrefix) || name == EnvPath || value == "" {
+			continue
+		}
+		if name == envName(packagesKey) {
+			for _, pair := range strings.Split(value, ",") {
+				pkg, level, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || pkg == "" {
+					return fmt.Errorf("%s: expected package=level, got %q", name, pair)
+				}
+				if err := c.Set(packagesKey+"."+pkg, level, name); err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+			}
+			continue
+		}
+		for _, s := range settings {
+			if envName(s.key) == name {
+				if err := c.Set(s.key, value, name); err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// envName returns the environment variable that overrides key.
+func envName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// Set changes the setting key, such as logging.level, recording source as
+// where the value came from.
+func (c *Config) Set(key, value, source string) error {
+	if pkg, ok := strings.CutPrefix(key, packagesKey+"."); ok {
+		if c.Logging.Packages == nil {
+			c.Logging.Packages = map[string]string{}
+		}
+		c.Logging.Packages[pkg] = value
+	} else {
+		i := slices.IndexFunc(settings, func(s setting) bool { return s.key == key })
+		if i < 0 {
+			return fmt.Errorf("unknown setting: %s", key)
+		}
+		if err := settings[i].set(c, value); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	if c.sources == nil {
+		c.sources = map[string]string{}
+	}
+	c.sources[key] = source
+	return nil
+}
+
+// Settings returns every setting with its effective value and source.
+func (c *Config) Settings() []Setting {
+	var out []Setting
+	add := func(key, value string) {
+		source := c.sources[key]
+		if source == "" {
+			source = "default"
+		}
+		out = append(out, Setting{Key: key, Value: value, Source: source})
+	}
+	for _, s := range settings {
+		add(s.key, s.get(c))
+	}
+	packages := make([]string, 0, len(c.Logging.Packages))
+	for pkg := range c.Logging.Packages {
+		packages = append(packages, pkg)
+	}
+	slices.Sort(packages)
+	for _, pkg := range packages {
+		add(packagesKey+"."+pkg, c.Logging.Packages[pkg])
+	}
+	return out
 }
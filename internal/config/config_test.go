@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+)
+
+// lookup returns the effective value and source of key.
+func lookup(cfg *Config, key string) Setting {
+	for _, s := range cfg.Settings() {
+		if s.Key == key {
+			return s
+		}
+	}
+	return Setting{}
+}
+
+func TestLoad(t *testing.T) {
+	runner.Run(t, "Config loading", func(t provider.T) {
+		dir := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+		t.Setenv(EnvPath, "")
+		t.Chdir(dir)
+
+		t.WithNewStep("defaults without a config file", func(s provider.StepCtx) {
+			cfg, err := Load("")
+			s.Require().NoError(err)
+			s.Assert().Empty(cfg.Path)
+			s.Assert().False(cfg.EnableLogging)
+			s.Assert().Equal(runtime.NumCPU(), cfg.Defaults.Workers)
+			s.Assert().Equal("default", lookup(cfg, "defaults.workers").Source)
+			s.Assert().Equal("false", lookup(cfg, "enable_logging").Value)
+		})
+
+		t.WithNewStep("json, yaml and toml files", func(s provider.StepCtx) {
+			files := map[string]string{
+				"c.json": `{"enable_logging": true, "logging": {"level": "warn", "packages": {"compression": "debug"}}, "defaults": {"compression_level": 9, "workers": 2}}`,
+				"c.yaml": "enable_logging: true\nlogging:\n  level: warn\n  packages:\n    compression: debug\ndefaults:\n  compression_level: 9\n  workers: 2\n",
+				"c.toml": "enable_logging = true\n[logging]\nlevel = \"warn\"\n[logging.packages]\ncompression = \"debug\"\n[defaults]\ncompression_level = 9\nworkers = 2\n",
+			}
+			for name, content := range files {
+				path := filepath.Join(dir, name)
+				s.Require().NoError(os.WriteFile(path, []byte(content), 0600))
+				cfg, err := Load(path)
+				s.Require().NoError(err, name)
+				s.Assert().Equal(path, cfg.Path)
+				s.Assert().True(cfg.EnableLogging, name)
+				s.Assert().Equal("warn", cfg.Logging.Level, name)
+				s.Assert().Equal(map[string]string{"compression": "debug"}, cfg.Logging.Packages, name)
+				s.Assert().Equal(9, cfg.Defaults.CompressionLevel, name)
+				s.Assert().Equal(2, cfg.Defaults.Workers, name)
+				s.Assert().Equal(path, lookup(cfg, "logging.packages.compression").Source)
+				s.Assert().Equal("default", lookup(cfg, "logging.format").Source)
+			}
+		})
+
+		t.WithNewStep("invalid files", func(s provider.StepCtx) {
+			for name, content := range map[string]string{
+				"unknown.json": `{"enable_loging": true}`,
+				"level.yaml":   "defaults:\n  compression_level: 11\n",
+				"method.yaml":  "defaults:\n  calculation_method: abacus\n",
+				"workers.toml": "[defaults]\nworkers = 0\n",
+				"list.yaml":    "logging:\n  packages: [core]\n",
+				"bad.json":     `{`,
+				"config.ini":   "enable_logging=true",
+			} {
+				path := filepath.Join(dir, name)
+				s.Require().NoError(os.WriteFile(path, []byte(content), 0600))
+				_, err := Load(path)
+				s.Assert().Error(err, name)
+			}
+			_, err := Load(filepath.Join(dir, "missing.yaml"))
+			s.Assert().Error(err, "a config file named explicitly must exist")
+		})
+
+		t.WithNewStep("discovery order", func(s provider.StepCtx) {
+			s.Require().NoError(os.WriteFile("config.toml", []byte("[defaults]\nworkers = 3\n"), 0600))
+			path, err := Discover("")
+			s.Require().NoError(err)
+			s.Assert().Equal("config.toml", path)
+
+			xdg := filepath.Join(dir, "xdg", "file-processing", "config.yaml")
+			s.Require().NoError(os.MkdirAll(filepath.Dir(xdg), 0700))
+			s.Require().NoError(os.WriteFile(xdg, []byte("defaults:\n  workers: 4\n"), 0600))
+			path, err = Discover("")
+			s.Require().NoError(err)
+			s.Assert().Equal(xdg, path, "the user config directory comes before the current directory")
+
+			env := filepath.Join(dir, "c.json")
+			t.Setenv(EnvPath, env)
+			path, err = Discover("")
+			s.Require().NoError(err)
+			s.Assert().Equal(env, path)
+
+			path, err = Discover("config.toml")
+			s.Require().NoError(err)
+			s.Assert().Equal("config.toml", path, "the flag comes first")
+			t.Setenv(EnvPath, "")
+		})
+
+		t.WithNewStep("environment overrides", func(s provider.StepCtx) {
+			path := filepath.Join(dir, "c.yaml")
+			t.Setenv("FILE_PROCESSING_LOGGING_LEVEL", "error")
+			t.Setenv("FILE_PROCESSING_LOGGING_PACKAGES", "core=debug, fileio=info")
+			t.Setenv("FILE_PROCESSING_DEFAULTS_CALCULATION_METHOD", "Parser")
+			t.Setenv("FILE_PROCESSING_UNRELATED", "ignored")
+			cfg, err := Load(path)
+			s.Require().NoError(err)
+			s.Assert().Equal("error", cfg.Logging.Level)
+			s.Assert().Equal(map[string]string{"compression": "debug", "core": "debug", "fileio": "info"}, cfg.Logging.Packages)
+			s.Assert().Equal("parser", cfg.Defaults.CalculationMethod)
+			s.Assert().Equal("FILE_PROCESSING_LOGGING_LEVEL", lookup(cfg, "logging.level").Source)
+			s.Assert().Equal("FILE_PROCESSING_LOGGING_PACKAGES", lookup(cfg, "logging.packages.core").Source)
+			s.Assert().Equal(path, lookup(cfg, "logging.packages.compression").Source)
+
+			t.Setenv("FILE_PROCESSING_DEFAULTS_WORKERS", "many")
+			_, err = Load(path)
+			s.Assert().Error(err)
+			t.Setenv("FILE_PROCESSING_DEFAULTS_WORKERS", "")
+			_, err = Load(path)
+			s.Assert().NoError(err, "empty variables are ignored")
+			t.Setenv("FILE_PROCESSING_LOGGING_PACKAGES", "core")
+			_, err = Load(path)
+			s.Assert().Error(err)
+		})
+	})
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	calc_const "github.com/dzibukalexander/file-processing/internal/calculation/constants"
+	comp_const "github.com/dzibukalexander/file-processing/internal/compression/constants"
+)
+
+// packagesKey holds per-package log levels as packagesKey.<package>.
+const packagesKey = "logging.packages"
+
+// setting reads and parses one configuration value by its dotted key.
+type setting struct {
+	key string
+	get func(*Config) string
+	set func(*Config, string) error
+}
+
+var settings = []setting{
+	{
+		key: "enable_logging",
+		get: func(c *Config) string { return strconv.FormatBool(c.EnableLogging) },
+		set: func(c *Config, v string) error { return parseBool(v, &c.EnableLogging) },
+	},
+	{
+		key: "logging.level",
+		get: func(c *Config) string { return c.Logging.Level },
+		set: func(c *Config, v string) error { c.Logging.Level = v; return nil },
+	},
+	{
+		key: "logging.format",
+		get: func(c *Config) string { return c.Logging.Format },
+		set: func(c *Config, v string) error { c.Logging.Format = v; return nil },
+	},
+	{
+		key: "logging.output",
+		get: func(c *Config) string { return c.Logging.Output },
+		set: func(c *Config, v string) error { c.Logging.Output = v; return nil },
+	},
+	{
+		key: "logging.max_size_mb",
+		get: func(c *Config) string { return strconv.Itoa(c.Logging.MaxSizeMB) },
+		set: func(c *Config, v string) error { return parseInt(v, 0, -1, &c.Logging.MaxSizeMB) },
+	},
+	{
+		key: "logging.max_backups",
+		get: func(c *Config) string { return strconv.Itoa(c.Logging.MaxBackups) },
+		set: func(c *Config, v string) error { return parseInt(v, 0, -1, &c.Logging.MaxBackups) },
+	},
+	{
+		key: "defaults.compression_level",
+		get: func(c *Config) string { return strconv.Itoa(c.Defaults.CompressionLevel) },
+		set: func(c *Config, v string) error {
+			return parseInt(v, 0, comp_const.MaxLevel, &c.Defaults.CompressionLevel)
+		},
+	},
+	{
+		key: "defaults.key_dir",
+		get: func(c *Config) string { return c.Defaults.KeyDir },
+		set: func(c *Config, v string) error {
+			if rest, ok := strings.CutPrefix(v, "~/"); ok {
+				home, err := os.UserHomeDir()
+				if err != nil {
+					return err
+				}
+				v = filepath.Join(home, rest)
+			}
+			c.Defaults.KeyDir = v
+			return nil
+		},
+	},
+	{
+		key: "defaults.calculation_method",
+		get: func(c *Config) string { return c.Defaults.CalculationMethod },
+		set: func(c *Config, v string) error {
+			if v != "" {
+				if _, err := calc_const.CalculationMethodFromString(strings.ToUpper(v)); err != nil {
+					return err
+				}
+			}
+			c.Defaults.CalculationMethod = strings.ToLower(v)
+			return nil
+		},
+	},
+	{
+		key: "defaults.workers",
+		get: func(c *Config) string { return strconv.Itoa(c.Defaults.Workers) },
+		set: func(c *Config, v string) error { return parseInt(v, 1, -1, &c.Defaults.Workers) },
+	},
+}
+
+func parseBool(v string, dst *bool) error {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("invalid boolean: %s", v)
+	}
+	*dst = b
+	return nil
+}
+
+// parseInt parses v into dst, requiring lo <= v and, unless hi is -1,
+// v <= hi.
+func parseInt(v string, lo, hi int, dst *int) error {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < lo || (hi >= 0 && n > hi) {
+		if hi < 0 {
+			return fmt.Errorf("invalid number: %s (want at least %d)", v, lo)
+		}
+		return fmt.Errorf("invalid number: %s (want %d to %d)", v, lo, hi)
+	}
+	*dst = n
+	return nil
+}
@@ -1,38 +1,127 @@
 package core
 
 import (
+	"context"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
-	"github.com/dzibukalexander/file-processing/internal/calculation"
-	calc_const "github.com/dzibukalexander/file-processing/internal/calculation/constants"
-	"github.com/dzibukalexander/file-processing/internal/compression"
-	comp_const "github.com/dzibukalexander/file-processing/internal/compression/constants"
-	"github.com/dzibukalexander/file-processing/internal/encryption"
-	enc_const "github.com/dzibukalexander/file-processing/internal/encryption/constants"
+	"github.com/dzibukalexander/file-processing/internal/catalog"
+	"github.com/dzibukalexander/file-processing/internal/config"
 	"github.com/dzibukalexander/file-processing/internal/fileio"
 	"github.com/dzibukalexander/file-processing/internal/fileio/constants"
+	"github.com/dzibukalexander/file-processing/internal/fileio/writer"
+	"github.com/dzibukalexander/file-processing/internal/keyring"
 	"github.com/dzibukalexander/file-processing/internal/logger"
+	"github.com/dzibukalexander/file-processing/internal/secmem"
+	"github.com/dzibukalexander/file-processing/pkg/fileprocessing"
 )
 
+// StdStream is the path that refers to standard input for Load and
+// standard output for ProcessFile.
+const StdStream = fileprocessing.StdStream
+
+// Operation defines a single, serializable processing step.
+type Operation = fileprocessing.Operation
+
 // Core is the central part of the application, managing data and the processing pipeline.
 type Core struct {
+	// originalData is the loaded data, held in loaded until ClearData.
 	originalData []byte
+	loaded       *secmem.Buffer
 	builder      *PipelineBuilder
+	stdin        io.Reader
+	stdout       io.Writer
+	catalog      *catalog.Catalog
+	keyring      *keyring.Keyring
+	defaults     config.DefaultsConfig
+
+	// stepData and stepIndex track progress through the pipeline made by Step.
+	stepData  []byte
+	stepIndex int
 }
 
-// NewCore creates a new Core instance.
+// NewCore creates a new Core instance using the user's pipeline catalog and
+// keyring, and the operation defaults of the application config.
 func NewCore() *Core {
-	return &Core{
+	c := &Core{
 		builder: NewPipelineBuilder(),
+		stdin:   os.Stdin,
+		stdout:  os.Stdout,
+	}
+	if config.AppConfig != nil {
+		c.defaults = config.AppConfig.Defaults
 	}
+	if cat, err := catalog.Default(); err == nil {
+		c.catalog = cat
+	} else {
+		logger.For("core").Warnf("Pipeline catalog unavailable: %v", err)
+	}
+	if c.defaults.KeyDir != "" {
+		c.keyring = keyring.New(c.defaults.KeyDir)
+	} else if kr, err := keyring.Default(); err == nil {
+		c.keyring = kr
+	} else {
+		logger.For("core").Warnf("Keyring unavailable: %v", err)
+	}
+	return c
+}
+
+// Catalog returns the pipeline catalog, or nil if there is none.
+func (c *Core) Catalog() *catalog.Catalog {
+	return c.catalog
+}
+
+// SetCatalog replaces the pipeline catalog; nil disables it.
+func (c *Core) SetCatalog(cat *catalog.Catalog) {
+	c.catalog = cat
+}
+
+// Keyring returns the keyring that key=<name> params refer to, or nil if
+// there is none.
+func (c *Core) Keyring() *keyring.Keyring {
+	return c.keyring
+}
+
+// SetKeyring replaces the keyring.
+func (c *Core) SetKeyring(kr *keyring.Keyring) {
+	c.keyring = kr
+}
+
+// SetDefaults replaces the operation defaults taken from the config.
+func (c *Core) SetDefaults(defaults config.DefaultsConfig) {
+	c.defaults = defaults
+}
+
+// pipelineOptions returns the options every pipeline run by c shares.
+func (c *Core) pipelineOptions() []fileprocessing.Option {
+	var opts []fileprocessing.Option
+	if c.keyring != nil {
+		opts = append(opts, fileprocessing.WithKeyStore(c.keyring))
+	}
+	if level := c.defaults.CompressionLevel; level != 0 {
+		opts = append(opts, fileprocessing.WithDefaultParams("compress", map[string]string{"level": strconv.Itoa(level)}))
+	}
+	if method := c.defaults.CalculationMethod; method != "" {
+		opts = append(opts, fileprocessing.WithDefaultParams("calculate", map[string]string{"type": method}))
+	}
+	return opts
 }
 
 // Load reads a file into memory and resets the processing pipeline.
+// A path of "-" reads from standard input.
 func (c *Core) Load(filePath string) error {
-	log := logger.GetInstance()
+	if filePath == StdStream {
+		return c.LoadFrom(c.stdin)
+	}
+
+	log := logger.For("core")
 	c.builder.Reset()
+	c.ClearData()
 	log.Debug("Pipeline builder reset")
 	fileType, err := constants.FileTypeFromExtension(filePath)
 	if err != nil {
@@ -47,7 +136,8 @@ func (c *Core) Load(filePath string) error {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	c.originalData = data
+	c.setData(data)
+	secmem.Wipe(data)
 	log.WithFields(map[string]interface{}{
 		"path": filePath,
 		"size": len(data),
@@ -55,41 +145,80 @@ func (c *Core) Load(filePath string) error {
 	return nil
 }
 
+// LoadFrom reads all data from r into memory and resets the processing pipeline.
+func (c *Core) LoadFrom(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		logger.For("core").Errorf("Failed to read input stream: %v", err)
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	defer secmem.Wipe(data)
+	return c.LoadBytes(data)
+}
+
+// LoadBytes uses a copy of data as the input to process and resets the
+// processing pipeline.
+func (c *Core) LoadBytes(data []byte) error {
+	c.builder.Reset()
+	c.setData(data)
+	logger.For("core").WithField("size", len(data)).Info("Data loaded successfully")
+	return nil
+}
+
+// setData replaces the loaded data and any Step output, wiping them, with a
+// copy of data kept in locked memory.
+func (c *Core) setData(data []byte) {
+	c.ClearData()
+	c.loaded = secmem.Copy(data)
+	if len(data) > 0 && !c.loaded.Locked() {
+		logger.For("core").Debug("Loaded data could not be locked into memory")
+	}
+	c.originalData = c.loaded.Bytes()
+}
+
+// ClearData wipes the loaded data and the output of Step from memory. The
+// pipeline is kept, so new data can be loaded and processed the same way.
+func (c *Core) ClearData() {
+	if c.loaded == nil {
+		return
+	}
+	secmem.Wipe(c.stepData)
+	c.ResetStepping()
+	c.loaded.Destroy()
+	c.loaded = nil
+	c.originalData = nil
+	logger.For("core").Debug("Loaded data wiped")
+}
+
+// Close wipes the loaded data. The Core can still be used afterwards.
+func (c *Core) Close() error {
+	c.ClearData()
+	return nil
+}
+
 // ProcessFile builds and runs the pipeline, then writes the result to a file.
-func (c *Core) ProcessFile(filePath string) error {
-	log := logger.GetInstance()
-	if c.originalData == nil {
-		log.Warn("ProcessFile called with no data loaded")
-		return fmt.Errorf("no data loaded to process")
+// A path of "-" writes to standard output. Cancelling ctx aborts the run.
+// Output params such as indent=2, sort_keys=true, canonical=true and
+// compact=true control how structured output formats are written.
+func (c *Core) ProcessFile(ctx context.Context, filePath string, params map[string]string) error {
+	if filePath == StdStream {
+		return c.ProcessTo(ctx, c.stdout, params)
 	}
-	log.Info("Starting file processing pipeline")
 
-	data := c.originalData
-	var err error
-
-	type Step func([]byte) ([]byte, error)
-
-	for i, op := range c.builder.operations {
-		log.WithFields(map[string]interface{}{
-			"step":      i + 1,
-			"operation": op.Name,
-			"params":    op.Params,
-		}).Debug("Executing pipeline step")
-		step, err_step := c.createStep(op.Name, op.Params)
-		if err_step != nil {
-			log.Errorf("Error creating step %d (%s): %v", i+1, op.Name, err_step)
-			return err_step
-		}
-		data, err = step(data)
-		if err != nil {
-			log.Errorf("Error processing step %d (%s): %v", i+1, op.Name, err)
-			return fmt.Errorf("error processing step '%s': %w", op.Name, err)
-		}
+	log := logger.For("core")
+	writerOpts, err := writer.ParseOptions(params)
+	if err != nil {
+		return fmt.Errorf("invalid output options: %w", err)
+	}
+
+	data, err := c.run(ctx, params)
+	if err != nil {
+		return err
 	}
 
 	outputType, _ := constants.FileTypeFromExtension(filePath)
-	writer := fileio.NewWriter(outputType)
-	if err := writer.Write(filePath, data); err != nil {
+	fileWriter := fileio.NewWriterWithOptions(outputType, writerOpts)
+	if err := fileWriter.Write(filePath, data); err != nil {
 		log.WithField("path", filePath).Errorf("Failed to write file: %v", err)
 		return fmt.Errorf("failed to write file: %w", err)
 	}
@@ -98,104 +227,380 @@ func (c *Core) ProcessFile(filePath string) error {
 	return nil
 }
 
-// Apply adds a processing step to the pipeline.
+// ProcessTo runs the pipeline and writes the result to w. Since there is no
+// file extension to go by, output options only take effect together with a
+// format=<json|yaml|xml> param.
+func (c *Core) ProcessTo(ctx context.Context, w io.Writer, params map[string]string) error {
+	log := logger.For("core")
+	writerOpts, err := writer.ParseOptions(params)
+	if err != nil {
+		return fmt.Errorf("invalid output options: %w", err)
+	}
+
+	outputType := constants.TEXT
+	if format, ok := params["format"]; ok {
+		outputType, err = constants.FileTypeFromString(strings.ToUpper(format))
+		if err != nil {
+			return err
+		}
+	} else if !writerOpts.IsZero() {
+		return fmt.Errorf("output options require a format param when writing to a stream")
+	}
+
+	data, err := c.run(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	data, err = fileio.Format(outputType, writerOpts, data)
+	if err != nil {
+		return fmt.Errorf("failed to format output: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		log.Errorf("Failed to write output stream: %v", err)
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	log.WithField("size", len(data)).Info("Data processed and written successfully")
+	return nil
+}
+
+// run executes every pipeline step over the loaded data and returns the result.
+// With a keep_intermediate param each step's output is also written to that directory.
+func (c *Core) run(ctx context.Context, params map[string]string) ([]byte, error) {
+	log := logger.For("core")
+	if c.originalData == nil {
+		log.Warn("Pipeline run with no data loaded")
+		return nil, fmt.Errorf("no data loaded to process")
+	}
+	if err := c.checkClosed(); err != nil {
+		return nil, err
+	}
+	log.Info("Starting file processing pipeline")
+
+	opts := c.pipelineOptions()
+	if dir := params[KeepIntermediateParam]; dir != "" {
+		dump, err := intermediateDumper(dir)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, fileprocessing.WithStepHook(dump))
+	}
+	ops, err := c.builder.Resolved(c.searchDirs())
+	if err != nil {
+		return nil, err
+	}
+	return fileprocessing.FromOperations(ops, opts...).Run(ctx, c.originalData)
+}
+
+// Apply adds a processing step to the pipeline. The "if" and "try" operations
+// open a block that collects the following steps; "else" (or "fallback" for
+// try) switches to the alternative branch and "end" closes the block.
 func (c *Core) Apply(operation string, params map[string]string) error {
-	op := &Operation{
-		Name:   operation,
-		Params: params,
+	log := logger.For("core")
+	switch operation {
+	case "else", "fallback", "end":
+		if len(params) > 0 {
+			return fmt.Errorf("%s takes no params", operation)
+		}
+	}
+
+	switch operation {
+	case "else", "fallback":
+		if operation == "fallback" && c.builder.OpenBlock() != "try" {
+			return fmt.Errorf("fallback without an open try block")
+		}
+		if err := c.builder.Else(); err != nil {
+			return err
+		}
+		log.WithField("block", c.builder.OpenBlock()).Info("Started alternative branch")
+		return nil
+	case "end":
+		name, err := c.builder.End()
+		if err != nil {
+			return err
+		}
+		log.WithField("block", name).Info("Block closed")
+		return nil
+	}
+
+	// Adding into a block changes a step that may already have been executed.
+	if c.builder.OpenBlock() != "" {
+		c.ResetStepping()
+	}
+	op, err := c.newOperation(operation, params)
+	if err != nil {
+		return err
+	}
+	if fileprocessing.IsBlock(operation) {
+		c.builder.Begin(op)
+	} else {
+		c.builder.Add(op)
 	}
-	c.builder.Add(op)
-	logger.GetInstance().WithFields(map[string]interface{}{
+	log.WithFields(map[string]interface{}{
 		"operation": op.Name,
 		"params":    op.Params,
 	}).Info("Step added to pipeline")
 	return nil
 }
 
-// SavePipeline saves the current pipeline to a file.
-func (c *Core) SavePipeline(filePath string) error {
-	log := logger.GetInstance()
-	err := c.builder.SaveToFile(filePath)
+// newOperation creates an operation, expanding it right away if it includes
+// another pipeline so errors surface when the step is added.
+func (c *Core) newOperation(name string, params map[string]string) (*Operation, error) {
+	op := fileprocessing.NewOperation(name, params)
+	if fileprocessing.IsInclude(name) {
+		if err := c.expand(op); err != nil {
+			return nil, err
+		}
+	}
+	return op, nil
+}
+
+// expand resolves an include or use operation relative to the working
+// directory, looking up "use" names in the catalog as well.
+func (c *Core) expand(op *Operation) error {
+	doc := fileprocessing.Document{Operations: []*Operation{op}}
+	return doc.ExpandIncludes("", c.searchDirs(), os.LookupEnv)
+}
+
+// searchDirs are the directories searched for pipelines referenced by name.
+func (c *Core) searchDirs() []string {
+	if c.catalog == nil {
+		return nil
+	}
+	return []string{c.catalog.Dir()}
+}
+
+// pipelinePath returns ref itself if it is an existing file, and otherwise
+// the path of the catalog pipeline named ref, if there is one.
+func (c *Core) pipelinePath(ref string) string {
+	if _, err := os.Stat(ref); err == nil || c.catalog == nil {
+		return ref
+	}
+	if path, err := c.catalog.Lookup(ref); err == nil {
+		return path
+	}
+	return ref
+}
+
+// checkClosed fails if an if or try block is still being built.
+func (c *Core) checkClosed() error {
+	if name := c.builder.OpenBlock(); name != "" {
+		return fmt.Errorf("unterminated %s block; finish it with 'apply end'", name)
+	}
+	return nil
+}
+
+// Steps returns the operations of the current pipeline in order.
+func (c *Core) Steps() []*Operation {
+	return c.builder.Operations()
+}
+
+// RemoveStep deletes the step at 1-based position n.
+func (c *Core) RemoveStep(n int) error {
+	if err := c.checkPosition(n); err != nil {
+		return err
+	}
+	return c.edited("Step removed", c.builder.Remove(n-1))
+}
+
+// InsertStep inserts an operation before the step at 1-based position n.
+// n may be one past the last step to append.
+func (c *Core) InsertStep(n int, operation string, params map[string]string) error {
+	if err := c.checkClosed(); err != nil {
+		return err
+	}
+	if n != c.builder.Len()+1 {
+		if err := c.checkPosition(n); err != nil {
+			return err
+		}
+	}
+	op, err := c.newOperation(operation, params)
 	if err != nil {
-		log.WithField("path", filePath).Errorf("Failed to save pipeline: %v", err)
 		return err
 	}
-	log.WithField("path", filePath).Info("Pipeline saved successfully")
+	return c.edited("Step inserted", c.builder.Insert(n-1, op))
+}
+
+// MoveStep moves the step at 1-based position from to position to.
+func (c *Core) MoveStep(from, to int) error {
+	if err := c.checkPosition(from); err != nil {
+		return err
+	}
+	if err := c.checkPosition(to); err != nil {
+		return err
+	}
+	return c.edited("Step moved", c.builder.Move(from-1, to-1))
+}
+
+// EditStep merges params into the step at 1-based position n. An empty value removes the param.
+func (c *Core) EditStep(n int, params map[string]string) error {
+	if err := c.checkPosition(n); err != nil {
+		return err
+	}
+	op := c.builder.operations[n-1]
+	if !fileprocessing.IsInclude(op.Name) {
+		return c.edited("Step edited", c.builder.Edit(n-1, params))
+	}
+
+	// Expand a copy first so a bad path leaves the step untouched.
+	probe := cloneOperation(op)
+	mergeParams(probe.Params, params)
+	if err := c.expand(probe); err != nil {
+		return err
+	}
+	if err := c.builder.Edit(n-1, params); err != nil {
+		return err
+	}
+	return c.edited("Step edited", c.expand(op))
+}
+
+// ClearPipeline removes every step, including any unfinished block; it can be undone.
+func (c *Core) ClearPipeline() {
+	c.builder.Clear()
+	_ = c.edited("Pipeline cleared", nil)
+}
+
+// Undo reverts the last pipeline edit.
+func (c *Core) Undo() error {
+	if err := c.checkClosed(); err != nil {
+		return err
+	}
+	if !c.builder.Undo() {
+		return fmt.Errorf("nothing to undo")
+	}
+	return c.edited("Pipeline edit undone", nil)
+}
+
+// Redo re-applies the last undone pipeline edit.
+func (c *Core) Redo() error {
+	if err := c.checkClosed(); err != nil {
+		return err
+	}
+	if !c.builder.Redo() {
+		return fmt.Errorf("nothing to redo")
+	}
+	return c.edited("Pipeline edit redone", nil)
+}
+
+func (c *Core) checkPosition(n int) error {
+	if err := c.checkClosed(); err != nil {
+		return err
+	}
+	if n < 1 || n > c.builder.Len() {
+		return fmt.Errorf("no step %d: pipeline has %d steps", n, c.builder.Len())
+	}
 	return nil
 }
 
-// LoadPipeline loads a pipeline from a file.
-func (c *Core) LoadPipeline(filePath string) error {
-	log := logger.GetInstance()
-	c.builder.Reset()
-	log.Debug("Pipeline builder reset before loading")
-	err := c.builder.LoadFromFile(filePath)
+// edited logs a successful pipeline edit and restarts stepping, since
+// executed steps may no longer match the pipeline.
+func (c *Core) edited(msg string, err error) error {
 	if err != nil {
-		log.WithField("path", filePath).Errorf("Failed to load pipeline: %v", err)
 		return err
 	}
-	log.WithField("path", filePath).Info("Pipeline loaded successfully")
+	c.ResetStepping()
+	logger.For("core").WithField("steps", c.builder.Len()).Info(msg)
 	return nil
 }
 
-func (c *Core) createStep(operation string, params map[string]string) (func([]byte) ([]byte, error), error) {
-	switch operation {
-	case "compress":
-		compType, err := comp_const.CompressionTypeFromString(strings.ToUpper(params["type"]))
-		if err != nil {
-			return nil, err
+// SavePipeline saves the current pipeline to a file. The name, description,
+// author and tags (comma-separated) params update the pipeline metadata
+// before saving.
+func (c *Core) SavePipeline(filePath string, params map[string]string) error {
+	log := logger.For("core")
+	if err := c.checkClosed(); err != nil {
+		return err
+	}
+	info := c.builder.Info()
+	name, description, author, tags := info.Name, info.Description, info.Author, info.Tags
+	for k, v := range params {
+		switch k {
+		case "name":
+			name = v
+		case "description":
+			description = v
+		case "author":
+			author = v
+		case "tags":
+			tags = splitTags(v)
+		default:
+			return fmt.Errorf("unknown pipeline metadata field: %s", k)
 		}
-		compressor := compression.NewCompressor(compType)
-		return compressor.Compress, nil
+	}
+	c.builder.SetInfo(name, description, author, tags)
+	err := c.builder.SaveToFile(filePath)
+	if err != nil {
+		log.WithField("path", filePath).Errorf("Failed to save pipeline: %v", err)
+		return err
+	}
+	log.WithField("path", filePath).Info("Pipeline saved successfully")
+	return nil
+}
 
-	case "decompress":
-		compType, err := comp_const.CompressionTypeFromString(strings.ToUpper(params["type"]))
-		if err != nil {
-			return nil, err
+// SaveToCatalog saves the current pipeline in the catalog under name, which
+// also becomes the pipeline name unless a name param is given.
+func (c *Core) SaveToCatalog(name string, params map[string]string) error {
+	if c.catalog == nil {
+		return fmt.Errorf("no pipeline catalog is available")
+	}
+	path, err := c.catalog.SavePath(name)
+	if err != nil {
+		return err
+	}
+	if _, ok := params["name"]; !ok {
+		params = maps.Clone(params)
+		if params == nil {
+			params = map[string]string{}
 		}
-		decompressor := compression.NewDecompressor(compType)
-		return decompressor.Decompress, nil
+		params["name"] = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return c.SavePipeline(path, params)
+}
 
-	case "encrypt":
-		encType, err := enc_const.EncryptionTypeFromString(strings.ToUpper(params["type"]))
-		if err != nil {
-			return nil, err
-		}
-		key, err := ioutil.ReadFile(params["key_file"])
-		if err != nil {
-			return nil, fmt.Errorf("failed to read key file: %w", err)
+// splitTags parses a comma-separated tag list, dropping empty entries.
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
 		}
-		encryptor := encryption.NewEncryptor(encType)
-		return func(data []byte) ([]byte, error) {
-			return encryptor.Encrypt(data, key)
-		}, nil
+	}
+	return tags
+}
 
-	case "decrypt":
-		encType, err := enc_const.EncryptionTypeFromString(strings.ToUpper(params["type"]))
-		if err != nil {
-			return nil, err
-		}
-		key, err := ioutil.ReadFile(params["key_file"])
-		if err != nil {
-			return nil, fmt.Errorf("failed to read key file: %w", err)
-		}
-		decryptor := encryption.NewDecryptor(encType)
-		return func(data []byte) ([]byte, error) {
-			return decryptor.Decrypt(data, key)
-		}, nil
+// PipelineInfo returns the metadata of the current pipeline.
+func (c *Core) PipelineInfo() fileprocessing.Document {
+	return c.builder.Info()
+}
 
-	case "calculate":
-		calcMethod, err := calc_const.CalculationMethodFromString(strings.ToUpper(params["type"]))
-		if err != nil {
-			return nil, err
-		}
-		calculator := calculation.NewCalculator(calcMethod)
-		return func(data []byte) ([]byte, error) {
-			res, err := calculator.Calculate(string(data))
-			return []byte(res), err
-		}, nil
+// LoadPipeline loads a pipeline from a file, or from the catalog if filePath
+// is the name of a saved pipeline rather than a file. Template variables are
+// resolved from vars, then the environment, then the defaults declared in the file.
+func (c *Core) LoadPipeline(filePath string, vars map[string]string) error {
+	log := logger.For("core")
+	c.builder.Reset()
+	c.ResetStepping()
+	log.Debug("Pipeline builder reset before loading")
+	filePath = c.pipelinePath(filePath)
+	err := c.builder.LoadFromFile(filePath, vars, c.searchDirs())
+	if err != nil {
+		log.WithField("path", filePath).Errorf("Failed to load pipeline: %v", err)
+		return err
+	}
+	log.WithField("path", filePath).Info("Pipeline loaded successfully")
+	return nil
+}
 
-	default:
-		return nil, fmt.Errorf("unknown operation: %s", operation)
+// Run loads inputPath, loads the pipeline at pipelinePath (or saved in the
+// catalog under that name) with vars and writes the result to outputPath.
+// Either data path may be "-".
+func (c *Core) Run(ctx context.Context, pipelinePath, inputPath, outputPath string, vars map[string]string) error {
+	if err := c.Load(inputPath); err != nil {
+		return err
+	}
+	if err := c.LoadPipeline(pipelinePath, vars); err != nil {
+		return err
 	}
+	return c.ProcessFile(ctx, outputPath, nil)
 }
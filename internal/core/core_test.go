@@ -1,11 +1,21 @@
 package core
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/dzibukalexander/file-processing/internal/catalog"
+	"github.com/dzibukalexander/file-processing/internal/config"
+	"github.com/dzibukalexander/file-processing/internal/detect"
+	"github.com/dzibukalexander/file-processing/internal/keyring"
+	"github.com/dzibukalexander/file-processing/pkg/fileprocessing"
 	"github.com/ozontech/allure-go/pkg/framework/provider"
 	"github.com/ozontech/allure-go/pkg/framework/runner"
 )
@@ -71,7 +81,7 @@ func TestCore_ProcessFile(t *testing.T) {
 			s.Require().NoError(core.Apply("calculate", map[string]string{"type": "library"}))
 
 			outputPath := filepath.Join(tempDir, "output.txt")
-			s.Require().NoError(core.ProcessFile(outputPath))
+			s.Require().NoError(core.ProcessFile(context.Background(), outputPath, nil))
 
 			outputData, err := ioutil.ReadFile(outputPath)
 			s.Require().NoError(err)
@@ -91,13 +101,534 @@ func TestCore_SaveLoadPipeline(t *testing.T) {
 			s.Require().NoError(core.Apply("compress", map[string]string{"type": "gzip"}))
 
 			pipelinePath := filepath.Join(tempDir, "pipeline.json")
-			s.Require().NoError(core.SavePipeline(pipelinePath))
+			s.Require().NoError(core.SavePipeline(pipelinePath, nil))
 
 			newCore := NewCore()
-			s.Require().NoError(newCore.LoadPipeline(pipelinePath))
+			s.Require().NoError(newCore.LoadPipeline(pipelinePath, nil))
 			s.Assert().Len(newCore.builder.operations, 2)
 			s.Assert().Equal("encrypt", newCore.builder.operations[0].Name)
 			s.Assert().Equal("compress", newCore.builder.operations[1].Name)
 		})
+
+		t.WithNewStep("variables survive loading and saving", func(s provider.StepCtx) {
+			templatePath := filepath.Join(tempDir, "template.json")
+			s.Require().NoError(os.WriteFile(templatePath, []byte(`{
+				"schema_version": 2,
+				"variables": [{"name": "METHOD"}],
+				"operations": [{"name": "calculate", "params": {"type": "${METHOD}"}}]
+			}`), 0644))
+
+			core := NewCore()
+			s.Require().NoError(core.LoadBytes([]byte("1 + 2")))
+			s.Require().NoError(core.LoadPipeline(templatePath, map[string]string{"METHOD": "library"}))
+			var out bytes.Buffer
+			s.Require().NoError(core.ProcessTo(context.Background(), &out, nil))
+			s.Assert().Equal("3", out.String())
+
+			savedPath := filepath.Join(tempDir, "saved.json")
+			s.Require().NoError(core.SavePipeline(savedPath, nil))
+			saved, err := fileprocessing.ReadDocument(savedPath)
+			s.Require().NoError(err)
+			s.Assert().Equal("${METHOD}", saved.Operations[0].Params["type"])
+			s.Require().Len(saved.Variables, 1)
+			s.Assert().Equal("METHOD", saved.Variables[0].Name)
+		})
+	})
+}
+
+func TestCore_StreamsAndBytes(t *testing.T) {
+	runner.Run(t, "Core stdin/stdout and in-memory data", func(t provider.T) {
+		t.WithNewStep("load bytes and process to writer", func(s provider.StepCtx) {
+			core := NewCore()
+			s.Require().NoError(core.LoadBytes([]byte("2 * 3")))
+			s.Require().NoError(core.Apply("calculate", map[string]string{"type": "library"}))
+
+			var out bytes.Buffer
+			s.Require().NoError(core.ProcessTo(context.Background(), &out, nil))
+			s.Assert().Equal("6", out.String())
+		})
+
+		t.WithNewStep("dash means stdin and stdout", func(s provider.StepCtx) {
+			core := NewCore()
+			var out bytes.Buffer
+			core.stdin = strings.NewReader(`{"b":1,"a":2}`)
+			core.stdout = &out
+
+			s.Require().NoError(core.Load(StdStream))
+			s.Require().NoError(core.ProcessFile(context.Background(), StdStream, map[string]string{"format": "json", "sort_keys": "true"}))
+			s.Assert().Equal(`{"a":2,"b":1}`, out.String())
+		})
+
+		t.WithNewStep("options without format", func(s provider.StepCtx) {
+			core := NewCore()
+			s.Require().NoError(core.LoadBytes([]byte(`{}`)))
+			s.Assert().Error(core.ProcessTo(context.Background(), &bytes.Buffer{}, map[string]string{"indent": "2"}))
+		})
+
+		t.WithNewStep("clear data wipes loaded data and step output", func(s provider.StepCtx) {
+			core := NewCore()
+			input := []byte("2 * 3")
+			s.Require().NoError(core.LoadBytes(input))
+			s.Assert().Equal("2 * 3", string(input), "the caller's slice is copied, not wiped")
+			s.Require().NoError(core.Apply("calculate", map[string]string{"type": "library"}))
+			_, err := core.Step(context.Background())
+			s.Require().NoError(err)
+			loaded, stepped := core.originalData, core.stepData
+
+			core.ClearData()
+			s.Assert().Equal(make([]byte, len(loaded)), loaded)
+			s.Assert().Equal(make([]byte, len(stepped)), stepped)
+			_, err = core.Peek()
+			s.Assert().Error(err)
+			s.Assert().Len(core.Steps(), 1, "the pipeline is kept")
+
+			s.Require().NoError(core.LoadBytes([]byte("4 * 5")))
+			s.Require().NoError(core.Apply("calculate", map[string]string{"type": "library"}))
+			var out bytes.Buffer
+			s.Require().NoError(core.ProcessTo(context.Background(), &out, nil))
+			s.Assert().Equal("20", out.String())
+			s.Assert().NoError(core.Close())
+			_, err = core.run(context.Background(), nil)
+			s.Assert().Error(err)
+		})
+	})
+}
+
+func TestCore_Debugging(t *testing.T) {
+	tempDir, cleanup := setupTest(t)
+	defer cleanup()
+
+	runner.Run(t, "Core step, peek and intermediate output", func(t provider.T) {
+		t.WithNewStep("step and peek", func(s provider.StepCtx) {
+			core := NewCore()
+			s.Require().NoError(core.LoadBytes([]byte("4 * 5")))
+			s.Require().NoError(core.Apply("calculate", map[string]string{"type": "parser"}))
+			s.Require().NoError(core.Apply("compress", map[string]string{"type": "gzip"}))
+
+			info, err := core.Peek()
+			s.Require().NoError(err)
+			s.Assert().Equal(0, info.Step)
+			s.Assert().Equal("4 * 5", string(info.Data))
+
+			result, err := core.Step(context.Background())
+			s.Require().NoError(err)
+			s.Assert().Equal(1, result.Index)
+			info, err = core.Peek()
+			s.Require().NoError(err)
+			s.Assert().Equal("20", string(info.Data))
+
+			_, err = core.Step(context.Background())
+			s.Require().NoError(err)
+			info, err = core.Peek()
+			s.Require().NoError(err)
+			s.Assert().Equal(detect.GZIP, info.Type)
+
+			_, err = core.Step(context.Background())
+			s.Assert().Error(err)
+
+			core.ResetStepping()
+			info, err = core.Peek()
+			s.Require().NoError(err)
+			s.Assert().Equal(0, info.Step)
+		})
+
+		t.WithNewStep("keep intermediate", func(s provider.StepCtx) {
+			core := NewCore()
+			s.Require().NoError(core.LoadBytes([]byte("1 + 2")))
+			s.Require().NoError(core.Apply("calculate", map[string]string{"type": "parser"}))
+			s.Require().NoError(core.Apply("compress", map[string]string{"type": "zip"}))
+
+			dir := filepath.Join(tempDir, "steps")
+			params := map[string]string{KeepIntermediateParam: dir}
+			s.Require().NoError(core.ProcessFile(context.Background(), filepath.Join(tempDir, "out.txt"), params))
+
+			first, err := os.ReadFile(filepath.Join(dir, "step-01-calculate.bin"))
+			s.Require().NoError(err)
+			s.Assert().Equal("3", string(first))
+			_, err = os.Stat(filepath.Join(dir, "step-02-compress.bin"))
+			s.Assert().NoError(err)
+		})
+	})
+}
+
+func TestPipelineBuilder_Editing(t *testing.T) {
+	names := func(b *PipelineBuilder) []string {
+		var out []string
+		for _, op := range b.Operations() {
+			out = append(out, op.Name)
+		}
+		return out
+	}
+
+	runner.Run(t, "PipelineBuilder editing", func(t provider.T) {
+		t.WithNewStep("remove, insert, move, edit", func(s provider.StepCtx) {
+			b := NewPipelineBuilder()
+			b.Add(&Operation{Name: "a", Params: map[string]string{}})
+			b.Add(&Operation{Name: "b", Params: map[string]string{}})
+			b.Add(&Operation{Name: "c", Params: map[string]string{}})
+
+			s.Require().NoError(b.Remove(1))
+			s.Assert().Equal([]string{"a", "c"}, names(b))
+
+			s.Require().NoError(b.Insert(0, &Operation{Name: "z"}))
+			s.Require().NoError(b.Insert(3, &Operation{Name: "end"}))
+			s.Assert().Equal([]string{"z", "a", "c", "end"}, names(b))
+
+			s.Require().NoError(b.Move(0, 3))
+			s.Assert().Equal([]string{"a", "c", "end", "z"}, names(b))
+			s.Require().NoError(b.Move(2, 0))
+			s.Assert().Equal([]string{"end", "a", "c", "z"}, names(b))
+
+			s.Require().NoError(b.Edit(1, map[string]string{"type": "gzip"}))
+			s.Assert().Equal("gzip", b.Operations()[1].Params["type"])
+
+			s.Assert().Error(b.Remove(4))
+			s.Assert().Error(b.Insert(6, &Operation{Name: "x"}))
+		})
+
+		t.WithNewStep("undo and redo", func(s provider.StepCtx) {
+			b := NewPipelineBuilder()
+			b.Add(&Operation{Name: "a", Params: map[string]string{"type": "zip"}})
+			s.Require().NoError(b.Edit(0, map[string]string{"type": "gzip"}))
+			b.Clear()
+			s.Assert().Empty(names(b))
+
+			s.Assert().True(b.Undo())
+			s.Assert().Equal("gzip", b.Operations()[0].Params["type"])
+			s.Assert().True(b.Undo())
+			s.Assert().Equal("zip", b.Operations()[0].Params["type"])
+			s.Assert().True(b.Redo())
+			s.Assert().Equal("gzip", b.Operations()[0].Params["type"])
+
+			b.Add(&Operation{Name: "b"})
+			s.Assert().False(b.Redo())
+			s.Assert().True(b.Undo())
+			s.Assert().True(b.Undo())
+			s.Assert().True(b.Undo())
+			s.Assert().False(b.Undo())
+		})
+
+		t.WithNewStep("core positions are 1-based", func(s provider.StepCtx) {
+			core := NewCore()
+			s.Require().NoError(core.Apply("compress", map[string]string{"type": "gzip"}))
+			s.Require().NoError(core.InsertStep(1, "calculate", map[string]string{"type": "parser"}))
+			s.Assert().Equal("calculate", core.Steps()[0].Name)
+			s.Assert().Error(core.RemoveStep(0))
+			s.Assert().Error(core.MoveStep(1, 3))
+			s.Require().NoError(core.RemoveStep(2))
+			s.Assert().Len(core.Steps(), 1)
+		})
+	})
+}
+
+func TestCore_Blocks(t *testing.T) {
+	runner.Run(t, "Core control-flow blocks", func(t provider.T) {
+		t.WithNewStep("build and run if/else", func(s provider.StepCtx) {
+			core := NewCore()
+			s.Require().NoError(core.LoadBytes([]byte("2 + 3")))
+			s.Require().NoError(core.Apply("if", map[string]string{"type": "json"}))
+			s.Require().NoError(core.Apply("compress", map[string]string{"type": "gzip"}))
+			s.Require().NoError(core.Apply("else", nil))
+			s.Require().NoError(core.Apply("calculate", map[string]string{"type": "library"}))
+
+			_, err := core.run(context.Background(), nil)
+			s.Require().Error(err)
+			s.Assert().Contains(err.Error(), "unterminated if block")
+			s.Assert().Error(core.Undo())
+			s.Assert().Error(core.Apply("fallback", nil))
+
+			s.Require().NoError(core.Apply("end", nil))
+			s.Assert().Error(core.Apply("end", nil))
+			s.Require().Len(core.Steps(), 1)
+			s.Assert().Len(core.Steps()[0].Steps, 1)
+			s.Assert().Len(core.Steps()[0].Else, 1)
+
+			out, err := core.run(context.Background(), nil)
+			s.Require().NoError(err)
+			s.Assert().Equal("5", string(out))
+		})
+
+		t.WithNewStep("undo restores nested steps", func(s provider.StepCtx) {
+			core := NewCore()
+			s.Require().NoError(core.Apply("try", nil))
+			s.Require().NoError(core.Apply("decompress", map[string]string{"type": "gzip"}))
+			s.Require().NoError(core.Apply("fallback", nil))
+			s.Require().NoError(core.Apply("end", nil))
+			s.Require().NoError(core.Apply("compress", map[string]string{"type": "zip"}))
+			s.Require().NoError(core.Undo())
+			s.Require().NoError(core.Undo())
+			s.Require().Len(core.Steps(), 1)
+			s.Assert().Empty(core.Steps()[0].Steps)
+		})
+	})
+}
+
+func TestCore_Include(t *testing.T) {
+	dir := t.TempDir()
+	shared := filepath.Join(dir, "pack.json")
+	other := filepath.Join(dir, "other.json")
+	if err := os.WriteFile(shared, []byte(`{"schema_version": 3, "operations": [{"name": "compress", "params": {"type": "${ALGO:-gzip}"}}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(other, []byte(`{"schema_version": 3, "operations": [{"name": "calculate", "params": {"type": "library"}}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runner.Run(t, "Core include steps", func(t provider.T) {
+		t.WithNewStep("apply and edit include", func(s provider.StepCtx) {
+			core := NewCore()
+			s.Require().NoError(core.Apply("include", map[string]string{"path": shared, "ALGO": "zip"}))
+			s.Require().Len(core.Steps()[0].Steps, 1)
+			s.Assert().Equal("zip", core.Steps()[0].Steps[0].Params["type"])
+
+			s.Assert().Error(core.EditStep(1, map[string]string{"path": filepath.Join(dir, "missing.json")}))
+			s.Assert().Equal(shared, core.Steps()[0].Params["path"])
+
+			s.Require().NoError(core.EditStep(1, map[string]string{"path": other, "ALGO": ""}))
+			s.Assert().Equal("calculate", core.Steps()[0].Steps[0].Name)
+			s.Require().NoError(core.LoadBytes([]byte("1 + 2")))
+			s.Require().NoError(core.Apply("include", map[string]string{"path": other}))
+			out, err := core.run(context.Background(), nil)
+			s.Require().NoError(err)
+			s.Assert().Equal("3", string(out))
+
+			s.Assert().Error(core.Apply("include", map[string]string{"path": filepath.Join(dir, "missing.json")}))
+		})
+	})
+}
+
+func TestCore_Catalog(t *testing.T) {
+	runner.Run(t, "Core pipeline catalog", func(t provider.T) {
+		t.WithNewStep("save and run by name", func(s provider.StepCtx) {
+			dir := t.TempDir()
+			core := NewCore()
+			core.SetCatalog(catalog.New(filepath.Join(dir, "pipelines")))
+			s.Require().NoError(core.Apply("calculate", map[string]string{"type": "library"}))
+			s.Require().NoError(core.SaveToCatalog("sum", map[string]string{"tags": "calc, math", "description": "adds"}))
+
+			info := core.PipelineInfo()
+			s.Assert().Equal("sum", info.Name)
+			s.Assert().Equal([]string{"calc", "math"}, info.Tags)
+
+			input := filepath.Join(dir, "in.txt")
+			output := filepath.Join(dir, "out.txt")
+			s.Require().NoError(os.WriteFile(input, []byte("4 + 5"), 0644))
+			s.Require().NoError(core.Run(context.Background(), "sum", input, output, nil))
+			out, err := os.ReadFile(output)
+			s.Require().NoError(err)
+			s.Assert().Equal("9", string(out))
+
+			s.Require().NoError(core.LoadBytes([]byte("1 + 1")))
+			s.Require().NoError(core.Apply("use", map[string]string{"name": "sum"}))
+			s.Assert().Len(core.Steps()[0].Steps, 1)
+		})
+	})
+}
+
+func TestCore_Keyring(t *testing.T) {
+	runner.Run(t, "Core keyring and rekey", func(t provider.T) {
+		ctx := context.Background()
+		dir := t.TempDir()
+		core := NewCore()
+		core.SetKeyring(keyring.New(filepath.Join(dir, "keys")))
+
+		t.WithNewStep("generate, export and import keys", func(s provider.StepCtx) {
+			for name, algorithm := range map[string]string{"old": "aes", "new": "chacha20-poly1305", "signer": "ed25519", "team": "x25519"} {
+				key, err := core.GenerateKey(name, algorithm)
+				s.Require().NoError(err)
+				s.Assert().Equal(algorithm, key.Algorithm)
+			}
+			_, err := core.GenerateKey("old", "aes")
+			s.Assert().True(errors.Is(err, keyring.ErrExists))
+			_, err = core.GenerateKey("bad", "multi")
+			s.Assert().Error(err)
+
+			files, err := core.ExportKey("signer", filepath.Join(dir, "signer"), true)
+			s.Require().NoError(err)
+			s.Assert().Equal([]string{filepath.Join(dir, "signer.pub")}, files)
+			key, err := core.ImportKey("signer-pub", "ed25519", filepath.Join(dir, "signer.pub"))
+			s.Require().NoError(err)
+			s.Assert().Equal("public", key.Kind())
+			signer, err := core.Keyring().Get("signer")
+			s.Require().NoError(err)
+			s.Assert().Equal(signer.Fingerprint, key.Fingerprint)
+
+			exposed := filepath.Join(dir, "exposed")
+			s.Require().NoError(os.WriteFile(exposed, []byte("old"), 0644))
+			_, err = core.ExportKey("old", exposed, false)
+			s.Assert().True(errors.Is(err, os.ErrExist), "secrets never replace an existing file")
+			files, err = core.ExportKey("old", filepath.Join(dir, "old.key"), false)
+			s.Require().NoError(err)
+			info, err := os.Stat(files[0])
+			s.Require().NoError(err)
+			s.Assert().Equal(os.FileMode(0600), info.Mode().Perm())
+		})
+
+		t.WithNewStep("key= params resolve from the keyring", func(s provider.StepCtx) {
+			s.Require().NoError(core.LoadBytes([]byte("keyring data")))
+			s.Require().NoError(core.Apply("sign", map[string]string{"key": "signer"}))
+			s.Require().NoError(core.Apply("encrypt", map[string]string{"key": "team"}))
+			s.Require().NoError(core.Apply("decrypt", map[string]string{"key": "team"}))
+			s.Require().NoError(core.Apply("verify-signature", map[string]string{"key": "signer-pub"}))
+			out, err := core.run(ctx, nil)
+			s.Require().NoError(err)
+			s.Assert().Equal("keyring data", string(out))
+
+			core.ClearPipeline()
+			s.Require().NoError(core.Apply("encrypt", map[string]string{"key": "missing"}))
+			_, err = core.run(ctx, nil)
+			s.Assert().True(errors.Is(err, keyring.ErrNotFound))
+		})
+
+		t.WithNewStep("x25519 keys still open multi-recipient data", func(s provider.StepCtx) {
+			// Before x25519 had its own format, encrypting with an x25519
+			// keyring key wrote the multi-recipient format.
+			files, err := core.ExportKey("team", filepath.Join(dir, "team"), true)
+			s.Require().NoError(err)
+			s.Require().NoError(core.LoadBytes([]byte("written before ecies")))
+			s.Require().NoError(core.Apply("encrypt", map[string]string{"type": "multi", "recipients": files[0]}))
+			sealed, err := core.run(ctx, nil)
+			s.Require().NoError(err)
+			s.Require().Equal("FPMR", string(sealed[:4]))
+
+			s.Require().NoError(core.LoadBytes(sealed))
+			s.Require().NoError(core.Apply("decrypt", map[string]string{"key": "team"}))
+			out, err := core.run(ctx, nil)
+			s.Require().NoError(err)
+			s.Assert().Equal("written before ecies", string(out))
+		})
+
+		t.WithNewStep("rekey replaces files encrypted with the old key", func(s provider.StepCtx) {
+			path := filepath.Join(dir, "secret.bin")
+			s.Require().NoError(core.LoadBytes([]byte("rotate me")))
+			core.ClearPipeline()
+			s.Require().NoError(core.Apply("encrypt", map[string]string{"key": "old"}))
+			encrypted, err := core.run(ctx, nil)
+			s.Require().NoError(err)
+			s.Require().NoError(os.WriteFile(path, encrypted, 0640))
+
+			done, err := core.Rekey(ctx, []string{path, filepath.Join(dir, "absent.bin")}, "old", "new")
+			s.Assert().Error(err)
+			s.Assert().Equal(1, done)
+
+			rekeyed, err := os.ReadFile(path)
+			s.Require().NoError(err)
+			info, err := os.Stat(path)
+			s.Require().NoError(err)
+			s.Assert().Equal(os.FileMode(0640), info.Mode().Perm())
+
+			s.Require().NoError(core.LoadBytes(rekeyed))
+			core.ClearPipeline()
+			s.Require().NoError(core.Apply("decrypt", map[string]string{"key": "new"}))
+			out, err := core.run(ctx, nil)
+			s.Require().NoError(err)
+			s.Assert().Equal("rotate me", string(out))
+
+			_, err = core.Rekey(ctx, []string{path}, "old", "new")
+			s.Assert().Error(err, "the file no longer opens with the old key")
+			again, err := os.ReadFile(path)
+			s.Require().NoError(err)
+			s.Assert().Equal(rekeyed, again, "a failed rekey leaves the file untouched")
+		})
+
+		t.WithNewStep("split and combine a key for escrow", func(s provider.StepCtx) {
+			shareDir := filepath.Join(dir, "shares")
+			files, err := core.SplitKey("old", 5, 3, shareDir, "")
+			s.Require().NoError(err)
+			s.Require().Len(files, 5)
+			s.Assert().Equal("old.share-2-of-5", filepath.Base(files[1]))
+
+			original, err := core.Keyring().Get("old")
+			s.Require().NoError(err)
+			key, err := core.CombineShares([]string{files[4], files[0], files[2]}, "", "old-restored")
+			s.Require().NoError(err)
+			s.Assert().Equal(original.Secret, key.Secret)
+			s.Assert().Equal(original.Fingerprint, key.Fingerprint)
+			_, err = core.CombineShares(files[:2], filepath.Join(dir, "too-few.key"), "")
+			s.Assert().Error(err)
+
+			keyFile := filepath.Join(dir, "archive.key")
+			s.Require().NoError(fileprocessing.GenerateKey(fileprocessing.AES, keyFile))
+			files, err = core.SplitKey(keyFile, 3, 2, shareDir, "")
+			s.Require().NoError(err)
+			restored := filepath.Join(dir, "restored.key")
+			_, err = core.CombineShares(files[1:], restored, "")
+			s.Require().NoError(err)
+			want, err := os.ReadFile(keyFile)
+			s.Require().NoError(err)
+			got, err := os.ReadFile(restored)
+			s.Require().NoError(err)
+			s.Assert().Equal(want, got)
+			_, err = core.CombineShares(files[1:], restored, "")
+			s.Assert().Error(err, "an existing key file is not overwritten")
+		})
+	})
+}
+
+func TestCore_ConfigDefaults(t *testing.T) {
+	runner.Run(t, "Core operation defaults from the config", func(t provider.T) {
+		ctx := context.Background()
+		dir := t.TempDir()
+
+		t.WithNewStep("key_dir selects the keyring", func(s provider.StepCtx) {
+			config.AppConfig = &config.Config{Defaults: config.DefaultsConfig{KeyDir: filepath.Join(dir, "keys")}}
+			defer func() { config.AppConfig = nil }()
+			core := NewCore()
+			s.Require().NotNil(core.Keyring())
+			s.Assert().Equal(filepath.Join(dir, "keys"), core.Keyring().Dir())
+		})
+
+		t.WithNewStep("calculation method and compression level", func(s provider.StepCtx) {
+			core := NewCore()
+			core.SetDefaults(config.DefaultsConfig{CalculationMethod: "parser", CompressionLevel: 1})
+			s.Require().NoError(core.LoadBytes([]byte("2 * 3")))
+			s.Require().NoError(core.Apply("calculate", nil))
+			s.Require().NoError(core.Apply("compress", map[string]string{"type": "gzip"}))
+			s.Require().NoError(core.Apply("decompress", map[string]string{"type": "gzip"}))
+			out, err := core.run(ctx, nil)
+			s.Require().NoError(err)
+			s.Assert().Equal("6", string(out))
+
+			core.SetDefaults(config.DefaultsConfig{})
+			_, err = core.run(ctx, nil)
+			s.Assert().Error(err, "calculate has no type without the default")
+		})
+
+		t.WithNewStep("rekey runs files on several workers", func(s provider.StepCtx) {
+			core := NewCore()
+			core.SetKeyring(keyring.New(filepath.Join(dir, "rekey-keys")))
+			core.SetDefaults(config.DefaultsConfig{Workers: 3})
+			for _, name := range []string{"old", "new"} {
+				_, err := core.GenerateKey(name, "aes")
+				s.Require().NoError(err)
+			}
+
+			var files []string
+			for i := range 7 {
+				s.Require().NoError(core.LoadBytes([]byte(fmt.Sprintf("file %d", i))))
+				s.Require().NoError(core.Apply("encrypt", map[string]string{"key": "old"}))
+				encrypted, err := core.run(ctx, nil)
+				s.Require().NoError(err)
+				path := filepath.Join(dir, fmt.Sprintf("file-%d.bin", i))
+				s.Require().NoError(os.WriteFile(path, encrypted, 0600))
+				files = append(files, path)
+			}
+			files = append(files, filepath.Join(dir, "absent.bin"))
+
+			done, err := core.Rekey(ctx, files, "old", "new")
+			s.Assert().Equal(7, done)
+			s.Require().Error(err)
+			s.Assert().Contains(err.Error(), "absent.bin")
+
+			for i, path := range files[:7] {
+				data, err := os.ReadFile(path)
+				s.Require().NoError(err)
+				s.Require().NoError(core.LoadBytes(data))
+				s.Require().NoError(core.Apply("decrypt", map[string]string{"key": "new"}))
+				out, err := core.run(ctx, nil)
+				s.Require().NoError(err)
+				s.Assert().Equal(fmt.Sprintf("file %d", i), string(out))
+			}
+		})
 	})
 }
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dzibukalexander/file-processing/internal/detect"
+	"github.com/dzibukalexander/file-processing/internal/logger"
+	"github.com/dzibukalexander/file-processing/pkg/fileprocessing"
+)
+
+// KeepIntermediateParam is the process param naming a directory that receives
+// the output of every pipeline step.
+const KeepIntermediateParam = "keep_intermediate"
+
+// StepResult describes an operation executed by Step.
+type StepResult struct {
+	Index      int // 1-based position of the executed operation
+	Total      int
+	Operation  *Operation
+	InputSize  int
+	OutputSize int
+}
+
+// PeekInfo summarizes the current buffer.
+type PeekInfo struct {
+	Data    []byte
+	Size    int
+	Entropy float64
+	Type    detect.Kind
+	Step    int // number of operations applied so far, 0 for the loaded data
+	Total   int
+}
+
+// Step executes the next pipeline operation on the current buffer. A failing
+// operation leaves the buffer and position unchanged so it can be fixed and retried.
+func (c *Core) Step(ctx context.Context) (*StepResult, error) {
+	if c.originalData == nil {
+		return nil, fmt.Errorf("no data loaded to process")
+	}
+	if err := c.checkClosed(); err != nil {
+		return nil, err
+	}
+	ops, err := c.builder.Resolved(c.searchDirs())
+	if err != nil {
+		return nil, err
+	}
+	if c.stepIndex == 0 {
+		c.stepData = c.originalData
+	}
+	if c.stepIndex >= len(ops) {
+		return nil, fmt.Errorf("all %d steps have been executed; use 'step reset' to start over", len(ops))
+	}
+
+	op := ops[c.stepIndex]
+	out, err := fileprocessing.FromOperations([]*Operation{op}, c.pipelineOptions()...).Run(ctx, c.stepData)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StepResult{
+		Index:      c.stepIndex + 1,
+		Total:      len(ops),
+		Operation:  op,
+		InputSize:  len(c.stepData),
+		OutputSize: len(out),
+	}
+	c.stepData = out
+	c.stepIndex++
+	logger.For("core").WithFields(map[string]interface{}{
+		"step":      result.Index,
+		"operation": op.Name,
+	}).Info("Pipeline step executed")
+	return result, nil
+}
+
+// ResetStepping discards the output of Step so the next Step starts from the loaded data.
+func (c *Core) ResetStepping() {
+	c.stepData = nil
+	c.stepIndex = 0
+}
+
+// Peek describes the current buffer: the output of the last Step, or the
+// loaded data if no step has been executed.
+func (c *Core) Peek() (*PeekInfo, error) {
+	if c.originalData == nil {
+		return nil, fmt.Errorf("no data loaded")
+	}
+	data := c.originalData
+	if c.stepIndex > 0 {
+		data = c.stepData
+	}
+	return &PeekInfo{
+		Data:    data,
+		Size:    len(data),
+		Entropy: detect.Entropy(data),
+		Type:    detect.Detect(data),
+		Step:    c.stepIndex,
+		Total:   len(c.builder.operations),
+	}, nil
+}
+
+// intermediateDumper writes each step's output to dir as step-NN-<operation>.bin.
+func intermediateDumper(dir string) (fileprocessing.StepHook, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create intermediate output directory: %w", err)
+	}
+	return func(index int, op *Operation, output []byte) error {
+		path := filepath.Join(dir, fmt.Sprintf("step-%02d-%s.bin", index+1, op.Name))
+		// Intermediate output may be decrypted data, so keep it private.
+		if err := os.WriteFile(path, output, 0600); err != nil {
+			return err
+		}
+		logger.For("core").WithField("path", path).Debug("Intermediate output written")
+		return nil
+	}, nil
+}
@@ -0,0 +1,347 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/dzibukalexander/file-processing/internal/keyring"
+	"github.com/dzibukalexander/file-processing/internal/logger"
+	"github.com/dzibukalexander/file-processing/pkg/fileprocessing"
+)
+
+// keyLayout describes how the keys of an algorithm are laid out on disk, the
+// way gen-key writes them: symmetric keys in one file, age identities in
+// path with the recipient in path.pub, and other key pairs in path.priv and
+// path.pub.
+func keyLayout(algorithm string) (symmetric bool, secretPath func(string) string, err error) {
+	pair := func(path string) string { return path + ".priv" }
+	same := func(path string) string { return path }
+
+	if _, err := fileprocessing.ParseSignature(algorithm); err == nil {
+		return false, pair, nil
+	}
+	e, err := fileprocessing.ParseEncryption(algorithm)
+	if err != nil {
+		return false, nil, fmt.Errorf("unsupported key algorithm: %s", algorithm)
+	}
+	switch e {
+	case fileprocessing.RSA, fileprocessing.PGP, fileprocessing.X25519, fileprocessing.P256:
+		return false, pair, nil
+	case fileprocessing.Age:
+		return false, same, nil
+	case fileprocessing.Multi:
+		return false, nil, fmt.Errorf("%s keys are RSA or X25519 key pairs; use those algorithms", e)
+	default:
+		return true, same, nil
+	}
+}
+
+// GenerateKey creates a new key for algorithm and stores it in the keyring
+// as name.
+func (c *Core) GenerateKey(name, algorithm string) (*keyring.Key, error) {
+	if c.keyring == nil {
+		return nil, fmt.Errorf("no keyring is available")
+	}
+	if _, _, err := keyLayout(algorithm); err != nil {
+		return nil, err
+	}
+	if _, err := c.keyring.Get(name); err == nil {
+		return nil, fmt.Errorf("%w: %s", keyring.ErrExists, name)
+	}
+
+	dir, err := os.MkdirTemp("", "file-processing-key-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, name)
+
+	if sig, sigErr := fileprocessing.ParseSignature(algorithm); sigErr == nil {
+		err = fileprocessing.GenerateSigningKey(sig, path)
+	} else {
+		err = fileprocessing.GenerateKey(fileprocessing.Encryption(algorithm), path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.ImportKey(name, algorithm, path)
+}
+
+// ImportKey adds the key at path to the keyring as name. Key pairs are read
+// from the files gen-key writes for path; a path ending in .pub imports just
+// the public key.
+func (c *Core) ImportKey(name, algorithm, path string) (*keyring.Key, error) {
+	if c.keyring == nil {
+		return nil, fmt.Errorf("no keyring is available")
+	}
+	symmetric, secretPath, err := keyLayout(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &keyring.Key{Name: name, Algorithm: algorithm}
+	switch {
+	case symmetric:
+		if key.Secret, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read key: %w", err)
+		}
+	case strings.HasSuffix(path, ".pub"):
+		if key.Public, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read public key: %w", err)
+		}
+	default:
+		base := strings.TrimSuffix(path, ".priv")
+		secret := secretPath(base)
+		if _, err := os.Stat(secret); err != nil {
+			secret = path
+		}
+		if key.Secret, err = os.ReadFile(secret); err != nil {
+			return nil, fmt.Errorf("failed to read private key: %w", err)
+		}
+		public, err := os.ReadFile(base + ".pub")
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read public key: %w", err)
+		}
+		key.Public = public
+	}
+
+	if err := c.keyring.Add(key); err != nil {
+		return nil, err
+	}
+	logger.For("core").WithField("name", name).Infof("Added %s key to keyring", algorithm)
+	return key, nil
+}
+
+// ExportKey writes the key called name to disk in the layout gen-key uses
+// for path, and returns the files written. With public set only the public
+// key of a key pair is written. The secret goes to a new file readable only
+// by the owner; an existing file is never replaced.
+func (c *Core) ExportKey(name, path string, public bool) ([]string, error) {
+	if c.keyring == nil {
+		return nil, fmt.Errorf("no keyring is available")
+	}
+	key, err := c.keyring.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	symmetric, secretPath, err := keyLayout(key.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if public && symmetric {
+		return nil, fmt.Errorf("%s is a symmetric key and has no public key", name)
+	}
+	if public && key.Public == nil {
+		return nil, fmt.Errorf("key %s has no public key", name)
+	}
+
+	var written []string
+	if !public && key.Secret != nil {
+		if err := writeNewFile(secretPath(path), key.Secret); err != nil {
+			return nil, fmt.Errorf("failed to write key: %w", err)
+		}
+		written = append(written, secretPath(path))
+	}
+	if !symmetric && key.Public != nil {
+		if err := os.WriteFile(path+".pub", key.Public, 0644); err != nil {
+			return written, fmt.Errorf("failed to write public key: %w", err)
+		}
+		written = append(written, path+".pub")
+	}
+	return written, nil
+}
+
+// Rekey decrypts each file with the keyring key from and encrypts it again
+// with the key to, replacing the file. Files that fail are left untouched
+// and the others are still rekeyed; it returns the number rekeyed. Up to
+// defaults.workers files are processed at once.
+func (c *Core) Rekey(ctx context.Context, files []string, from, to string) (int, error) {
+	if c.keyring == nil {
+		return 0, fmt.Errorf("no keyring is available")
+	}
+	pipeline := fileprocessing.FromOperations([]*Operation{
+		{Name: "decrypt", Params: map[string]string{"key": from}},
+		{Name: "encrypt", Params: map[string]string{"key": to}},
+	}, c.pipelineOptions()...)
+
+	log := logger.For("core")
+	errs := make([]error, len(files))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(c.workers(), len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if err := rekeyFile(ctx, pipeline, files[i]); err != nil {
+					log.WithField("file", files[i]).Errorf("Rekey failed: %v", err)
+					errs[i] = fmt.Errorf("%s: %w", files[i], err)
+					continue
+				}
+				log.WithField("file", files[i]).Info("File rekeyed")
+			}
+		}()
+	}
+	for i := range files {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	done := 0
+	for _, err := range errs {
+		if err == nil {
+			done++
+		}
+	}
+	return done, errors.Join(errs...)
+}
+
+// workers returns the number of files commands taking several files process
+// at once.
+func (c *Core) workers() int {
+	return max(c.defaults.Workers, 1)
+}
+
+// rekeyFile runs pipeline over file and replaces it with the result through
+// a temporary file, so an interrupted rekey never leaves a partial file.
+func rekeyFile(ctx context.Context, pipeline *fileprocessing.Pipeline, file string) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	out, err := pipeline.Run(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".rekey-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(out); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// SplitKey splits the keyring key called ref, or the key file at ref if the
+// keyring has no such key, into n Shamir shares any k of which rebuild it.
+// The shares are written to dir as <name>.share-<i>-of-<n>. Key files are
+// taken to hold an algorithm key, aes unless given.
+func (c *Core) SplitKey(ref string, n, k int, dir, algorithm string) ([]string, error) {
+	var key *keyring.Key
+	var err error
+	if c.keyring != nil {
+		key, err = c.keyring.Get(ref)
+	}
+	if key == nil {
+		secret, readErr := os.ReadFile(ref)
+		if readErr != nil {
+			if err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to read key file: %w", readErr)
+		}
+		if algorithm == "" {
+			algorithm = string(fileprocessing.AES)
+		}
+		name := strings.TrimSuffix(filepath.Base(ref), filepath.Ext(ref))
+		key = &keyring.Key{Name: name, Algorithm: algorithm, Secret: secret}
+	} else if algorithm != "" && algorithm != key.Algorithm {
+		return nil, fmt.Errorf("key %s is a %s key, not %s", ref, key.Algorithm, algorithm)
+	}
+
+	shares, err := keyring.Split(key, n, k)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, share := range shares {
+		text, err := share.MarshalText()
+		if err != nil {
+			return written, err
+		}
+		path := filepath.Join(dir, fmt.Sprintf("%s.share-%d-of-%d", key.Name, share.Index, share.Total))
+		if err := writeNewFile(path, text); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	logger.For("core").WithField("name", key.Name).Infof("Key split into %d shares, %d needed", n, k)
+	return written, nil
+}
+
+// CombineShares rebuilds a key from share files and stores it in the
+// keyring as name (by default the name recorded in the shares) or, if out is
+// set, writes its secret to the file out.
+func (c *Core) CombineShares(paths []string, out, name string) (*keyring.Key, error) {
+	shares := make([]*keyring.Share, len(paths))
+	for i, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if shares[i], err = keyring.ParseShare(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	key, err := keyring.Combine(shares)
+	if err != nil {
+		return nil, err
+	}
+
+	if out != "" {
+		if err := writeNewFile(out, key.Secret); err != nil {
+			return nil, err
+		}
+		if name == "" {
+			return key, nil
+		}
+	}
+	if c.keyring == nil {
+		return nil, fmt.Errorf("no keyring is available; use out=<file>")
+	}
+	if name != "" {
+		key.Name = name
+	}
+	if err := c.keyring.Add(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// writeNewFile writes data to a new file readable only by the owner and
+// refuses to replace an existing one.
+func writeNewFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
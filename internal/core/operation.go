@@ -1,7 +0,0 @@
-package core
-
-// Operation defines a single, serializable processing step.
-type Operation struct {
-	Name   string            `json:"name"`
-	Params map[string]string `json:"params"`
-}
@@ -1,13 +1,37 @@
 package core
 
 import (
-	"encoding/json"
-	"io/ioutil"
+	"fmt"
+	"os"
+
+	"github.com/dzibukalexander/file-processing/pkg/fileprocessing"
 )
 
 // PipelineBuilder constructs a sequence of processing steps.
 type PipelineBuilder struct {
 	operations []*Operation
+
+	// info holds the metadata and variable declarations of the loaded or last
+	// saved pipeline file.
+	info fileprocessing.Document
+
+	// vars and source are the variable values and path the pipeline was
+	// loaded with; the operations keep their ${NAME} references until run.
+	vars   map[string]string
+	source string
+
+	// undo and redo hold snapshots of operations taken around each edit.
+	undo [][]*Operation
+	redo [][]*Operation
+
+	// open is the stack of control-flow blocks that Add currently appends into.
+	open []*block
+}
+
+// block is an "if" or "try" operation still being built.
+type block struct {
+	op     *Operation
+	inElse bool
 }
 
 // NewPipelineBuilder creates a new builder.
@@ -15,30 +39,264 @@ func NewPipelineBuilder() *PipelineBuilder {
 	return &PipelineBuilder{}
 }
 
-// Add appends a new operation to the pipeline.
+// Add appends a new operation to the pipeline, or to the innermost open block.
 func (b *PipelineBuilder) Add(op *Operation) {
-	b.operations = append(b.operations, op)
+	b.snapshot()
+	if len(b.open) == 0 {
+		b.operations = append(b.operations, op)
+		return
+	}
+	top := b.open[len(b.open)-1]
+	if top.inElse {
+		top.op.Else = append(top.op.Else, op)
+	} else {
+		top.op.Steps = append(top.op.Steps, op)
+	}
+}
+
+// Begin adds a control-flow operation and directs following Adds into its steps.
+func (b *PipelineBuilder) Begin(op *Operation) {
+	b.Add(op)
+	b.open = append(b.open, &block{op: op})
+}
+
+// Else directs following Adds into the else branch of the innermost open block.
+func (b *PipelineBuilder) Else() error {
+	if len(b.open) == 0 {
+		return fmt.Errorf("else without an open if or try block")
+	}
+	top := b.open[len(b.open)-1]
+	if top.inElse {
+		return fmt.Errorf("%s block already has an else branch", top.op.Name)
+	}
+	top.inElse = true
+	return nil
 }
 
-// Reset clears all operations from the pipeline.
+// End closes the innermost open block and returns its operation name.
+func (b *PipelineBuilder) End() (string, error) {
+	if len(b.open) == 0 {
+		return "", fmt.Errorf("end without an open if or try block")
+	}
+	top := b.open[len(b.open)-1]
+	b.open = b.open[:len(b.open)-1]
+	return top.op.Name, nil
+}
+
+// OpenBlock returns the name of the innermost unfinished block, or "" if none is open.
+func (b *PipelineBuilder) OpenBlock() string {
+	if len(b.open) == 0 {
+		return ""
+	}
+	return b.open[len(b.open)-1].op.Name
+}
+
+// Reset clears all operations from the pipeline along with its metadata and edit history.
 func (b *PipelineBuilder) Reset() {
 	b.operations = []*Operation{}
+	b.info = fileprocessing.Document{}
+	b.vars = nil
+	b.source = ""
+	b.undo = nil
+	b.redo = nil
+	b.open = nil
+}
+
+// Operations returns the current steps in order.
+func (b *PipelineBuilder) Operations() []*Operation {
+	return append([]*Operation(nil), b.operations...)
+}
+
+// Len returns the number of steps in the pipeline.
+func (b *PipelineBuilder) Len() int {
+	return len(b.operations)
+}
+
+// Remove deletes the step at index.
+func (b *PipelineBuilder) Remove(index int) error {
+	if err := b.checkIndex(index); err != nil {
+		return err
+	}
+	b.snapshot()
+	b.operations = append(b.operations[:index:index], b.operations[index+1:]...)
+	return nil
+}
+
+// Insert places op before the step at index; index == Len() appends.
+func (b *PipelineBuilder) Insert(index int, op *Operation) error {
+	if index != len(b.operations) {
+		if err := b.checkIndex(index); err != nil {
+			return err
+		}
+	}
+	b.snapshot()
+	ops := make([]*Operation, 0, len(b.operations)+1)
+	ops = append(ops, b.operations[:index]...)
+	ops = append(ops, op)
+	b.operations = append(ops, b.operations[index:]...)
+	return nil
+}
+
+// Move relocates the step at from so that it ends up at index to.
+func (b *PipelineBuilder) Move(from, to int) error {
+	if err := b.checkIndex(from); err != nil {
+		return err
+	}
+	if err := b.checkIndex(to); err != nil {
+		return err
+	}
+	b.snapshot()
+	op := b.operations[from]
+	ops := append(b.operations[:from:from], b.operations[from+1:]...)
+	b.operations = append(ops[:to:to], append([]*Operation{op}, ops[to:]...)...)
+	return nil
+}
+
+// Edit merges params into the step at index. An empty value removes the key.
+func (b *PipelineBuilder) Edit(index int, params map[string]string) error {
+	if err := b.checkIndex(index); err != nil {
+		return err
+	}
+	b.snapshot()
+	op := b.operations[index]
+	if op.Params == nil {
+		op.Params = map[string]string{}
+	}
+	mergeParams(op.Params, params)
+	return nil
+}
+
+// mergeParams sets each of params in dst, deleting those with empty values.
+func mergeParams(dst, params map[string]string) {
+	for k, v := range params {
+		if v == "" {
+			delete(dst, k)
+		} else {
+			dst[k] = v
+		}
+	}
+}
+
+// Clear removes every step. Unlike Reset it can be undone.
+func (b *PipelineBuilder) Clear() {
+	b.snapshot()
+	b.operations = []*Operation{}
+	b.open = nil
+}
+
+// Undo reverts the last edit. It returns false if there is nothing to undo.
+func (b *PipelineBuilder) Undo() bool {
+	if len(b.undo) == 0 {
+		return false
+	}
+	b.redo = append(b.redo, cloneOperations(b.operations))
+	b.operations = b.undo[len(b.undo)-1]
+	b.undo = b.undo[:len(b.undo)-1]
+	return true
+}
+
+// Redo re-applies the last undone edit. It returns false if there is nothing to redo.
+func (b *PipelineBuilder) Redo() bool {
+	if len(b.redo) == 0 {
+		return false
+	}
+	b.undo = append(b.undo, cloneOperations(b.operations))
+	b.operations = b.redo[len(b.redo)-1]
+	b.redo = b.redo[:len(b.redo)-1]
+	return true
+}
+
+// Info returns the pipeline metadata.
+func (b *PipelineBuilder) Info() fileprocessing.Document {
+	info := b.info
+	info.Operations = b.Operations()
+	return info
+}
+
+// SetInfo replaces the name, description, author and tags of the pipeline.
+func (b *PipelineBuilder) SetInfo(name, description, author string, tags []string) {
+	b.info.Name = name
+	b.info.Description = description
+	b.info.Author = author
+	b.info.Tags = tags
 }
 
-// SaveToFile serializes the pipeline to a JSON file.
+// SaveToFile writes the pipeline as a versioned document, in YAML for
+// .yaml/.yml paths and JSON otherwise.
 func (b *PipelineBuilder) SaveToFile(filePath string) error {
-	data, err := json.MarshalIndent(b.operations, "", "  ")
-	if err != nil {
+	doc := b.Info()
+	if err := doc.WriteFile(filePath); err != nil {
 		return err
 	}
-	return ioutil.WriteFile(filePath, data, 0644)
+	doc.Operations = nil
+	b.info = doc
+	return nil
 }
 
-// LoadFromFile deserializes the pipeline from a JSON file.
-func (b *PipelineBuilder) LoadFromFile(filePath string) error {
-	data, err := ioutil.ReadFile(filePath)
+// LoadFromFile reads a pipeline document, migrating files written in older
+// schema versions. The steps keep their template variables so the pipeline
+// saves back unchanged; they are checked against vars and the environment
+// here and resolved again by Resolved each time the pipeline runs. Pipelines
+// used by name are looked up next to the file and then in dirs.
+func (b *PipelineBuilder) LoadFromFile(filePath string, vars map[string]string, dirs []string) error {
+	doc, err := fileprocessing.ReadDocument(filePath)
 	if err != nil {
 		return err
 	}
-	return json.Unmarshal(data, &b.operations)
+	if _, err := doc.Resolved(vars, os.LookupEnv, filePath, dirs); err != nil {
+		return err
+	}
+	b.operations = doc.Operations
+	doc.Operations = nil
+	b.info = *doc
+	b.vars = vars
+	b.source = filePath
+	return nil
+}
+
+// Resolved returns a copy of the steps with template variables resolved and
+// include and use operations expanded, ready to run.
+func (b *PipelineBuilder) Resolved(dirs []string) ([]*Operation, error) {
+	info := b.Info()
+	doc, err := info.Resolved(b.vars, os.LookupEnv, b.source, dirs)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Operations, nil
+}
+
+func (b *PipelineBuilder) checkIndex(index int) error {
+	if index < 0 || index >= len(b.operations) {
+		return fmt.Errorf("step index %d out of range [0, %d)", index, len(b.operations))
+	}
+	return nil
+}
+
+// snapshot records the current operations for Undo and drops the redo history.
+func (b *PipelineBuilder) snapshot() {
+	b.undo = append(b.undo, cloneOperations(b.operations))
+	b.redo = nil
+}
+
+func cloneOperations(ops []*Operation) []*Operation {
+	if ops == nil {
+		return nil
+	}
+	clone := make([]*Operation, len(ops))
+	for i, op := range ops {
+		clone[i] = cloneOperation(op)
+	}
+	return clone
+}
+
+func cloneOperation(op *Operation) *Operation {
+	params := make(map[string]string, len(op.Params))
+	for k, v := range op.Params {
+		params[k] = v
+	}
+	clone := *op
+	clone.Params = params
+	clone.Steps = cloneOperations(op.Steps)
+	clone.Else = cloneOperations(op.Else)
+	return &clone
 }
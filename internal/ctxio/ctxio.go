@@ -0,0 +1,46 @@
+// Package ctxio wraps readers and writers so long-running copies stop as
+// soon as their context is cancelled.
+package ctxio
+
+import (
+	"context"
+	"io"
+)
+
+type reader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// NewReader returns a reader that fails with ctx.Err() once ctx is done.
+func NewReader(ctx context.Context, r io.Reader) io.Reader {
+	return &reader{ctx: ctx, r: r}
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// chunkSize bounds how much data is written between cancellation checks.
+const chunkSize = 64 * 1024
+
+// Write writes data to w in chunks, checking ctx between them.
+func Write(ctx context.Context, w io.Writer, data []byte) error {
+	for len(data) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n := len(data)
+		if n > chunkSize {
+			n = chunkSize
+		}
+		if _, err := w.Write(data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+// Package detect guesses what kind of content a buffer holds, so pipeline
+// state can be inspected and branched on without knowing how it was produced.
+package detect
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"math"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Kind string
+
+const (
+	EMPTY  Kind = "EMPTY"
+	GZIP   Kind = "GZIP"
+	ZIP    Kind = "ZIP"
+	JSON   Kind = "JSON"
+	XML    Kind = "XML"
+	HTML   Kind = "HTML"
+	YAML   Kind = "YAML"
+	TEXT   Kind = "TEXT"
+	BINARY Kind = "BINARY"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// Detect inspects data and returns its most specific Kind.
+func Detect(data []byte) Kind {
+	switch {
+	case len(data) == 0:
+		return EMPTY
+	case bytes.HasPrefix(data, gzipMagic):
+		return GZIP
+	case bytes.HasPrefix(data, zipMagic):
+		return ZIP
+	case !IsText(data):
+		return BINARY
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case json.Valid(trimmed) && (trimmed[0] == '{' || trimmed[0] == '['):
+		return JSON
+	case isHTML(trimmed):
+		return HTML
+	case trimmed[0] == '<' && isXML(trimmed):
+		return XML
+	case isYAML(trimmed):
+		return YAML
+	default:
+		return TEXT
+	}
+}
+
+// Compression reports the compression format of data, or "" if it is not compressed.
+func Compression(data []byte) Kind {
+	switch kind := Detect(data); kind {
+	case GZIP, ZIP:
+		return kind
+	default:
+		return ""
+	}
+}
+
+// IsText reports whether data is valid UTF-8 without control characters
+// other than common whitespace.
+func IsText(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	for _, b := range data {
+		if b < 0x20 && b != '\n' && b != '\r' && b != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// Entropy returns the Shannon entropy of data in bits per byte (0 to 8).
+// Compressed or encrypted data is close to 8.
+func Entropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	entropy := 0.0
+	total := float64(len(data))
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / total
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+func isHTML(data []byte) bool {
+	lower := bytes.ToLower(data[:min(len(data), 512)])
+	return bytes.HasPrefix(lower, []byte("<!doctype html")) || bytes.Contains(lower, []byte("<html"))
+}
+
+func isXML(data []byte) bool {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	sawElement := false
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return sawElement
+		}
+		if err != nil {
+			return false
+		}
+		if _, ok := tok.(xml.StartElement); ok {
+			sawElement = true
+		}
+	}
+}
+
+// isYAML only accepts documents whose top level is a mapping or sequence;
+// almost any plain text parses as a YAML scalar.
+func isYAML(data []byte) bool {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil || len(node.Content) == 0 {
+		return false
+	}
+	kind := node.Content[0].Kind
+	return kind == yaml.MappingNode || kind == yaml.SequenceNode
+}
@@ -0,0 +1,56 @@
+package detect
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+)
+
+func TestDetect(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte("hello"))
+	w.Close()
+
+	testCases := map[string]struct {
+		data     []byte
+		expected Kind
+	}{
+		"empty":  {nil, EMPTY},
+		"gzip":   {gz.Bytes(), GZIP},
+		"zip":    {[]byte("PK\x03\x04rest"), ZIP},
+		"json":   {[]byte(`{"a": 1}`), JSON},
+		"xml":    {[]byte(`<?xml version="1.0"?><a><b/></a>`), XML},
+		"html":   {[]byte("<!DOCTYPE html><html><body></body></html>"), HTML},
+		"yaml":   {[]byte("a: 1\nb:\n  - c\n"), YAML},
+		"text":   {[]byte("1 + 2 = three"), TEXT},
+		"binary": {[]byte{0x00, 0xff, 0x10, 0x80}, BINARY},
+	}
+
+	runner.Run(t, "Detect", func(t provider.T) {
+		for name, tc := range testCases {
+			tc := tc
+			t.WithNewStep(name, func(s provider.StepCtx) {
+				s.Assert().Equal(tc.expected, Detect(tc.data))
+			})
+		}
+	})
+}
+
+func TestEntropy(t *testing.T) {
+	runner.Run(t, "Entropy", func(t provider.T) {
+		t.WithNewStep("bounds", func(s provider.StepCtx) {
+			s.Assert().Equal(0.0, Entropy([]byte("aaaa")))
+			s.Assert().Equal(1.0, Entropy([]byte("abab")))
+
+			all := make([]byte, 256)
+			for i := range all {
+				all[i] = byte(i)
+			}
+			s.Assert().Equal(8.0, Entropy(all))
+		})
+	})
+}
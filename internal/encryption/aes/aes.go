@@ -1,52 +1,142 @@
 package aes
 
 import (
+	"bytes"
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"io/ioutil"
+
+	"github.com/dzibukalexander/file-processing/internal/encryption/constants"
 )
 
-type AESEncryptor struct{}
+// Ciphertexts start with a header that is authenticated as additional data
+// together with any caller-supplied AAD:
+//
+//	"FPAE" | version (1) | algorithm ID (1) | key fingerprint (8) | nonce (12) | ciphertext
+//
+// Ciphertexts without the header (nonce || ciphertext) are still accepted.
+const (
+	headerVersion  = 1
+	algorithmGCM   = 1
+	fingerprintLen = 8
+	headerSize     = 4 + 2 + fingerprintLen
+)
 
-func (e *AESEncryptor) Encrypt(data []byte, key []byte) ([]byte, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
+var magic = []byte("FPAE")
+
+// AESEncryptor encrypts with AES-GCM. AAD, if set, must be supplied again to
+// decrypt.
+type AESEncryptor struct {
+	AAD []byte
+}
+
+func (e *AESEncryptor) Encrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-
-	gcm, err := cipher.NewGCM(block)
+	gcm, err := newGCM(key)
 	if err != nil {
 		return nil, err
 	}
 
+	header := make([]byte, headerSize, headerSize+gcm.NonceSize()+len(data)+gcm.Overhead())
+	copy(header, magic)
+	header[4] = headerVersion
+	header[5] = algorithmGCM
+	copy(header[6:], Fingerprint(key))
+
 	nonce := make([]byte, gcm.NonceSize())
 	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
 		return nil, err
 	}
-
-	return gcm.Seal(nonce, nonce, data, nil), nil
+	out := append(header, nonce...)
+	return gcm.Seal(out, nonce, data, additionalData(header, e.AAD)), nil
 }
 
-type AESDecryptor struct{}
+// AESDecryptor decrypts AES-GCM ciphertexts made with the same AAD. It
+// returns constants.ErrWrongKey if the ciphertext names a different key and
+// constants.ErrCorruptedData if authentication fails.
+type AESDecryptor struct {
+	AAD []byte
+}
 
-func (d *AESDecryptor) Decrypt(data []byte, key []byte) ([]byte, error) {
-	block, err := aes.NewCipher(key)
+func (d *AESDecryptor) Decrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
 	if err != nil {
 		return nil, err
 	}
 
-	gcm, err := cipher.NewGCM(block)
+	if !bytes.HasPrefix(data, magic) {
+		return d.decryptLegacy(gcm, data)
+	}
+	if len(data) < headerSize+gcm.NonceSize()+gcm.Overhead() {
+		return nil, fmt.Errorf("%w: ciphertext is too short", constants.ErrCorruptedData)
+	}
+	header := data[:headerSize]
+	if header[4] != headerVersion {
+		return nil, fmt.Errorf("unsupported AES ciphertext version %d", header[4])
+	}
+	if header[5] != algorithmGCM {
+		return nil, fmt.Errorf("unsupported AES ciphertext algorithm %d", header[5])
+	}
+	if want := header[6:headerSize]; !bytes.Equal(want, Fingerprint(key)) {
+		return nil, fmt.Errorf("%w: data was encrypted with key %x, not %x", constants.ErrWrongKey, want, Fingerprint(key))
+	}
+
+	nonce := data[headerSize : headerSize+gcm.NonceSize()]
+	plain, err := gcm.Open(nil, nonce, data[headerSize+gcm.NonceSize():], additionalData(header, d.AAD))
 	if err != nil {
-		return nil, err
+		if len(d.AAD) > 0 {
+			return nil, fmt.Errorf("%w or the associated data does not match", constants.ErrCorruptedData)
+		}
+		return nil, constants.ErrCorruptedData
 	}
+	return plain, nil
+}
 
+// decryptLegacy opens nonce || ciphertext as written before headers were
+// added. Such ciphertexts carry no key ID and no AAD.
+func (d *AESDecryptor) decryptLegacy(gcm cipher.AEAD, data []byte) ([]byte, error) {
+	if len(d.AAD) > 0 {
+		return nil, fmt.Errorf("ciphertext has no header, so associated data cannot be checked")
+	}
 	nonceSize := gcm.NonceSize()
+	if len(data) < nonceSize+gcm.Overhead() {
+		return nil, fmt.Errorf("%w: ciphertext is too short", constants.ErrCorruptedData)
+	}
 	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w or the key is wrong", constants.ErrCorruptedData)
+	}
+	return plain, nil
+}
 
-	return gcm.Open(nil, nonce, ciphertext, nil)
+// Fingerprint identifies key without revealing it: the first 8 bytes of
+// SHA-256 over a domain-separated copy of the key.
+func Fingerprint(key []byte) []byte {
+	sum := sha256.Sum256(append([]byte("file-processing aes key id\x00"), key...))
+	return sum[:fingerprintLen]
+}
+
+func additionalData(header, aad []byte) []byte {
+	return append(bytes.Clone(header), aad...)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
 }
 
 func (e *AESEncryptor) GenerateKey(path string) error {
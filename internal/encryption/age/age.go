@@ -0,0 +1,134 @@
+// Package age encrypts in the age format (https://age-encryption.org/v1), so
+// files open with the age and rage command-line tools.
+package age
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/dzibukalexander/file-processing/internal/encryption/constants"
+)
+
+// AgeEncryptor encrypts to the X25519 recipients ("age1...", one per line)
+// in the key passed to Encrypt, or with the key as a passphrase (scrypt) if
+// Passphrase is set. Armor selects the ASCII-armored encoding.
+type AgeEncryptor struct {
+	Passphrase bool
+	Armor      bool
+}
+
+func (e *AgeEncryptor) Encrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var recipients []age.Recipient
+	if e.Passphrase {
+		recipient, err := age.NewScryptRecipient(passphrase(key))
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	} else {
+		parsed, err := age.ParseRecipients(bytes.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse age recipients: %w", err)
+		}
+		recipients = parsed
+	}
+
+	var out bytes.Buffer
+	var dst io.Writer = &out
+	var armored io.WriteCloser
+	if e.Armor {
+		armored = armor.NewWriter(&out)
+		dst = armored
+	}
+	w, err := age.Encrypt(dst, recipients...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if armored != nil {
+		if err := armored.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// AgeDecryptor decrypts with the identities ("AGE-SECRET-KEY-1...") in the
+// key passed to Decrypt, or with the key as a passphrase if Passphrase is
+// set. Armored input is detected automatically.
+type AgeDecryptor struct {
+	Passphrase bool
+}
+
+func (d *AgeDecryptor) Decrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var identities []age.Identity
+	if d.Passphrase {
+		identity, err := age.NewScryptIdentity(passphrase(key))
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	} else {
+		parsed, err := age.ParseIdentities(bytes.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse age identities: %w", err)
+		}
+		identities = parsed
+	}
+
+	var src io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(armor.Header)) {
+		src = armor.NewReader(bytes.NewReader(bytes.TrimSpace(data)))
+	}
+	r, err := age.Decrypt(src, identities...)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return nil, fmt.Errorf("%w: %v", constants.ErrWrongKey, err)
+		}
+		return nil, err
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", constants.ErrCorruptedData, err)
+	}
+	return plain, nil
+}
+
+// GenerateKey writes a new X25519 identity to path, in the format of
+// age-keygen, and its recipient to path.pub.
+func (e *AgeEncryptor) GenerateKey(path string) error {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return fmt.Errorf("failed to generate age identity: %w", err)
+	}
+	recipient := identity.Recipient().String()
+	content := fmt.Sprintf("# public key: %s\n%s\n", recipient, identity)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write age identity: %w", err)
+	}
+	return os.WriteFile(path+".pub", []byte(recipient+"\n"), 0644)
+}
+
+// passphrase drops the line ending a passphrase file usually has.
+func passphrase(key []byte) string {
+	return strings.TrimRight(string(key), "\r\n")
+}
@@ -1,6 +1,9 @@
 package constants
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 type EncryptionType string
 
@@ -8,6 +11,26 @@ const (
 	NONE EncryptionType = "NONE"
 	AES  EncryptionType = "AES"
 	RSA  EncryptionType = "RSA"
+	// CHACHA20 is ChaCha20-Poly1305 with 96-bit random nonces.
+	CHACHA20 EncryptionType = "CHACHA20-POLY1305"
+	// XCHACHA20 is XChaCha20-Poly1305 with 192-bit random nonces.
+	XCHACHA20 EncryptionType = "XCHACHA20-POLY1305"
+	// AESSIV is deterministic AES-SIV (RFC 5297).
+	AESSIV EncryptionType = "AES-SIV"
+	// AESCTRHMAC is AES-256-CTR with an HMAC-SHA256 tag (encrypt-then-MAC).
+	AESCTRHMAC EncryptionType = "AES-CTR-HMAC"
+	// AESGCMSTREAM is AES-GCM applied to fixed-size chunks (STREAM).
+	AESGCMSTREAM EncryptionType = "AES-GCM-STREAM"
+	// MULTI wraps a data key for several RSA or X25519 recipients.
+	MULTI EncryptionType = "MULTI"
+	// AGE is the age file format (https://age-encryption.org/v1).
+	AGE EncryptionType = "AGE"
+	// PGP is the OpenPGP message format (RFC 4880).
+	PGP EncryptionType = "PGP"
+	// X25519 is ECIES: X25519 key agreement with ChaCha20-Poly1305.
+	X25519 EncryptionType = "X25519"
+	// P256 is ECIES: P-256 key agreement with ChaCha20-Poly1305.
+	P256 EncryptionType = "P256"
 )
 
 func EncryptionTypeFromString(s string) (EncryptionType, error) {
@@ -18,7 +41,35 @@ func EncryptionTypeFromString(s string) (EncryptionType, error) {
 		return AES, nil
 	case "RSA":
 		return RSA, nil
+	case "CHACHA20-POLY1305":
+		return CHACHA20, nil
+	case "XCHACHA20-POLY1305":
+		return XCHACHA20, nil
+	case "AES-SIV":
+		return AESSIV, nil
+	case "AES-CTR-HMAC":
+		return AESCTRHMAC, nil
+	case "AES-GCM-STREAM":
+		return AESGCMSTREAM, nil
+	case "MULTI":
+		return MULTI, nil
+	case "AGE":
+		return AGE, nil
+	case "PGP":
+		return PGP, nil
+	case "X25519":
+		return X25519, nil
+	case "P256":
+		return P256, nil
 	default:
 		return NONE, fmt.Errorf("unknown encryption type: %s", s)
 	}
 }
+
+var (
+	// ErrWrongKey is returned when a ciphertext names a different key than
+	// the one supplied for decryption.
+	ErrWrongKey = errors.New("wrong key")
+	// ErrCorruptedData is returned when a ciphertext fails authentication.
+	ErrCorruptedData = errors.New("encrypted data is corrupted")
+)
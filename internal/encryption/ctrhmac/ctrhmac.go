@@ -0,0 +1,92 @@
+// internal/encryption/ctrhmac/ctrhmac.go
+package ctrhmac
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// KeySize is an AES-256 key followed by a 256-bit HMAC key.
+const KeySize = 64
+
+// AESCTRHMACEncryptor encrypts with AES-256-CTR and then authenticates
+// iv||ciphertext with HMAC-SHA256. The output is iv||ciphertext||tag.
+type AESCTRHMACEncryptor struct{}
+
+func (e *AESCTRHMACEncryptor) Encrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	encKey, macKey, err := splitKey(key)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, aes.BlockSize+len(data), aes.BlockSize+len(data)+sha256.Size)
+	iv := out[:aes.BlockSize]
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+	cipher.NewCTR(block, iv).XORKeyStream(out[aes.BlockSize:], data)
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(out)
+	return mac.Sum(out), nil
+}
+
+type AESCTRHMACDecryptor struct{}
+
+func (d *AESCTRHMACDecryptor) Decrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	encKey, macKey, err := splitKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aes.BlockSize+sha256.Size {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	body, tag := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), tag) {
+		return nil, errors.New("message authentication failed")
+	}
+
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(body)-aes.BlockSize)
+	cipher.NewCTR(block, body[:aes.BlockSize]).XORKeyStream(plaintext, body[aes.BlockSize:])
+	return plaintext, nil
+}
+
+func splitKey(key []byte) ([]byte, []byte, error) {
+	if len(key) != KeySize {
+		return nil, nil, fmt.Errorf("AES-CTR-HMAC key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key[:32], key[32:], nil
+}
+
+func (e *AESCTRHMACEncryptor) GenerateKey(path string) error {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return fmt.Errorf("failed to generate AES-CTR-HMAC key: %w", err)
+	}
+	return os.WriteFile(path, key, 0600)
+}
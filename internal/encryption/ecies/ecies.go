@@ -0,0 +1,211 @@
+// Package ecies encrypts to an X25519 or P-256 public key, ECIES style: an
+// ephemeral key pair agrees a shared secret with the recipient's key (ECDH),
+// HKDF-SHA256 turns it into a one-time key, and ChaCha20-Poly1305 encrypts
+// the data.
+//
+// Ciphertexts are "FPEC" | version | curve | ephemeral public key | sealed
+// data, with everything before the sealed data authenticated as associated
+// data.
+package ecies
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/dzibukalexander/file-processing/internal/encryption/constants"
+	"github.com/dzibukalexander/file-processing/internal/keyfile"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	magic    = "FPEC"
+	version  = 1
+	hkdfInfo = "file-processing ecies v1"
+)
+
+// Curve selects the key agreement.
+type Curve byte
+
+const (
+	X25519 Curve = 1
+	P256   Curve = 2
+)
+
+func (c Curve) String() string {
+	switch c {
+	case X25519:
+		return "x25519"
+	case P256:
+		return "p256"
+	default:
+		return fmt.Sprintf("curve %d", byte(c))
+	}
+}
+
+func (c Curve) ecdh() ecdh.Curve {
+	if c == P256 {
+		return ecdh.P256()
+	}
+	return ecdh.X25519()
+}
+
+// publicKeySize is the length of an encoded public key: 32 bytes for X25519
+// and 65 for an uncompressed P-256 point.
+func (c Curve) publicKeySize() int {
+	if c == P256 {
+		return 65
+	}
+	return 32
+}
+
+// ECIESEncryptor encrypts to the PEM (PKIX) public key passed to Encrypt.
+type ECIESEncryptor struct {
+	Curve Curve
+}
+
+func (e *ECIESEncryptor) Encrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	parsed, err := keyfile.ParsePublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := e.Curve.publicKey(parsed)
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := e.Curve.ecdh().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(magic), version, byte(e.Curve))
+	header = append(header, ephemeral.PublicKey().Bytes()...)
+	aead, err := dataKey(shared, ephemeral.PublicKey(), recipient)
+	if err != nil {
+		return nil, err
+	}
+	// The key is used once, so a zero nonce is safe.
+	nonce := make([]byte, aead.NonceSize())
+	return aead.Seal(header, nonce, data, header), nil
+}
+
+// ECIESDecryptor decrypts with the PEM private key passed to Decrypt, which
+// KeyPassphrase unlocks if it is encrypted. It returns constants.ErrWrongKey
+// if the data was encrypted to another key.
+type ECIESDecryptor struct {
+	Curve         Curve
+	KeyPassphrase []byte
+}
+
+func (d *ECIESDecryptor) Decrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	parsed, err := keyfile.ParsePrivateKeyWithPassphrase(key, d.KeyPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := d.Curve.privateKey(parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	headerSize := len(magic) + 2 + d.Curve.publicKeySize()
+	if len(data) < headerSize || !bytes.HasPrefix(data, []byte(magic)) {
+		return nil, errors.New("data is not ECIES encrypted")
+	}
+	if data[len(magic)] != version {
+		return nil, fmt.Errorf("unsupported ECIES version %d", data[len(magic)])
+	}
+	if curve := Curve(data[len(magic)+1]); curve != d.Curve {
+		return nil, fmt.Errorf("data is encrypted with %s, not %s", curve, d.Curve)
+	}
+	header := data[:headerSize]
+	ephemeral, err := d.Curve.ecdh().NewPublicKey(header[len(magic)+2:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", constants.ErrCorruptedData, err)
+	}
+	shared, err := priv.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := dataKey(shared, ephemeral, priv.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	plain, err := aead.Open(nil, nonce, data[headerSize:], header)
+	if err != nil {
+		// A different key derives a different data key, so a wrong key and
+		// corrupted data look the same here.
+		return nil, fmt.Errorf("%w or %w", constants.ErrWrongKey, constants.ErrCorruptedData)
+	}
+	return plain, nil
+}
+
+// dataKey derives the one-time AEAD key from the shared secret, salted with
+// both public keys.
+func dataKey(shared []byte, ephemeral, recipient *ecdh.PublicKey) (cipher.AEAD, error) {
+	salt := append(bytes.Clone(ephemeral.Bytes()), recipient.Bytes()...)
+	key, err := hkdf.Key(sha256.New, shared, salt, hkdfInfo, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.New(key)
+}
+
+// publicKey converts a parsed PKIX key into an ECDH key on c. P-256 keys
+// may be ECDSA keys, such as those made by gen-key ecdsa.
+func (c Curve) publicKey(key any) (*ecdh.PublicKey, error) {
+	if k, ok := key.(*ecdsa.PublicKey); ok {
+		converted, err := k.ECDH()
+		if err != nil {
+			return nil, err
+		}
+		key = converted
+	}
+	k, ok := key.(*ecdh.PublicKey)
+	if !ok || k.Curve() != c.ecdh() {
+		return nil, fmt.Errorf("not a %s public key: %T", c, key)
+	}
+	return k, nil
+}
+
+func (c Curve) privateKey(key any) (*ecdh.PrivateKey, error) {
+	if k, ok := key.(*ecdsa.PrivateKey); ok {
+		converted, err := k.ECDH()
+		if err != nil {
+			return nil, err
+		}
+		key = converted
+	}
+	k, ok := key.(*ecdh.PrivateKey)
+	if !ok || k.Curve() != c.ecdh() {
+		return nil, fmt.Errorf("not a %s private key: %T", c, key)
+	}
+	return k, nil
+}
+
+// GenerateKey writes a new key pair on the encryptor's curve as path.priv
+// (PKCS#8, readable only by the owner) and path.pub (PKIX).
+func (e *ECIESEncryptor) GenerateKey(path string) error {
+	priv, err := e.Curve.ecdh().GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate %s key pair: %w", e.Curve, err)
+	}
+	return keyfile.WriteKeyPair(path, priv)
+}
@@ -2,17 +2,27 @@
 package encryption
 
 import (
+	"context"
+
 	"github.com/dzibukalexander/file-processing/internal/encryption/aes"
+	"github.com/dzibukalexander/file-processing/internal/encryption/age"
+	"github.com/dzibukalexander/file-processing/internal/encryption/chacha20"
 	. "github.com/dzibukalexander/file-processing/internal/encryption/constants"
+	"github.com/dzibukalexander/file-processing/internal/encryption/ctrhmac"
+	"github.com/dzibukalexander/file-processing/internal/encryption/ecies"
+	"github.com/dzibukalexander/file-processing/internal/encryption/multi"
+	"github.com/dzibukalexander/file-processing/internal/encryption/pgp"
 	"github.com/dzibukalexander/file-processing/internal/encryption/rsa"
+	"github.com/dzibukalexander/file-processing/internal/encryption/siv"
+	"github.com/dzibukalexander/file-processing/internal/encryption/stream"
 )
 
 type Encryptor interface {
-	Encrypt(data []byte, key []byte) ([]byte, error)
+	Encrypt(ctx context.Context, data []byte, key []byte) ([]byte, error)
 }
 
 type Decryptor interface {
-	Decrypt(data []byte, key []byte) ([]byte, error)
+	Decrypt(ctx context.Context, data []byte, key []byte) ([]byte, error)
 }
 
 func NewEncryptor(encType EncryptionType) Encryptor {
@@ -22,6 +32,26 @@ func NewEncryptor(encType EncryptionType) Encryptor {
 		encryptor = &aes.AESEncryptor{}
 	case RSA:
 		encryptor = &rsa.RSAEncryptor{}
+	case CHACHA20:
+		encryptor = &chacha20.ChaCha20Encryptor{}
+	case XCHACHA20:
+		encryptor = &chacha20.XChaCha20Encryptor{}
+	case AESSIV:
+		encryptor = &siv.AESSIVEncryptor{}
+	case AESCTRHMAC:
+		encryptor = &ctrhmac.AESCTRHMACEncryptor{}
+	case AESGCMSTREAM:
+		encryptor = &stream.StreamEncryptor{}
+	case MULTI:
+		encryptor = &multi.MultiEncryptor{}
+	case AGE:
+		encryptor = &age.AgeEncryptor{}
+	case PGP:
+		encryptor = &pgp.PGPEncryptor{}
+	case X25519:
+		encryptor = &ecies.ECIESEncryptor{Curve: ecies.X25519}
+	case P256:
+		encryptor = &ecies.ECIESEncryptor{Curve: ecies.P256}
 	default:
 		return nil
 	}
@@ -35,8 +65,93 @@ func NewDecryptor(encType EncryptionType) Decryptor {
 		decryptor = &aes.AESDecryptor{}
 	case RSA:
 		decryptor = &rsa.RSADecryptor{}
+	case CHACHA20:
+		decryptor = &chacha20.ChaCha20Decryptor{}
+	case XCHACHA20:
+		decryptor = &chacha20.XChaCha20Decryptor{}
+	case AESSIV:
+		decryptor = &siv.AESSIVDecryptor{}
+	case AESCTRHMAC:
+		decryptor = &ctrhmac.AESCTRHMACDecryptor{}
+	case AESGCMSTREAM:
+		decryptor = &stream.StreamDecryptor{}
+	case MULTI:
+		decryptor = &multi.MultiDecryptor{}
+	case AGE:
+		decryptor = &age.AgeDecryptor{}
+	case PGP:
+		decryptor = &pgp.PGPDecryptor{}
+	case X25519:
+		decryptor = &ecies.ECIESDecryptor{Curve: ecies.X25519}
+	case P256:
+		decryptor = &ecies.ECIESDecryptor{Curve: ecies.P256}
 	default:
 		return nil
 	}
 	return NewLoggingDecryptor(decryptor)
 }
+
+// NewStreamEncryptor returns an AES-GCM-STREAM encryptor that seals
+// chunkSize bytes of plaintext per chunk.
+func NewStreamEncryptor(chunkSize int) Encryptor {
+	return NewLoggingEncryptor(&stream.StreamEncryptor{ChunkSize: chunkSize})
+}
+
+// NewAESEncryptor returns an AES-GCM encryptor that authenticates aad along
+// with the ciphertext header.
+func NewAESEncryptor(aad []byte) Encryptor {
+	return NewLoggingEncryptor(&aes.AESEncryptor{AAD: aad})
+}
+
+// NewAESDecryptor returns an AES-GCM decryptor expecting the aad given to
+// NewAESEncryptor.
+func NewAESDecryptor(aad []byte) Decryptor {
+	return NewLoggingDecryptor(&aes.AESDecryptor{AAD: aad})
+}
+
+// NewRSADecryptor returns an RSA decryptor whose private key may be
+// encrypted with keyPassphrase. legacyPadding selects PKCS#1 v1.5 instead
+// of OAEP, for data encrypted by older versions.
+func NewRSADecryptor(keyPassphrase []byte, legacyPadding bool) Decryptor {
+	return NewLoggingDecryptor(&rsa.RSADecryptor{KeyPassphrase: keyPassphrase, LegacyPadding: legacyPadding})
+}
+
+// NewECIESDecryptor returns an X25519 or P256 decryptor whose private key may
+// be encrypted with keyPassphrase.
+func NewECIESDecryptor(encType EncryptionType, keyPassphrase []byte) Decryptor {
+	curve := ecies.X25519
+	if encType == P256 {
+		curve = ecies.P256
+	}
+	return NewLoggingDecryptor(&ecies.ECIESDecryptor{Curve: curve, KeyPassphrase: keyPassphrase})
+}
+
+// NewMultiDecryptor returns a multi-recipient decryptor whose private key
+// may be encrypted with keyPassphrase.
+func NewMultiDecryptor(keyPassphrase []byte) Decryptor {
+	return NewLoggingDecryptor(&multi.MultiDecryptor{KeyPassphrase: keyPassphrase})
+}
+
+// NewAgeEncryptor returns an age encryptor. With passphrase set the key is a
+// passphrase rather than a list of recipients; armor selects ASCII armor.
+func NewAgeEncryptor(passphrase, armor bool) Encryptor {
+	return NewLoggingEncryptor(&age.AgeEncryptor{Passphrase: passphrase, Armor: armor})
+}
+
+// NewAgeDecryptor returns an age decryptor taking identities, or a
+// passphrase if passphrase is set.
+func NewAgeDecryptor(passphrase bool) Decryptor {
+	return NewLoggingDecryptor(&age.AgeDecryptor{Passphrase: passphrase})
+}
+
+// NewPGPEncryptor returns an OpenPGP encryptor. With passphrase set the key
+// is a passphrase rather than public keys; armor selects ASCII armor.
+func NewPGPEncryptor(passphrase, armor bool) Encryptor {
+	return NewLoggingEncryptor(&pgp.PGPEncryptor{Passphrase: passphrase, Armor: armor})
+}
+
+// NewPGPDecryptor returns an OpenPGP decryptor taking private keys, which
+// keyPassphrase unlocks, or a passphrase if passphrase is set.
+func NewPGPDecryptor(passphrase bool, keyPassphrase []byte) Decryptor {
+	return NewLoggingDecryptor(&pgp.PGPDecryptor{Passphrase: passphrase, KeyPassphrase: keyPassphrase})
+}
@@ -1,6 +1,7 @@
 package encryption
 
 import (
+	"context"
 	"time"
 
 	"github.com/dzibukalexander/file-processing/internal/logger"
@@ -14,8 +15,8 @@ func NewLoggingEncryptor(encryptor Encryptor) Encryptor {
 	return &loggingEncryptor{encryptor: encryptor}
 }
 
-func (l *loggingEncryptor) Encrypt(data []byte, key []byte) (result []byte, err error) {
-	log := logger.GetInstance().WithField("input_size", len(data))
+func (l *loggingEncryptor) Encrypt(ctx context.Context, data []byte, key []byte) (result []byte, err error) {
+	log := logger.For("encryption").WithField("input_size", len(data))
 	log.Info("Starting encryption")
 
 	defer func(begin time.Time) {
@@ -29,7 +30,7 @@ func (l *loggingEncryptor) Encrypt(data []byte, key []byte) (result []byte, err
 		}
 	}(time.Now())
 
-	return l.encryptor.Encrypt(data, key)
+	return l.encryptor.Encrypt(ctx, data, key)
 }
 
 type loggingDecryptor struct {
@@ -40,8 +41,8 @@ func NewLoggingDecryptor(decryptor Decryptor) Decryptor {
 	return &loggingDecryptor{decryptor: decryptor}
 }
 
-func (l *loggingDecryptor) Decrypt(data []byte, key []byte) (result []byte, err error) {
-	log := logger.GetInstance().WithField("input_size", len(data))
+func (l *loggingDecryptor) Decrypt(ctx context.Context, data []byte, key []byte) (result []byte, err error) {
+	log := logger.For("encryption").WithField("input_size", len(data))
 	log.Info("Starting decryption")
 
 	defer func(begin time.Time) {
@@ -55,5 +56,5 @@ func (l *loggingDecryptor) Decrypt(data []byte, key []byte) (result []byte, err
 		}
 	}(time.Now())
 
-	return l.decryptor.Decrypt(data, key)
+	return l.decryptor.Decrypt(ctx, data, key)
 }
@@ -2,6 +2,7 @@ package encryption
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"testing"
 
@@ -17,8 +18,8 @@ type MockEncryptor struct {
 	mock.Mock
 }
 
-func (m *MockEncryptor) Encrypt(data []byte, key []byte) ([]byte, error) {
-	args := m.Called(data, key)
+func (m *MockEncryptor) Encrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	args := m.Called(ctx, data, key)
 	return args.Get(0).([]byte), args.Error(1)
 }
 
@@ -27,8 +28,8 @@ type MockDecryptor struct {
 	mock.Mock
 }
 
-func (m *MockDecryptor) Decrypt(data []byte, key []byte) ([]byte, error) {
-	args := m.Called(data, key)
+func (m *MockDecryptor) Decrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	args := m.Called(ctx, data, key)
 	return args.Get(0).([]byte), args.Error(1)
 }
 
@@ -51,10 +52,10 @@ func TestLoggingEncryptor(t *testing.T) {
 			mockEnc := new(MockEncryptor)
 			data := []byte("data")
 			key := []byte("key")
-			mockEnc.On("Encrypt", data, key).Return([]byte("encrypted"), nil)
+			mockEnc.On("Encrypt", mock.Anything, data, key).Return([]byte("encrypted"), nil)
 
 			loggingEnc := NewLoggingEncryptor(mockEnc)
-			_, err := loggingEnc.Encrypt(data, key)
+			_, err := loggingEnc.Encrypt(context.Background(), data, key)
 
 			s.Assert().NoError(err)
 			s.Assert().Contains(logOutput.String(), "Starting encryption")
@@ -75,10 +76,10 @@ func TestLoggingDecryptor_Error(t *testing.T) {
 			data := []byte("data")
 			key := []byte("key")
 			expectedErr := errors.New("decryption error")
-			mockDec.On("Decrypt", data, key).Return([]byte(nil), expectedErr)
+			mockDec.On("Decrypt", mock.Anything, data, key).Return([]byte(nil), expectedErr)
 
 			loggingDec := NewLoggingDecryptor(mockDec)
-			_, err := loggingDec.Decrypt(data, key)
+			_, err := loggingDec.Decrypt(context.Background(), data, key)
 
 			s.Assert().Error(err)
 			s.Assert().Equal(expectedErr, err)
@@ -0,0 +1,326 @@
+// Package multi encrypts data once for several recipients. A random AES-256
+// data key encrypts the payload and is wrapped separately for each
+// recipient's public key, so any one of the matching private keys can
+// decrypt:
+//
+//	"FPMR" | version (1) | count (uint16 BE) | stanza... | nonce (12) | AES-GCM ciphertext
+//	stanza: type (1) | key ID (8) | length (uint16 BE) | wrapped data key
+//
+// RSA stanzas hold the data key encrypted with RSA-OAEP (SHA-256). X25519
+// stanzas hold an ephemeral public key followed by the data key sealed with
+// ChaCha20-Poly1305 under a key derived from the shared secret with HKDF.
+// The key ID is a hash of the recipient's public key. The whole header is
+// authenticated with the payload, so stanzas cannot be added or removed.
+package multi
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/dzibukalexander/file-processing/internal/encryption/constants"
+	"github.com/dzibukalexander/file-processing/internal/keyfile"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	version  = 1
+	keyIDLen = 8
+	dataKey  = 32
+
+	stanzaRSA    = 1
+	stanzaX25519 = 2
+)
+
+var (
+	magic     = []byte("FPMR")
+	oaepLabel = []byte("file-processing multi-recipient")
+	hkdfInfo  = "file-processing x25519 recipient"
+)
+
+// IsMultiRecipient reports whether data starts like a multi-recipient
+// ciphertext.
+func IsMultiRecipient(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// MultiEncryptor encrypts for every PEM public key in the key passed to
+// Encrypt, which may hold several concatenated PEM blocks.
+type MultiEncryptor struct{}
+
+func (e *MultiEncryptor) Encrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	recipients := splitPEM(key)
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipient public keys given")
+	}
+	if len(recipients) > math.MaxUint16 {
+		return nil, fmt.Errorf("too many recipients: %d", len(recipients))
+	}
+
+	fileKey := make([]byte, dataKey)
+	if _, err := io.ReadFull(rand.Reader, fileKey); err != nil {
+		return nil, err
+	}
+
+	header := append(bytes.Clone(magic), version, 0, 0)
+	binary.BigEndian.PutUint16(header[len(magic)+1:], uint16(len(recipients)))
+	seen := make(map[string]bool)
+	for i, recipient := range recipients {
+		pub, err := keyfile.ParsePublicKey(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("recipient %d: %w", i+1, err)
+		}
+		id, err := keyID(pub)
+		if err != nil {
+			return nil, fmt.Errorf("recipient %d: %w", i+1, err)
+		}
+		if seen[string(id)] {
+			return nil, fmt.Errorf("recipient %d is listed twice", i+1)
+		}
+		seen[string(id)] = true
+
+		typ, wrapped, err := wrap(pub, fileKey)
+		if err != nil {
+			return nil, fmt.Errorf("recipient %d: %w", i+1, err)
+		}
+		header = append(header, typ)
+		header = append(header, id...)
+		header = binary.BigEndian.AppendUint16(header, uint16(len(wrapped)))
+		header = append(header, wrapped...)
+	}
+
+	gcm, err := newGCM(fileKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	out := append(header, nonce...)
+	return gcm.Seal(out, nonce, data, header), nil
+}
+
+// MultiDecryptor decrypts with a PEM private key of one of the recipients,
+// which KeyPassphrase unlocks if it is encrypted. It returns
+// constants.ErrWrongKey if the key is not a recipient.
+type MultiDecryptor struct {
+	KeyPassphrase []byte
+}
+
+func (d *MultiDecryptor) Decrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	priv, err := keyfile.ParsePrivateKeyWithPassphrase(key, d.KeyPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	pk, ok := priv.(keyfile.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", priv)
+	}
+	id, err := keyID(pk.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	header, stanzas, err := parseHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	var fileKey []byte
+	for _, s := range stanzas {
+		if bytes.Equal(s.id, id) {
+			if fileKey, err = unwrap(priv, s); err != nil {
+				return nil, fmt.Errorf("%w: cannot unwrap the data key", constants.ErrCorruptedData)
+			}
+			break
+		}
+	}
+	if fileKey == nil {
+		return nil, fmt.Errorf("%w: key %x is not one of the %d recipients", constants.ErrWrongKey, id, len(stanzas))
+	}
+
+	gcm, err := newGCM(fileKey)
+	if err != nil {
+		return nil, err
+	}
+	rest := data[len(header):]
+	if len(rest) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, fmt.Errorf("%w: ciphertext is too short", constants.ErrCorruptedData)
+	}
+	plain, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], header)
+	if err != nil {
+		return nil, constants.ErrCorruptedData
+	}
+	return plain, nil
+}
+
+// splitPEM returns each PEM block in data, re-encoded on its own.
+func splitPEM(data []byte) [][]byte {
+	var blocks [][]byte
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			return blocks
+		}
+		blocks = append(blocks, pem.EncodeToMemory(block))
+		data = rest
+	}
+}
+
+type stanza struct {
+	typ     byte
+	id      []byte
+	wrapped []byte
+}
+
+// parseHeader returns the header bytes and the stanzas in it.
+func parseHeader(data []byte) ([]byte, []stanza, error) {
+	if !IsMultiRecipient(data) {
+		return nil, nil, errors.New("not a multi-recipient ciphertext")
+	}
+	pos := len(magic)
+	if len(data) < pos+3 {
+		return nil, nil, fmt.Errorf("%w: header is truncated", constants.ErrCorruptedData)
+	}
+	if data[pos] != version {
+		return nil, nil, fmt.Errorf("unsupported multi-recipient version %d", data[pos])
+	}
+	count := int(binary.BigEndian.Uint16(data[pos+1:]))
+	pos += 3
+
+	stanzas := make([]stanza, 0, count)
+	for range count {
+		if len(data) < pos+1+keyIDLen+2 {
+			return nil, nil, fmt.Errorf("%w: header is truncated", constants.ErrCorruptedData)
+		}
+		s := stanza{typ: data[pos], id: data[pos+1 : pos+1+keyIDLen]}
+		pos += 1 + keyIDLen
+		n := int(binary.BigEndian.Uint16(data[pos:]))
+		pos += 2
+		if len(data) < pos+n {
+			return nil, nil, fmt.Errorf("%w: header is truncated", constants.ErrCorruptedData)
+		}
+		s.wrapped = data[pos : pos+n]
+		pos += n
+		stanzas = append(stanzas, s)
+	}
+	return data[:pos], stanzas, nil
+}
+
+func wrap(pub any, fileKey []byte) (byte, []byte, error) {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, fileKey, oaepLabel)
+		return stanzaRSA, wrapped, err
+	case *ecdh.PublicKey:
+		if pub.Curve() != ecdh.X25519() {
+			return 0, nil, errors.New("only X25519 keys are supported for key agreement")
+		}
+		ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return 0, nil, err
+		}
+		shared, err := ephemeral.ECDH(pub)
+		if err != nil {
+			return 0, nil, err
+		}
+		aead, err := x25519KEK(shared, ephemeral.PublicKey(), pub)
+		if err != nil {
+			return 0, nil, err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		return stanzaX25519, aead.Seal(ephemeral.PublicKey().Bytes(), nonce, fileKey, nil), nil
+	default:
+		return 0, nil, fmt.Errorf("unsupported recipient key type %T; use an RSA or X25519 public key", pub)
+	}
+}
+
+func unwrap(priv any, s stanza) ([]byte, error) {
+	switch priv := priv.(type) {
+	case *rsa.PrivateKey:
+		if s.typ != stanzaRSA {
+			return nil, errors.New("stanza is not RSA")
+		}
+		return rsa.DecryptOAEP(sha256.New(), nil, priv, s.wrapped, oaepLabel)
+	case *ecdh.PrivateKey:
+		if s.typ != stanzaX25519 || len(s.wrapped) < 32 {
+			return nil, errors.New("stanza is not X25519")
+		}
+		ephemeral, err := ecdh.X25519().NewPublicKey(s.wrapped[:32])
+		if err != nil {
+			return nil, err
+		}
+		shared, err := priv.ECDH(ephemeral)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := x25519KEK(shared, ephemeral, priv.PublicKey())
+		if err != nil {
+			return nil, err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		return aead.Open(nil, nonce, s.wrapped[32:], nil)
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", priv)
+	}
+}
+
+// x25519KEK derives the key that wraps the data key from the shared secret,
+// salted with both public keys. Each wrapping key is used once, so a zero
+// nonce is safe.
+func x25519KEK(shared []byte, ephemeral, recipient *ecdh.PublicKey) (cipher.AEAD, error) {
+	salt := append(bytes.Clone(ephemeral.Bytes()), recipient.Bytes()...)
+	kek, err := hkdf.Key(sha256.New, shared, salt, hkdfInfo, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.New(kek)
+}
+
+// keyID identifies a public key in stanzas: the first 8 bytes of the
+// SHA-256 of its PKIX encoding.
+func keyID(pub any) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(der)
+	return sum[:keyIDLen], nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// GenerateX25519Key writes an X25519 key pair for use as a recipient to
+// path.priv and path.pub.
+func GenerateX25519Key(path string) error {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate X25519 key pair: %w", err)
+	}
+	return keyfile.WriteKeyPair(path, priv)
+}
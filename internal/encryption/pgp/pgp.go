@@ -0,0 +1,214 @@
+// Package pgp encrypts OpenPGP messages (RFC 4880) that open with gpg and
+// other OpenPGP tools.
+package pgp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/dzibukalexander/file-processing/internal/encryption/constants"
+)
+
+const (
+	messageType = "PGP MESSAGE"
+	armorStart  = "-----BEGIN PGP"
+)
+
+// PGPEncryptor encrypts to the public keys in the key passed to Encrypt (one
+// or more armored or binary keyrings, as gpg --export writes), or with the
+// key as a passphrase if Passphrase is set. Armor selects ASCII armor.
+type PGPEncryptor struct {
+	Passphrase bool
+	Armor      bool
+}
+
+func (e *PGPEncryptor) Encrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	var dst io.Writer = &out
+	var armored io.WriteCloser
+	if e.Armor {
+		var err error
+		if armored, err = armor.Encode(&out, messageType, nil); err != nil {
+			return nil, err
+		}
+		dst = armored
+	}
+
+	hints := &openpgp.FileHints{IsBinary: true}
+	var w io.WriteCloser
+	var err error
+	if e.Passphrase {
+		w, err = openpgp.SymmetricallyEncrypt(dst, []byte(passphrase(key)), hints, nil)
+	} else {
+		var keyring openpgp.EntityList
+		if keyring, err = ReadKeyRing(key); err != nil {
+			return nil, err
+		}
+		w, err = openpgp.Encrypt(dst, keyring, nil, hints, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if armored != nil {
+		if err := armored.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// PGPDecryptor decrypts with the private keys in the key passed to Decrypt,
+// or with the key as a passphrase if Passphrase is set. KeyPassphrase
+// unlocks private keys that are protected by one. Armored input is detected
+// automatically.
+type PGPDecryptor struct {
+	Passphrase    bool
+	KeyPassphrase []byte
+}
+
+func (d *PGPDecryptor) Decrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var keyring openpgp.EntityList
+	if !d.Passphrase {
+		var err error
+		if keyring, err = ReadKeyRing(key); err != nil {
+			return nil, err
+		}
+	}
+
+	var src io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(armorStart)) {
+		block, err := armor.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode armored message: %w", err)
+		}
+		src = block.Body
+	}
+
+	tried := false
+	prompt := func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		if tried {
+			// Called again after a failed attempt.
+			return nil, constants.ErrWrongKey
+		}
+		tried = true
+		if symmetric {
+			if !d.Passphrase {
+				return nil, fmt.Errorf("message is passphrase-encrypted; decrypt with a passphrase")
+			}
+			return []byte(passphrase(key)), nil
+		}
+		for _, k := range keys {
+			if k.PrivateKey != nil && k.PrivateKey.Encrypted {
+				if len(d.KeyPassphrase) == 0 {
+					return nil, errors.New("private key is protected by a passphrase; supply it to unlock the key")
+				}
+				if err := k.PrivateKey.Decrypt([]byte(passphrase(d.KeyPassphrase))); err != nil {
+					return nil, fmt.Errorf("failed to unlock private key: %w", err)
+				}
+			}
+		}
+		return nil, nil
+	}
+
+	md, err := openpgp.ReadMessage(src, keyring, prompt, nil)
+	if err != nil {
+		if errors.Is(err, pgperrors.ErrKeyIncorrect) || errors.Is(err, constants.ErrWrongKey) {
+			return nil, fmt.Errorf("%w: %v", constants.ErrWrongKey, err)
+		}
+		return nil, err
+	}
+	plain, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", constants.ErrCorruptedData, err)
+	}
+	if md.IsSigned && md.SignatureError != nil && md.SignedBy != nil {
+		return nil, fmt.Errorf("message signature is invalid: %w", md.SignatureError)
+	}
+	return plain, nil
+}
+
+// ReadKeyRing parses one or more concatenated keyrings, each either ASCII
+// armored or binary.
+func ReadKeyRing(data []byte) (openpgp.EntityList, error) {
+	text := string(data)
+	if !strings.Contains(text, armorStart) {
+		keyring, err := openpgp.ReadKeyRing(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OpenPGP keys: %w", err)
+		}
+		return keyring, nil
+	}
+
+	var keyring openpgp.EntityList
+	blocks := strings.Split(text, armorStart)
+	for _, block := range blocks[1:] {
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armorStart + block))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OpenPGP keys: %w", err)
+		}
+		keyring = append(keyring, entities...)
+	}
+	return keyring, nil
+}
+
+// GenerateKey writes a new OpenPGP key for name to path.priv and its public
+// key to path.pub, both armored.
+func (e *PGPEncryptor) GenerateKey(path, name string) error {
+	entity, err := openpgp.NewEntity(name, "", "", &packet.Config{RSABits: 3072})
+	if err != nil {
+		return fmt.Errorf("failed to generate OpenPGP key: %w", err)
+	}
+
+	var priv bytes.Buffer
+	w, err := armor.Encode(&priv, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		return err
+	}
+	if err := entity.SerializePrivate(w, nil); err != nil {
+		return fmt.Errorf("failed to write OpenPGP private key: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".priv", append(priv.Bytes(), '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write OpenPGP private key: %w", err)
+	}
+
+	var pub bytes.Buffer
+	if w, err = armor.Encode(&pub, openpgp.PublicKeyType, nil); err != nil {
+		return err
+	}
+	if err := entity.Serialize(w); err != nil {
+		return fmt.Errorf("failed to write OpenPGP public key: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(path+".pub", append(pub.Bytes(), '\n'), 0644)
+}
+
+// passphrase drops the line ending a passphrase file usually has.
+func passphrase(key []byte) string {
+	return strings.TrimRight(string(key), "\r\n")
+}
@@ -2,18 +2,29 @@
 package rsa
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
 	"fmt"
-	"os"
+
+	"github.com/dzibukalexander/file-processing/internal/keyfile"
 )
 
+// DefaultKeyBits is the size of keys made by GenerateKey.
+const DefaultKeyBits = 3072
+
+// RSAEncryptor encrypts with RSA-OAEP (SHA-256). Data longer than the key
+// allows, 318 bytes for a 3072-bit key, is rejected.
 type RSAEncryptor struct{}
 
-func (e *RSAEncryptor) Encrypt(data []byte, key []byte) ([]byte, error) {
+func (e *RSAEncryptor) Encrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	block, _ := pem.Decode(key)
 	if block == nil {
 		return nil, errors.New("failed to parse PEM block containing the public key")
@@ -24,53 +35,65 @@ func (e *RSAEncryptor) Encrypt(data []byte, key []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	return rsa.EncryptPKCS1v15(rand.Reader, pub.(*rsa.PublicKey), data)
-}
-
-type RSADecryptor struct{}
-
-func (d *RSADecryptor) Decrypt(data []byte, key []byte) ([]byte, error) {
-	block, _ := pem.Decode(key)
-	if block == nil {
-		return nil, errors.New("failed to parse PEM block containing the private key")
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("not an RSA public key: %T", pub)
 	}
 
-	priv, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-	if err != nil {
-		return nil, err
+	encrypted, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, rsaPub, data, nil)
+	if errors.Is(err, rsa.ErrMessageTooLong) {
+		return nil, fmt.Errorf("%w: RSA encrypts at most %d bytes with this key; use recipients= for larger data", err, rsaPub.Size()-2*sha256.Size-2)
 	}
+	return encrypted, err
+}
 
-	return rsa.DecryptPKCS1v15(rand.Reader, priv, data)
+// RSADecryptor accepts PKCS#1 and PKCS#8 private keys, either plain or
+// encrypted (encrypted PKCS#8 or legacy encrypted PEM), which KeyPassphrase
+// unlocks. It expects RSA-OAEP (SHA-256) unless LegacyPadding selects
+// PKCS#1 v1.5, which is only kept for data encrypted by older versions.
+type RSADecryptor struct {
+	KeyPassphrase []byte
+	LegacyPadding bool
 }
 
-func (e *RSAEncryptor) GenerateKey(path string) error {
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+func (d *RSADecryptor) Decrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	parsed, err := keyfile.ParsePrivateKeyWithPassphrase(key, d.KeyPassphrase)
 	if err != nil {
-		return fmt.Errorf("failed to generate RSA key pair: %w", err)
+		return nil, err
+	}
+	priv, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("not an RSA private key: %T", parsed)
 	}
 
-	// Save private key
-	privBytes := x509.MarshalPKCS1PrivateKey(privateKey)
-	privBlock := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: privBytes}
-	privFile, err := os.Create(path + ".priv")
-	if err != nil {
-		return fmt.Errorf("failed to create private key file: %w", err)
+	if d.LegacyPadding {
+		return rsa.DecryptPKCS1v15(rand.Reader, priv, data)
 	}
-	defer privFile.Close()
-	if err := pem.Encode(privFile, privBlock); err != nil {
-		return fmt.Errorf("failed to write private key: %w", err)
+	plain, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, priv, data, nil)
+	if errors.Is(err, rsa.ErrDecryption) {
+		return nil, fmt.Errorf("%w (data encrypted with PKCS#1 v1.5 padding by older versions needs padding=pkcs1v15)", err)
 	}
+	return plain, err
+}
 
-	// Save public key
-	pubBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
-	if err != nil {
-		return fmt.Errorf("failed to marshal public key: %w", err)
+// GenerateKey writes a new DefaultKeyBits key pair as path.priv and path.pub.
+func (e *RSAEncryptor) GenerateKey(path string) error {
+	return GenerateKeyPair(path, DefaultKeyBits, nil)
+}
+
+// GenerateKeyPair writes a new RSA key of bits (3072 or 4096) as path.priv,
+// in PKCS#8 readable only by the owner and encrypted with passphrase unless
+// it is empty, and path.pub (PKIX).
+func GenerateKeyPair(path string, bits int, passphrase []byte) error {
+	if bits != 3072 && bits != 4096 {
+		return fmt.Errorf("unsupported RSA key size %d; use 3072 or 4096", bits)
 	}
-	pubBlock := &pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes}
-	pubFile, err := os.Create(path + ".pub")
+	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
 	if err != nil {
-		return fmt.Errorf("failed to create public key file: %w", err)
+		return fmt.Errorf("failed to generate RSA key pair: %w", err)
 	}
-	defer pubFile.Close()
-	return pem.Encode(pubFile, pubBlock)
+	return keyfile.WriteEncryptedKeyPair(path, privateKey, passphrase)
 }
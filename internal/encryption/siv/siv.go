@@ -0,0 +1,183 @@
+// internal/encryption/siv/siv.go
+package siv
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+// KeySize is the AES-SIV key generated by GenerateKey: two AES-256 keys, one
+// for S2V and one for CTR. 32 and 48 byte keys (AES-128 and AES-192) are
+// accepted as well.
+const KeySize = 64
+
+// AESSIVEncryptor implements deterministic authenticated encryption with
+// AES-SIV (RFC 5297): the same key and plaintext always give the same
+// ciphertext, so encrypted data can still be deduplicated. The output is
+// the 16-byte synthetic IV followed by the ciphertext.
+type AESSIVEncryptor struct{}
+
+func (e *AESSIVEncryptor) Encrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return Seal(key, data)
+}
+
+type AESSIVDecryptor struct{}
+
+func (d *AESSIVDecryptor) Decrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return Open(key, data)
+}
+
+func (e *AESSIVEncryptor) GenerateKey(path string) error {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return fmt.Errorf("failed to generate AES-SIV key: %w", err)
+	}
+	return os.WriteFile(path, key, 0600)
+}
+
+// Seal encrypts plaintext, authenticating it together with the associated data.
+func Seal(key, plaintext []byte, associatedData ...[]byte) ([]byte, error) {
+	macBlock, ctrBlock, err := newCiphers(key)
+	if err != nil {
+		return nil, err
+	}
+	v := s2v(macBlock, append(slices.Clip(associatedData), plaintext))
+	out := make([]byte, aes.BlockSize+len(plaintext))
+	copy(out, v)
+	cipher.NewCTR(ctrBlock, counter(v)).XORKeyStream(out[aes.BlockSize:], plaintext)
+	return out, nil
+}
+
+// Open decrypts and authenticates data produced by Seal with the same associated data.
+func Open(key, data []byte, associatedData ...[]byte) ([]byte, error) {
+	macBlock, ctrBlock, err := newCiphers(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aes.BlockSize {
+		return nil, errors.New("ciphertext too short")
+	}
+	v := data[:aes.BlockSize]
+	plaintext := make([]byte, len(data)-aes.BlockSize)
+	cipher.NewCTR(ctrBlock, counter(v)).XORKeyStream(plaintext, data[aes.BlockSize:])
+	if subtle.ConstantTimeCompare(v, s2v(macBlock, append(slices.Clip(associatedData), plaintext))) != 1 {
+		return nil, errors.New("message authentication failed")
+	}
+	return plaintext, nil
+}
+
+func newCiphers(key []byte) (cipher.Block, cipher.Block, error) {
+	switch len(key) {
+	case 32, 48, 64:
+	default:
+		return nil, nil, fmt.Errorf("AES-SIV key must be 32, 48 or 64 bytes, got %d", len(key))
+	}
+	half := len(key) / 2
+	macBlock, err := aes.NewCipher(key[:half])
+	if err != nil {
+		return nil, nil, err
+	}
+	ctrBlock, err := aes.NewCipher(key[half:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return macBlock, ctrBlock, nil
+}
+
+// counter clears the two bits of the synthetic IV that RFC 5297 masks so
+// implementations can use 32 or 64-bit counters.
+func counter(v []byte) []byte {
+	q := append([]byte(nil), v...)
+	q[8] &= 0x7f
+	q[12] &= 0x7f
+	return q
+}
+
+// s2v is the S2V construction over strings; the last one is the plaintext.
+func s2v(block cipher.Block, strings [][]byte) []byte {
+	d := cmac(block, make([]byte, aes.BlockSize))
+	for _, s := range strings[:len(strings)-1] {
+		d = xor(dbl(d), cmac(block, s))
+	}
+
+	last := strings[len(strings)-1]
+	if len(last) >= aes.BlockSize {
+		t := append([]byte(nil), last...)
+		tail := t[len(t)-aes.BlockSize:]
+		copy(tail, xor(tail, d))
+		return cmac(block, t)
+	}
+	return cmac(block, xor(dbl(d), pad(last)))
+}
+
+// cmac computes AES-CMAC (RFC 4493).
+func cmac(block cipher.Block, msg []byte) []byte {
+	k1 := make([]byte, aes.BlockSize)
+	block.Encrypt(k1, k1)
+	k1 = dbl(k1)
+	k2 := dbl(k1)
+
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	var last []byte
+	if n > 0 && len(msg)%aes.BlockSize == 0 {
+		last = xor(msg[(n-1)*aes.BlockSize:], k1)
+	} else {
+		if n == 0 {
+			n = 1
+		}
+		last = xor(pad(msg[(n-1)*aes.BlockSize:]), k2)
+	}
+
+	x := make([]byte, aes.BlockSize)
+	for i := 0; i < n-1; i++ {
+		x = xor(x, msg[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		block.Encrypt(x, x)
+	}
+	x = xor(x, last)
+	block.Encrypt(x, x)
+	return x
+}
+
+// dbl multiplies a block by x in GF(2^128).
+func dbl(b []byte) []byte {
+	out := make([]byte, aes.BlockSize)
+	carry := byte(0)
+	for i := aes.BlockSize - 1; i >= 0; i-- {
+		out[i] = b[i]<<1 | carry
+		carry = b[i] >> 7
+	}
+	if carry != 0 {
+		out[aes.BlockSize-1] ^= 0x87
+	}
+	return out
+}
+
+// pad appends the 10* padding to a partial block.
+func pad(b []byte) []byte {
+	out := make([]byte, aes.BlockSize)
+	copy(out, b)
+	out[len(b)] = 0x80
+	return out
+}
+
+func xor(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
@@ -0,0 +1,339 @@
+// Package stream implements segmented AES-GCM encryption using the STREAM
+// construction (Hoang, Reyhanitabar, Rogaway and Vizár, "Online
+// Authenticated-Encryption and its Nonce-Reuse Misuse-Resistance"), so large
+// inputs are encrypted and decrypted a chunk at a time.
+//
+// An encrypted stream is a 16-byte header followed by chunks:
+//
+//	header: "FPST" | version (1) | chunk size (uint32 BE) | nonce prefix (7)
+//	chunk:  AES-GCM(plaintext chunk), sealed with the header as associated data
+//
+// Every chunk but the last holds exactly chunk size bytes of plaintext. The
+// nonce of chunk i is prefix || i (uint32 BE) || last, where last is 1 only
+// for the final chunk, so dropping, reordering or truncating chunks makes
+// decryption fail.
+package stream
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	version    = 1
+	headerSize = 16
+	prefixSize = 7
+	// DefaultChunkSize is the plaintext size of each chunk unless set.
+	DefaultChunkSize = 64 * 1024
+	// MaxChunkSize bounds the memory a reader allocates for one chunk.
+	MaxChunkSize = 16 * 1024 * 1024
+)
+
+var magic = []byte("FPST")
+
+var (
+	// ErrTruncated is returned when the stream ends before its final chunk.
+	ErrTruncated = errors.New("encrypted stream is truncated")
+	// ErrCorrupted is returned when a chunk fails authentication, because
+	// the data was modified or reordered or the key is wrong.
+	ErrCorrupted = errors.New("encrypted stream is corrupted or the key is wrong")
+)
+
+// Writer encrypts everything written to it. Close must be called to write
+// the final chunk.
+type Writer struct {
+	w         io.Writer
+	aead      cipher.AEAD
+	header    []byte
+	chunkSize int
+	counter   uint64
+	buf       []byte
+	closed    bool
+	err       error
+}
+
+// NewWriter writes a stream header to w and returns a writer that encrypts
+// into it with key, an AES-128, AES-192 or AES-256 key. A chunkSize of 0
+// selects DefaultChunkSize.
+func NewWriter(w io.Writer, key []byte, chunkSize int) (*Writer, error) {
+	if chunkSize == 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize < 1 || chunkSize > MaxChunkSize {
+		return nil, fmt.Errorf("chunk size must be between 1 and %d bytes, got %d", MaxChunkSize, chunkSize)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	header[4] = version
+	binary.BigEndian.PutUint32(header[5:9], uint32(chunkSize))
+	if _, err := io.ReadFull(rand.Reader, header[9:]); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &Writer{
+		w:         w,
+		aead:      aead,
+		header:    header,
+		chunkSize: chunkSize,
+		buf:       make([]byte, 0, chunkSize+1),
+	}, nil
+}
+
+// Write encrypts and writes every full chunk of p. A chunk is only written
+// once more data follows it, since the last chunk is sealed differently.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed stream")
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), w.chunkSize+1-len(w.buf))
+		w.buf = append(w.buf, p[:n]...)
+		p = p[n:]
+		written += n
+		if len(w.buf) > w.chunkSize {
+			if w.err = w.flush(w.buf[:w.chunkSize], false); w.err != nil {
+				return written, w.err
+			}
+			w.buf = append(w.buf[:0], w.buf[w.chunkSize:]...)
+		}
+	}
+	return written, nil
+}
+
+// Close writes the final chunk. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.flush(w.buf, true)
+	return w.err
+}
+
+func (w *Writer) flush(chunk []byte, last bool) error {
+	nonce, err := chunkNonce(w.header, w.counter, last)
+	if err != nil {
+		return err
+	}
+	w.counter++
+	_, err = w.w.Write(w.aead.Seal(nil, nonce, chunk, w.header))
+	return err
+}
+
+// Reader decrypts a stream written by Writer.
+type Reader struct {
+	r         io.Reader
+	aead      cipher.AEAD
+	header    []byte
+	chunkSize int
+	counter   uint64
+	buf       []byte
+	pending   int
+	out       []byte
+	plain     []byte
+	done      bool
+	err       error
+}
+
+// NewReader reads and checks the stream header from r and returns a reader
+// that decrypts the chunks that follow with key.
+func NewReader(r io.Reader, key []byte) (*Reader, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrTruncated
+		}
+		return nil, err
+	}
+	if !bytes.Equal(header[:4], magic) {
+		return nil, errors.New("not an encrypted stream")
+	}
+	if header[4] != version {
+		return nil, fmt.Errorf("unsupported encrypted stream version %d", header[4])
+	}
+	chunkSize := int(binary.BigEndian.Uint32(header[5:9]))
+	if chunkSize < 1 || chunkSize > MaxChunkSize {
+		return nil, fmt.Errorf("invalid chunk size %d in encrypted stream", chunkSize)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		r:         r,
+		aead:      aead,
+		header:    header,
+		chunkSize: chunkSize,
+		buf:       make([]byte, chunkSize+aead.Overhead()+1),
+		out:       make([]byte, 0, chunkSize),
+	}, nil
+}
+
+// Read returns decrypted data. Plaintext is only returned once its chunk has
+// been authenticated; io.EOF is returned after the final chunk.
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.err = r.next()
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+// next decrypts the following chunk. One byte past the chunk is read ahead
+// to tell whether it is the last one.
+func (r *Reader) next() error {
+	full := r.chunkSize + r.aead.Overhead()
+	n, err := io.ReadFull(r.r, r.buf[r.pending:])
+	n += r.pending
+	last := false
+	switch {
+	case err == nil:
+		n = full
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		last = true
+	default:
+		return err
+	}
+	if n < r.aead.Overhead() {
+		return ErrTruncated
+	}
+
+	nonce, err := chunkNonce(r.header, r.counter, last)
+	if err != nil {
+		return err
+	}
+	plain, err := r.aead.Open(r.out[:0], nonce, r.buf[:n], r.header)
+	if err != nil {
+		// A chunk that opens as a middle chunk means the stream was cut
+		// at a chunk boundary.
+		if last && n == full {
+			if middle, _ := chunkNonce(r.header, r.counter, false); middle != nil {
+				if _, err := r.aead.Open(nil, middle, r.buf[:n], r.header); err == nil {
+					return ErrTruncated
+				}
+			}
+		}
+		return fmt.Errorf("%w (chunk %d)", ErrCorrupted, r.counter)
+	}
+	r.counter++
+	r.plain = plain
+	r.done = last
+
+	r.pending = 0
+	if !last {
+		r.buf[0] = r.buf[full]
+		r.pending = 1
+	}
+	return nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(header []byte, counter uint64, last bool) ([]byte, error) {
+	if counter > math.MaxUint32 {
+		return nil, errors.New("encrypted stream has too many chunks")
+	}
+	nonce := make([]byte, 12)
+	copy(nonce, header[9:headerSize])
+	binary.BigEndian.PutUint32(nonce[prefixSize:], uint32(counter))
+	if last {
+		nonce[11] = 1
+	}
+	return nonce, nil
+}
+
+// Encrypt seals data as a stream with the given chunk size, checking ctx
+// between chunks.
+func Encrypt(ctx context.Context, data, key []byte, chunkSize int) ([]byte, error) {
+	var out bytes.Buffer
+	w, err := NewWriter(&out, key, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	out.Grow(len(data) + (len(data)/w.chunkSize+1)*w.aead.Overhead())
+	for len(data) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n := min(len(data), w.chunkSize)
+		if _, err := w.Write(data[:n]); err != nil {
+			return nil, err
+		}
+		data = data[n:]
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Decrypt opens a stream held in memory, checking ctx between chunks.
+func Decrypt(ctx context.Context, data, key []byte) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(data), key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(data))
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := r.next(); err != nil {
+			return nil, err
+		}
+		out = append(out, r.plain...)
+		if r.done {
+			return out, nil
+		}
+	}
+}
+
+// StreamEncryptor encrypts in ChunkSize segments (DefaultChunkSize if 0).
+type StreamEncryptor struct {
+	ChunkSize int
+}
+
+func (e *StreamEncryptor) Encrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	return Encrypt(ctx, data, key, e.ChunkSize)
+}
+
+type StreamDecryptor struct{}
+
+func (d *StreamDecryptor) Decrypt(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	return Decrypt(ctx, data, key)
+}
@@ -21,6 +21,9 @@ func FileTypeFromExtension(filename string) (FileType, error) {
 	switch ext {
 	case ".txt", ".text":
 		return TEXT, nil
+	case ".age", ".gpg", ".pgp", ".asc":
+		// Encrypted files are passed through as raw bytes.
+		return TEXT, nil
 	case ".json":
 		return JSON, nil
 	case ".xml":
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/dzibukalexander/file-processing/internal/fileio/constants"
+	"github.com/dzibukalexander/file-processing/internal/fileio/writer"
 	"github.com/ozontech/allure-go/pkg/framework/provider"
 	"github.com/ozontech/allure-go/pkg/framework/runner"
 )
@@ -53,3 +54,53 @@ func TestFileIO_ReadWrite(t *testing.T) {
 		}
 	})
 }
+
+func TestFileIO_WriterOptions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fileType constants.FileType
+		filename string
+		opts     writer.Options
+		data     string
+		expected string
+	}{
+		{"json indent", constants.JSON, "indent.json", writer.Options{Indent: 2}, `{"b":1,"a":[1,2]}`, "{\n  \"b\": 1,\n  \"a\": [\n    1,\n    2\n  ]\n}\n"},
+		{"json sort keys", constants.JSON, "sorted.json", writer.Options{SortKeys: true}, `{"b": 1, "a": {"d": 2, "c": 3}}`, `{"a":{"c":3,"d":2},"b":1}`},
+		{"json compact", constants.JSON, "compact.json", writer.Options{Compact: true}, "{\n  \"b\": 1,\n  \"a\": 2\n}", `{"b":1,"a":2}`},
+		{"json canonical", constants.JSON, "canonical.json", writer.Options{Canonical: true}, `{"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001], "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/", "literals": [null, true, false]}`, `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`},
+		{"yaml sort keys", constants.YAML, "sorted.yaml", writer.Options{SortKeys: true, Indent: 2}, "b: 1\na:\n  d: 2\n  c: 3\n", "a:\n  c: 3\n  d: 2\nb: 1\n"},
+		{"xml indent", constants.XML, "indent.xml", writer.Options{Indent: 2}, `<root><item id="1">a</item><item id="2"/></root>`, "<root>\n  <item id=\"1\">a</item>\n  <item id=\"2\"></item>\n</root>\n"},
+	}
+
+	runner.Run(t, "FileIO writer options", func(at provider.T) {
+		tempDir, cleanup := setupTest(t)
+		defer cleanup()
+
+		for _, tc := range testCases {
+			tc := tc
+			at.WithNewStep(tc.name, func(s provider.StepCtx) {
+				filePath := filepath.Join(tempDir, tc.filename)
+				w := NewWriterWithOptions(tc.fileType, tc.opts)
+				s.Require().NoError(w.Write(filePath, []byte(tc.data)))
+
+				written, err := os.ReadFile(filePath)
+				s.Require().NoError(err)
+				s.Assert().Equal(tc.expected, string(written))
+			})
+		}
+	})
+}
+
+func TestWriterParseOptions(t *testing.T) {
+	runner.Run(t, "Writer ParseOptions", func(at provider.T) {
+		at.WithNewStep("valid", func(s provider.StepCtx) {
+			opts, err := writer.ParseOptions(map[string]string{"indent": "2", "sort_keys": "true"})
+			s.Require().NoError(err)
+			s.Assert().Equal(writer.Options{Indent: 2, SortKeys: true}, opts)
+		})
+		at.WithNewStep("conflicting", func(s provider.StepCtx) {
+			_, err := writer.ParseOptions(map[string]string{"indent": "2", "compact": "true"})
+			s.Assert().Error(err)
+		})
+	})
+}
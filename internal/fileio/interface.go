@@ -20,6 +20,11 @@ type FileIO interface {
 	FileWriter
 }
 
+// Formatter applies output options to data without writing it anywhere.
+type Formatter interface {
+	Format(data []byte) ([]byte, error)
+}
+
 func NewFileReader(fileType constants.FileType) FileReader {
 	var r FileReader
 	switch fileType {
@@ -38,14 +43,20 @@ func NewFileReader(fileType constants.FileType) FileReader {
 }
 
 func NewWriter(fileType constants.FileType) FileWriter {
+	return NewWriterWithOptions(fileType, writer.Options{})
+}
+
+// NewWriterWithOptions creates a writer that formats structured output
+// (JSON, YAML, XML) according to opts. Other file types ignore opts.
+func NewWriterWithOptions(fileType constants.FileType, opts writer.Options) FileWriter {
 	var w FileWriter
 	switch fileType {
 	case constants.JSON:
-		w = &writer.JSONWriter{}
+		w = &writer.JSONWriter{Options: opts}
 	case constants.XML:
-		w = &writer.XMLWriter{}
+		w = &writer.XMLWriter{Options: opts}
 	case constants.YAML:
-		w = &writer.YAMLWriter{}
+		w = &writer.YAMLWriter{Options: opts}
 	case constants.HTML:
 		w = &writer.HTMLWriter{}
 	default:
@@ -53,3 +64,20 @@ func NewWriter(fileType constants.FileType) FileWriter {
 	}
 	return NewLoggingFileWriter(w)
 }
+
+// Format applies the structured output options for fileType to data, for
+// sinks that are not files such as stdout or an in-memory buffer.
+func Format(fileType constants.FileType, opts writer.Options, data []byte) ([]byte, error) {
+	var f Formatter
+	switch fileType {
+	case constants.JSON:
+		f = &writer.JSONWriter{Options: opts}
+	case constants.XML:
+		f = &writer.XMLWriter{Options: opts}
+	case constants.YAML:
+		f = &writer.YAMLWriter{Options: opts}
+	default:
+		return data, nil
+	}
+	return f.Format(data)
+}
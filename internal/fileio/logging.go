@@ -15,7 +15,7 @@ func NewLoggingFileReader(reader FileReader) FileReader {
 }
 
 func (l *loggingFileReader) Read(filePath string) (data []byte, err error) {
-	log := logger.GetInstance().WithField("path", filePath)
+	log := logger.For("fileio").WithField("path", filePath)
 	log.Info("Starting file read")
 
 	defer func(begin time.Time) {
@@ -41,7 +41,7 @@ func NewLoggingFileWriter(writer FileWriter) FileWriter {
 }
 
 func (l *loggingFileWriter) Write(filePath string, data []byte) (err error) {
-	log := logger.GetInstance().WithFields(map[string]interface{}{
+	log := logger.For("fileio").WithFields(map[string]interface{}{
 		"path": filePath,
 		"size": len(data),
 	})
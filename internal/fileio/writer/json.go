@@ -1,21 +1,86 @@
 package writer
 
 import (
+	"bytes"
 	"encoding/json"
 	"os"
+	"strings"
 )
 
-type JSONWriter struct{}
+type JSONWriter struct {
+	Options Options
+}
 
 // '0644 FileMode' means you can read and write the file or
 //
 //	directory and other users can only read it.
 //	Suitable for public text files.
 func (w *JSONWriter) Write(filePath string, data []byte) error {
+	formatted, err := w.Format(data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, formatted, 0644)
+}
+
+// Format validates data as JSON and applies the writer options.
+func (w *JSONWriter) Format(data []byte) ([]byte, error) {
 	// Validate data is valid JSON
 	var temp interface{}
 	if err := json.Unmarshal(data, &temp); err != nil {
-		return err
+		return nil, err
+	}
+
+	opts := w.Options
+	switch {
+	case opts.IsZero():
+		return data, nil
+	case opts.Canonical:
+		return CanonicalizeJSON(data)
+	case opts.SortKeys:
+		sorted, err := sortJSONKeys(data)
+		if err != nil {
+			return nil, err
+		}
+		return indentJSON(sorted, opts.Indent)
+	case opts.Compact:
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, data); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return indentJSON(data, opts.Indent)
+	}
+}
+
+// sortJSONKeys re-encodes data with object keys in sorted order while
+// keeping numbers exactly as they were written.
+func sortJSONKeys(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func indentJSON(data []byte, indent int) ([]byte, error) {
+	if indent == 0 {
+		return data, nil
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", strings.Repeat(" ", indent)); err != nil {
+		return nil, err
 	}
-	return os.WriteFile(filePath, data, 0644)
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,64 @@
+package writer
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Options controls how structured writers (JSON, YAML, XML) format their output.
+// The zero value writes data verbatim.
+type Options struct {
+	Indent    int
+	SortKeys  bool
+	Canonical bool
+	Compact   bool
+}
+
+// IsZero reports whether no formatting was requested.
+func (o Options) IsZero() bool {
+	return o == Options{}
+}
+
+// ParseOptions builds Options from "key=value" parameters such as
+// indent=2, sort_keys=true, canonical=true and compact=true.
+// Unknown keys are ignored so callers can share one parameter map.
+func ParseOptions(params map[string]string) (Options, error) {
+	var opts Options
+	var err error
+
+	if v, ok := params["indent"]; ok {
+		opts.Indent, err = strconv.Atoi(v)
+		if err != nil || opts.Indent < 0 {
+			return Options{}, fmt.Errorf("invalid indent: %s", v)
+		}
+	}
+	if opts.SortKeys, err = parseBool(params, "sort_keys"); err != nil {
+		return Options{}, err
+	}
+	if opts.Canonical, err = parseBool(params, "canonical"); err != nil {
+		return Options{}, err
+	}
+	if opts.Compact, err = parseBool(params, "compact"); err != nil {
+		return Options{}, err
+	}
+
+	if opts.Compact && opts.Indent > 0 {
+		return Options{}, fmt.Errorf("compact and indent cannot be used together")
+	}
+	if opts.Canonical && opts.Indent > 0 {
+		return Options{}, fmt.Errorf("canonical output cannot be indented")
+	}
+	return opts, nil
+}
+
+func parseBool(params map[string]string, key string) (bool, error) {
+	v, ok := params[key]
+	if !ok {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %s", key, v)
+	}
+	return b, nil
+}
@@ -1,15 +1,130 @@
 package writer
 
 import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
 )
 
-type XMLWriter struct{}
+type XMLWriter struct {
+	Options Options
+}
 
 // '0644 FileMode' means you can read and write the file or
 //
 //	directory and other users can only read it.
 //	Suitable for public text files.
 func (x *XMLWriter) Write(filePath string, data []byte) error {
-	return os.WriteFile(filePath, data, 0644)
+	formatted, err := x.Format(data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, formatted, 0644)
+}
+
+// Format re-serializes the document token by token. Raw tokens are used so
+// namespace prefixes are written back exactly as they appeared in the input.
+func (x *XMLWriter) Format(data []byte) ([]byte, error) {
+	opts := x.Options
+	if opts.IsZero() {
+		return data, nil
+	}
+	if opts.Canonical {
+		return nil, fmt.Errorf("canonical output is only supported for JSON")
+	}
+
+	indent := strings.Repeat(" ", opts.Indent)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var buf bytes.Buffer
+	depth := 0
+	// lastWasStart tracks whether an element has had no children yet, so
+	// <a>text</a> stays on one line when indenting.
+	lastWasStart := false
+
+	newline := func(level int) {
+		if indent == "" {
+			return
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(strings.Repeat(indent, level))
+	}
+
+	for {
+		tok, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			newline(depth)
+			attrs := t.Attr
+			if opts.SortKeys {
+				attrs = append([]xml.Attr(nil), attrs...)
+				sort.SliceStable(attrs, func(i, j int) bool {
+					return qualifiedName(attrs[i].Name) < qualifiedName(attrs[j].Name)
+				})
+			}
+			buf.WriteString("<" + qualifiedName(t.Name))
+			for _, attr := range attrs {
+				buf.WriteString(" " + qualifiedName(attr.Name) + `="`)
+				if err := xml.EscapeText(&buf, []byte(attr.Value)); err != nil {
+					return nil, err
+				}
+				buf.WriteByte('"')
+			}
+			buf.WriteByte('>')
+			depth++
+			lastWasStart = true
+		case xml.EndElement:
+			depth--
+			if !lastWasStart {
+				newline(depth)
+			}
+			buf.WriteString("</" + qualifiedName(t.Name) + ">")
+			lastWasStart = false
+		case xml.CharData:
+			text := t
+			if indent != "" || opts.Compact {
+				text = bytes.TrimSpace(t)
+			}
+			if len(text) == 0 {
+				continue
+			}
+			if err := xml.EscapeText(&buf, text); err != nil {
+				return nil, err
+			}
+		case xml.Comment:
+			newline(depth)
+			buf.WriteString("<!--" + string(t) + "-->")
+			lastWasStart = false
+		case xml.ProcInst:
+			newline(depth)
+			buf.WriteString("<?" + t.Target + " " + string(t.Inst) + "?>")
+		case xml.Directive:
+			newline(depth)
+			buf.WriteString("<!" + string(t) + ">")
+		}
+	}
+
+	if indent != "" {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
 }
@@ -1,13 +1,19 @@
 package writer
 
 import (
+	"bytes"
+	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/dzibukalexander/file-processing/internal/fileio/constants"
+	"gopkg.in/yaml.v3"
 )
 
-type YAMLWriter struct{}
+type YAMLWriter struct {
+	Options Options
+}
 
 // '0644 FileMode' means you can read and write the file or
 //
@@ -17,5 +23,74 @@ func (y *YAMLWriter) Write(filePath string, data []byte) error {
 	ext := strings.ToLower(string(constants.YAML))
 	basePath := strings.TrimSuffix(filePath, "."+ext)
 	outputPath := basePath + "." + ext
-	return os.WriteFile(outputPath, data, 0644)
+	formatted, err := y.Format(data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, formatted, 0644)
+}
+
+// Format re-encodes the YAML document according to the writer options.
+func (y *YAMLWriter) Format(data []byte) ([]byte, error) {
+	opts := y.Options
+	if opts.IsZero() {
+		return data, nil
+	}
+	if opts.Canonical {
+		return nil, fmt.Errorf("canonical output is only supported for JSON")
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if opts.SortKeys {
+		sortYAMLKeys(&doc)
+	}
+	if opts.Compact {
+		setFlowStyle(&doc)
+	}
+
+	indent := opts.Indent
+	if indent == 0 {
+		indent = 2
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(indent)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func sortYAMLKeys(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		pairs := make([][2]*yaml.Node, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			pairs = append(pairs, [2]*yaml.Node{node.Content[i], node.Content[i+1]})
+		}
+		sort.SliceStable(pairs, func(i, j int) bool {
+			return pairs[i][0].Value < pairs[j][0].Value
+		})
+		node.Content = node.Content[:0]
+		for _, pair := range pairs {
+			node.Content = append(node.Content, pair[0], pair[1])
+		}
+	}
+	for _, child := range node.Content {
+		sortYAMLKeys(child)
+	}
+}
+
+func setFlowStyle(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode {
+		node.Style |= yaml.FlowStyle
+	}
+	for _, child := range node.Content {
+		setFlowStyle(child)
+	}
 }
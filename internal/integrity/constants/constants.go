@@ -0,0 +1,52 @@
+// internal/integrity/constants/constants.go
+package constants
+
+import (
+	"fmt"
+	"strings"
+)
+
+type HashAlgorithm string
+
+const (
+	NONE    HashAlgorithm = "NONE"
+	SHA256  HashAlgorithm = "SHA256"
+	SHA512  HashAlgorithm = "SHA512"
+	BLAKE2B HashAlgorithm = "BLAKE2B"
+	CRC32   HashAlgorithm = "CRC32"
+)
+
+func HashAlgorithmFromString(s string) (HashAlgorithm, error) {
+	switch s {
+	case "NONE":
+		return NONE, nil
+	case "SHA256":
+		return SHA256, nil
+	case "SHA512":
+		return SHA512, nil
+	case "BLAKE2B":
+		return BLAKE2B, nil
+	case "CRC32":
+		return CRC32, nil
+	default:
+		return NONE, fmt.Errorf("unknown hash algorithm: %s", s)
+	}
+}
+
+// Extension returns the sidecar file extension for the algorithm, e.g. ".sha256".
+func (h HashAlgorithm) Extension() string {
+	return "." + strings.ToLower(string(h))
+}
+
+// HashAlgorithmFromPath infers the algorithm from a sidecar file extension.
+func HashAlgorithmFromPath(path string) (HashAlgorithm, bool) {
+	dot := strings.LastIndex(path, ".")
+	if dot < 0 {
+		return NONE, false
+	}
+	algo, err := HashAlgorithmFromString(strings.ToUpper(path[dot+1:]))
+	if err != nil || algo == NONE {
+		return NONE, false
+	}
+	return algo, true
+}
@@ -0,0 +1,48 @@
+package digest
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"hash/crc32"
+
+	"github.com/dzibukalexander/file-processing/internal/ctxio"
+	"github.com/dzibukalexander/file-processing/internal/integrity/constants"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Hasher computes digests with one of the supported hash algorithms.
+// BLAKE2b produces 512-bit digests, as b2sum does; CRC32 uses the IEEE
+// polynomial and is written big-endian.
+type Hasher struct {
+	Algorithm constants.HashAlgorithm
+}
+
+func (h *Hasher) Hash(ctx context.Context, data []byte) ([]byte, error) {
+	digest, err := New(h.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctxio.Write(ctx, digest, data); err != nil {
+		return nil, err
+	}
+	return digest.Sum(nil), nil
+}
+
+// New returns a hash.Hash for the algorithm.
+func New(algo constants.HashAlgorithm) (hash.Hash, error) {
+	switch algo {
+	case constants.SHA256:
+		return sha256.New(), nil
+	case constants.SHA512:
+		return sha512.New(), nil
+	case constants.BLAKE2B:
+		return blake2b.New512(nil)
+	case constants.CRC32:
+		return crc32.NewIEEE(), nil
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", algo)
+	}
+}
@@ -0,0 +1,54 @@
+package integrity
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/dzibukalexander/file-processing/internal/integrity/constants"
+)
+
+// embedMagic starts data that carries its own digest. The layout is
+// magic | algorithm length (1 byte) | algorithm | digest length (1 byte) | digest | data.
+var embedMagic = []byte("FPH1")
+
+// Embed prefixes data with its digest and the algorithm that produced it.
+func Embed(algo constants.HashAlgorithm, digest, data []byte) []byte {
+	out := make([]byte, 0, len(embedMagic)+2+len(algo)+len(digest)+len(data))
+	out = append(out, embedMagic...)
+	out = append(out, byte(len(algo)))
+	out = append(out, algo...)
+	out = append(out, byte(len(digest)))
+	out = append(out, digest...)
+	return append(out, data...)
+}
+
+// Extract splits data written by Embed into the algorithm, digest and payload.
+func Extract(data []byte) (constants.HashAlgorithm, []byte, []byte, error) {
+	if !bytes.HasPrefix(data, embedMagic) {
+		return constants.NONE, nil, nil, errors.New("data has no embedded checksum")
+	}
+	rest := data[len(embedMagic):]
+	field := func() ([]byte, error) {
+		if len(rest) < 1 || len(rest) < 1+int(rest[0]) {
+			return nil, errors.New("embedded checksum header is truncated")
+		}
+		value := rest[1 : 1+int(rest[0])]
+		rest = rest[1+int(rest[0]):]
+		return value, nil
+	}
+
+	name, err := field()
+	if err != nil {
+		return constants.NONE, nil, nil, err
+	}
+	algo, err := constants.HashAlgorithmFromString(string(name))
+	if err != nil {
+		return constants.NONE, nil, nil, fmt.Errorf("embedded checksum: %w", err)
+	}
+	digest, err := field()
+	if err != nil {
+		return constants.NONE, nil, nil, err
+	}
+	return algo, digest, rest, nil
+}
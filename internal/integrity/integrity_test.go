@@ -0,0 +1,83 @@
+package integrity
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/dzibukalexander/file-processing/internal/integrity/constants"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+)
+
+func TestHashers(t *testing.T) {
+	runner.Run(t, "Hash algorithms", func(t provider.T) {
+		t.WithNewStep("known digests of abc", func(s provider.StepCtx) {
+			vectors := map[constants.HashAlgorithm]string{
+				constants.SHA256:  "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+				constants.SHA512:  "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f",
+				constants.BLAKE2B: "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923",
+				constants.CRC32:   "352441c2",
+			}
+			for algo, want := range vectors {
+				digest, err := NewHasher(algo).Hash(context.Background(), []byte("abc"))
+				s.Require().NoError(err, algo)
+				s.Assert().Equal(want, hex.EncodeToString(digest), algo)
+			}
+			s.Assert().Nil(NewHasher(constants.NONE))
+		})
+
+		t.WithNewStep("cancelled context", func(s provider.StepCtx) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := NewHasher(constants.SHA256).Hash(ctx, []byte("abc"))
+			s.Assert().ErrorIs(err, context.Canceled)
+		})
+
+		t.WithNewStep("algorithm from sidecar path", func(s provider.StepCtx) {
+			algo, ok := constants.HashAlgorithmFromPath("out.tar.gz.SHA512")
+			s.Assert().True(ok)
+			s.Assert().Equal(constants.SHA512, algo)
+			_, ok = constants.HashAlgorithmFromPath("out.tar.gz")
+			s.Assert().False(ok)
+			s.Assert().Equal(".blake2b", constants.BLAKE2B.Extension())
+		})
+	})
+}
+
+func TestSums(t *testing.T) {
+	runner.Run(t, "Checksum files", func(t provider.T) {
+		t.WithNewStep("format and parse", func(s provider.StepCtx) {
+			line := FormatSum([]byte{0xab, 0xcd}, "dir/file name.txt")
+			s.Assert().Equal("abcd  dir/file name.txt\n", line)
+
+			sums, err := ParseSums([]byte(line + "# comment\n\n0102 *binary.bin\r\n"))
+			s.Require().NoError(err)
+			s.Require().Len(sums, 2)
+			s.Assert().Equal("dir/file name.txt", sums[0].Name)
+			s.Assert().Equal([]byte{1, 2}, sums[1].Digest)
+			s.Assert().Equal("binary.bin", sums[1].Name)
+		})
+
+		t.WithNewStep("malformed lines", func(s provider.StepCtx) {
+			for _, input := range []string{"abcd\n", "xyz  file\n", "abcd file\n"} {
+				_, err := ParseSums([]byte(input))
+				s.Assert().Error(err, input)
+			}
+		})
+
+		t.WithNewStep("embed and extract", func(s provider.StepCtx) {
+			embedded := Embed(constants.SHA256, []byte{1, 2, 3}, []byte("payload"))
+			algo, digest, payload, err := Extract(embedded)
+			s.Require().NoError(err)
+			s.Assert().Equal(constants.SHA256, algo)
+			s.Assert().Equal([]byte{1, 2, 3}, digest)
+			s.Assert().Equal("payload", string(payload))
+
+			_, _, _, err = Extract([]byte("payload"))
+			s.Assert().Error(err)
+			_, _, _, err = Extract(embedded[:8])
+			s.Assert().Error(err)
+		})
+	})
+}
@@ -0,0 +1,22 @@
+// internal/integrity/interface.go
+package integrity
+
+import (
+	"context"
+
+	. "github.com/dzibukalexander/file-processing/internal/integrity/constants"
+	"github.com/dzibukalexander/file-processing/internal/integrity/digest"
+)
+
+type Hasher interface {
+	Hash(ctx context.Context, data []byte) ([]byte, error)
+}
+
+func NewHasher(algo HashAlgorithm) Hasher {
+	switch algo {
+	case SHA256, SHA512, BLAKE2B, CRC32:
+		return NewLoggingHasher(&digest.Hasher{Algorithm: algo})
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,31 @@
+package integrity
+
+import (
+	"context"
+	"time"
+
+	"github.com/dzibukalexander/file-processing/internal/logger"
+)
+
+type loggingHasher struct {
+	hasher Hasher
+}
+
+func NewLoggingHasher(hasher Hasher) Hasher {
+	return &loggingHasher{hasher: hasher}
+}
+
+func (l *loggingHasher) Hash(ctx context.Context, data []byte) (result []byte, err error) {
+	log := logger.For("integrity").WithField("input_size", len(data))
+	log.Info("Starting hashing")
+
+	defer func(begin time.Time) {
+		if err != nil {
+			log.WithError(err).Error("Hashing failed")
+		} else {
+			log.WithField("duration", time.Since(begin)).Info("Hashing finished")
+		}
+	}(time.Now())
+
+	return l.hasher.Hash(ctx, data)
+}
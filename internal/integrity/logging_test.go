@@ -0,0 +1,70 @@
+package integrity
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dzibukalexander/file-processing/internal/config"
+	"github.com/dzibukalexander/file-processing/internal/logger"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockHasher is a mock for the Hasher interface
+type MockHasher struct {
+	mock.Mock
+}
+
+func (m *MockHasher) Hash(ctx context.Context, data []byte) ([]byte, error) {
+	args := m.Called(ctx, data)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func setupLoggingTest() *bytes.Buffer {
+	config.AppConfig = &config.Config{EnableLogging: true}
+	logger.SetupLogger()
+
+	logOutput := new(bytes.Buffer)
+	logger.GetInstance().SetOutput(logOutput)
+	return logOutput
+}
+
+func TestLoggingHasher(t *testing.T) {
+	runner.Run(t, "LoggingHasher", func(t provider.T) {
+		t.WithNewStep("success path", func(s provider.StepCtx) {
+			logOutput := setupLoggingTest()
+			origOut := logger.GetInstance().Out
+			defer logger.GetInstance().SetOutput(origOut)
+
+			mockHasher := new(MockHasher)
+			input := []byte("data")
+			mockHasher.On("Hash", mock.Anything, input).Return([]byte{1}, nil)
+
+			_, err := NewLoggingHasher(mockHasher).Hash(context.Background(), input)
+
+			s.Assert().NoError(err)
+			s.Assert().Contains(logOutput.String(), "Starting hashing")
+			s.Assert().Contains(logOutput.String(), "Hashing finished")
+			mockHasher.AssertExpectations(t)
+		})
+
+		t.WithNewStep("error path", func(s provider.StepCtx) {
+			logOutput := setupLoggingTest()
+			origOut := logger.GetInstance().Out
+			defer logger.GetInstance().SetOutput(origOut)
+
+			mockHasher := new(MockHasher)
+			input := []byte("data")
+			mockHasher.On("Hash", mock.Anything, input).Return([]byte(nil), errors.New("hash error"))
+
+			_, err := NewLoggingHasher(mockHasher).Hash(context.Background(), input)
+
+			s.Assert().Error(err)
+			s.Assert().Contains(logOutput.String(), "Hashing failed")
+			mockHasher.AssertExpectations(t)
+		})
+	})
+}
@@ -0,0 +1,52 @@
+package integrity
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMismatch is returned when data does not match its expected digest.
+var ErrMismatch = errors.New("checksum mismatch")
+
+// Sum is one line of a checksum file.
+type Sum struct {
+	Digest []byte
+	Name   string
+}
+
+// FormatSum renders a checksum line in the format written by sha256sum and
+// friends, "<hex digest>  <name>", which their -c option reads back.
+func FormatSum(digest []byte, name string) string {
+	return hex.EncodeToString(digest) + "  " + name + "\n"
+}
+
+// ParseSums reads checksum lines as written by FormatSum or sha256sum, in
+// text ("<hex>  <name>") or binary ("<hex> *<name>") mode. Blank lines and
+// lines starting with # are skipped.
+func ParseSums(data []byte) ([]Sum, error) {
+	var sums []Sum
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		hexDigest, name, ok := strings.Cut(text, " ")
+		if !ok || len(name) < 2 || (name[0] != ' ' && name[0] != '*') {
+			return nil, fmt.Errorf("line %d: expected \"<digest>  <name>\"", line)
+		}
+		digest, err := hex.DecodeString(hexDigest)
+		if err != nil || len(digest) == 0 {
+			return nil, fmt.Errorf("line %d: invalid digest %q", line, hexDigest)
+		}
+		sums = append(sums, Sum{Digest: digest, Name: name[1:]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
@@ -0,0 +1,161 @@
+// Package keyfile reads and writes PEM-encoded asymmetric keys.
+package keyfile
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	// ErrPassphraseRequired is returned for encrypted private keys read
+	// without a passphrase.
+	ErrPassphraseRequired = errors.New("private key is encrypted; a passphrase is required")
+	// ErrWrongPassphrase is returned when a passphrase does not unlock a key.
+	ErrWrongPassphrase = errors.New("wrong passphrase for private key")
+)
+
+// ParsePrivateKey decodes an unencrypted PKCS#8, PKCS#1 ("RSA PRIVATE KEY")
+// or SEC 1 ("EC PRIVATE KEY") PEM private key.
+func ParsePrivateKey(data []byte) (crypto.PrivateKey, error) {
+	return ParsePrivateKeyWithPassphrase(data, nil)
+}
+
+// ParsePrivateKeyWithPassphrase decodes a PEM private key like
+// ParsePrivateKey, and also encrypted PKCS#8 keys ("ENCRYPTED PRIVATE KEY")
+// and legacy encrypted PEM keys ("Proc-Type: 4,ENCRYPTED"), which passphrase
+// unlocks. A trailing line ending on passphrase is ignored.
+func ParsePrivateKeyWithPassphrase(data, passphrase []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to parse PEM block containing the private key")
+	}
+	passphrase = bytes.TrimRight(passphrase, "\r\n")
+
+	der := block.Bytes
+	encrypted := false
+	switch {
+	case block.Type == encryptedKeyType:
+		if len(passphrase) == 0 {
+			return nil, ErrPassphraseRequired
+		}
+		decrypted, err := decryptPKCS8(der, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		der, encrypted = decrypted, true
+	case x509.IsEncryptedPEMBlock(block):
+		// Legacy PEM encryption is deprecated as insecure, but keys written
+		// by "openssl genrsa -aes256" and older tools still use it.
+		if len(passphrase) == 0 {
+			return nil, ErrPassphraseRequired
+		}
+		decrypted, err := x509.DecryptPEMBlock(block, passphrase)
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return nil, ErrWrongPassphrase
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+		}
+		der, encrypted = decrypted, true
+	}
+
+	var key crypto.PrivateKey
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(der)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(der)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(der)
+	}
+	if err != nil && encrypted {
+		// CBC padding can come out valid by chance under a wrong passphrase.
+		return nil, ErrWrongPassphrase
+	}
+	return key, err
+}
+
+// ParsePublicKey decodes a PKIX PEM public key.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to parse PEM block containing the public key")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// PrivateKey is implemented by the private keys of the standard library,
+// including crypto.Signer keys and *ecdh.PrivateKey.
+type PrivateKey interface {
+	Public() crypto.PublicKey
+}
+
+// MarshalPrivateKey encodes key as a PKCS#8 PEM block, encrypted with
+// passphrase ("ENCRYPTED PRIVATE KEY") unless passphrase is empty.
+func MarshalPrivateKey(key PrivateKey, passphrase []byte) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	passphrase = bytes.TrimRight(passphrase, "\r\n")
+	if len(passphrase) == 0 {
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+	}
+	encrypted, err := encryptPKCS8(der, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt private key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: encryptedKeyType, Bytes: encrypted}), nil
+}
+
+// WriteKeyPair saves key as path.priv (PKCS#8, readable only by the owner)
+// and its public half as path.pub (PKIX).
+func WriteKeyPair(path string, key PrivateKey) error {
+	return WriteEncryptedKeyPair(path, key, nil)
+}
+
+// WriteEncryptedKeyPair is WriteKeyPair with the private key encrypted with
+// passphrase, if it is not empty.
+func WriteEncryptedKeyPair(path string, key PrivateKey, passphrase []byte) error {
+	privPEM, err := MarshalPrivateKey(key, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := writePrivate(path+".priv", privPEM); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+
+	pubBytes, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes})
+	if err := os.WriteFile(path+".pub", pubPEM, 0644); err != nil {
+		return fmt.Errorf("failed to write public key: %w", err)
+	}
+	return nil
+}
+
+// writePrivate writes data to a file readable only by the owner. An existing
+// file is removed first rather than overwritten, so it cannot keep a looser
+// mode.
+func writePrivate(path string, data []byte) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,179 @@
+package keyfile
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// Encrypted PKCS#8 keys (RFC 5958) use PBES2 (RFC 8018) with PBKDF2 and
+// AES-CBC, the scheme openssl writes by default.
+const (
+	encryptedKeyType = "ENCRYPTED PRIVATE KEY"
+	// kdfIterations follows the OWASP recommendation for PBKDF2-HMAC-SHA256.
+	kdfIterations = 600_000
+	// maxIterations bounds the work a crafted key file can ask for.
+	maxIterations = 10_000_000
+	saltSize      = 16
+)
+
+var (
+	oidPBES2      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// encryptPKCS8 encrypts a DER PKCS#8 private key with passphrase, using
+// PBKDF2-HMAC-SHA256 and AES-256-CBC.
+func encryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	key, err := pbkdf2.Key(sha256.New, string(passphrase), salt, kdfIterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	padding := aes.BlockSize - len(der)%aes.BlockSize
+	plain := append(bytes.Clone(der), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	encrypted := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, plain)
+
+	kdf, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: kdfIterations,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ivParam, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdf}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParam}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm:     pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}},
+		EncryptedData: encrypted,
+	})
+}
+
+// decryptPKCS8 returns the DER PKCS#8 private key inside an encrypted one.
+func decryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted private key: %w", err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported private key encryption %s; only PBES2 is supported", info.Algorithm.Algorithm)
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("failed to parse PBES2 parameters: %w", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported key derivation %s; only PBKDF2 is supported", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, fmt.Errorf("failed to parse PBKDF2 parameters: %w", err)
+	}
+	if kdf.IterationCount < 1 || kdf.IterationCount > maxIterations {
+		return nil, fmt.Errorf("unsupported PBKDF2 iteration count %d", kdf.IterationCount)
+	}
+
+	var prf func() hash.Hash
+	switch alg := kdf.PRF.Algorithm; {
+	case len(alg) == 0, alg.Equal(oidHMACSHA1):
+		prf = sha1.New
+	case alg.Equal(oidHMACSHA256):
+		prf = sha256.New
+	case alg.Equal(oidHMACSHA512):
+		prf = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported PBKDF2 hash %s", alg)
+	}
+
+	var keySize int
+	switch alg := params.EncryptionScheme.Algorithm; {
+	case alg.Equal(oidAES128CBC):
+		keySize = 16
+	case alg.Equal(oidAES192CBC):
+		keySize = 24
+	case alg.Equal(oidAES256CBC):
+		keySize = 32
+	default:
+		return nil, fmt.Errorf("unsupported private key cipher %s; only AES-CBC is supported", alg)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil || len(iv) != aes.BlockSize {
+		return nil, errors.New("invalid AES-CBC parameters in encrypted private key")
+	}
+	if len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, errors.New("encrypted private key is corrupted")
+	}
+
+	key, err := pbkdf2.Key(prf, string(passphrase), kdf.Salt, kdf.IterationCount, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, info.EncryptedData)
+
+	// A wrong passphrase almost always shows up as bad padding.
+	padding := int(plain[len(plain)-1])
+	if padding < 1 || padding > aes.BlockSize || !bytes.Equal(plain[len(plain)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, ErrWrongPassphrase
+	}
+	return plain[:len(plain)-padding], nil
+}
@@ -0,0 +1,242 @@
+// Package keyring stores named encryption and signing keys in a directory,
+// by default $XDG_CONFIG_HOME/file-processing/keys, so pipelines can refer
+// to keys by name instead of by file.
+package keyring
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dzibukalexander/file-processing/internal/encryption/aes"
+	"github.com/dzibukalexander/file-processing/internal/secmem"
+)
+
+const extension = ".json"
+
+// validName keeps key names usable as file names on every platform.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+var (
+	// ErrNotFound is returned for names that are not in the keyring.
+	ErrNotFound = errors.New("key not found in keyring")
+	// ErrExists is returned when adding a key under a name that is taken.
+	ErrExists = errors.New("key already exists in keyring")
+)
+
+// Key is a named key. Symmetric keys only have Secret; key pairs have the
+// private key in Secret and the public key in Public, and keys imported
+// from someone else may only have Public.
+type Key struct {
+	Name        string    `json:"name"`
+	Algorithm   string    `json:"algorithm"`
+	Created     time.Time `json:"created"`
+	Fingerprint string    `json:"fingerprint"`
+	Secret      []byte    `json:"secret,omitempty"`
+	Public      []byte    `json:"public,omitempty"`
+}
+
+// Kind describes which parts of the key are held: "secret", "pair" or
+// "public".
+func (k *Key) Kind() string {
+	switch {
+	case k.Public == nil:
+		return "secret"
+	case k.Secret == nil:
+		return "public"
+	default:
+		return "pair"
+	}
+}
+
+// Keyring is a directory of keys addressed by name.
+type Keyring struct {
+	dir string
+}
+
+// New returns a keyring stored in dir. The directory is created on first add.
+func New(dir string) *Keyring {
+	return &Keyring{dir: dir}
+}
+
+// DefaultDir returns the user's keyring directory, which honours
+// $XDG_CONFIG_HOME on Unix systems.
+func DefaultDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "file-processing", "keys"), nil
+}
+
+// Default returns the keyring in DefaultDir.
+func Default() (*Keyring, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return New(dir), nil
+}
+
+// Dir returns the directory holding the keyring.
+func (r *Keyring) Dir() string {
+	return r.dir
+}
+
+// Get returns the key called name.
+func (r *Keyring) Get(name string) (*Key, error) {
+	path, err := r.path(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The file holds the secret in base64; wipe it once decoded.
+	defer secmem.Wipe(data)
+	var key Key
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", name, err)
+	}
+	return &key, nil
+}
+
+// Add stores key, filling in its creation time and fingerprint if unset.
+// Keys are written readable only by the owner.
+func (r *Keyring) Add(key *Key) error {
+	path, err := r.path(key.Name)
+	if err != nil {
+		return err
+	}
+	if key.Secret == nil && key.Public == nil {
+		return fmt.Errorf("key %s has no key material", key.Name)
+	}
+	if key.Created.IsZero() {
+		key.Created = time.Now().UTC().Truncate(time.Second)
+	}
+	if key.Fingerprint == "" {
+		key.Fingerprint = Fingerprint(key.Algorithm, key.Secret, key.Public)
+	}
+	data, err := json.MarshalIndent(key, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(r.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create keyring directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%w: %s", ErrExists, key.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Delete removes the key called name.
+func (r *Keyring) Delete(name string) error {
+	path, err := r.path(name)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return err
+}
+
+// List returns every key sorted by name. A missing keyring directory is
+// treated as empty.
+func (r *Keyring) List() ([]*Key, error) {
+	files, err := os.ReadDir(r.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*Key
+	for _, file := range files {
+		name, ok := strings.CutSuffix(file.Name(), extension)
+		if file.IsDir() || !ok {
+			continue
+		}
+		key, err := r.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Name < keys[j].Name
+	})
+	return keys, nil
+}
+
+// LoadKey returns the algorithm of the key called name and either its public
+// key (for encrypting and verifying) or its secret. Symmetric keys return
+// the secret either way. The returned slice is read fresh from disk and
+// belongs to the caller, who may wipe it.
+func (r *Keyring) LoadKey(name string, public bool) (string, []byte, error) {
+	key, err := r.Get(name)
+	if err != nil {
+		return "", nil, err
+	}
+	switch {
+	case public && key.Public != nil:
+		secmem.Wipe(key.Secret)
+		return key.Algorithm, key.Public, nil
+	case key.Secret != nil:
+		return key.Algorithm, key.Secret, nil
+	default:
+		return "", nil, fmt.Errorf("key %s only holds a public key", name)
+	}
+}
+
+func (r *Keyring) path(name string) (string, error) {
+	if !validName.MatchString(name) {
+		return "", fmt.Errorf("invalid key name %q: use letters, digits, '.', '-' and '_'", name)
+	}
+	return filepath.Join(r.dir, name+extension), nil
+}
+
+// Fingerprint identifies a key without revealing it. AES keys use the key
+// ID recorded in AES ciphertext headers. Other keys use the first 8 bytes of
+// the SHA-256 of the public key or, if there is none, of the secret prefixed
+// with the algorithm, so the ID of a secret is never a plain hash of it.
+func Fingerprint(algorithm string, secret, public []byte) string {
+	switch algorithm {
+	case "aes", "aes-gcm-stream":
+		if secret != nil {
+			return hex.EncodeToString(aes.Fingerprint(secret))
+		}
+	}
+	if public != nil {
+		sum := sha256.Sum256(public)
+		return hex.EncodeToString(sum[:8])
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "file-processing %s key id\x00", algorithm)
+	h.Write(secret)
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
@@ -0,0 +1,154 @@
+package keyring
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+)
+
+func TestKeyring(t *testing.T) {
+	runner.Run(t, "Named keyring", func(t provider.T) {
+		t.WithNewStep("default dir follows XDG_CONFIG_HOME", func(s provider.StepCtx) {
+			t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg-test")
+			dir, err := DefaultDir()
+			s.Require().NoError(err)
+			s.Assert().Equal(filepath.Join("/tmp/xdg-test", "file-processing", "keys"), dir)
+		})
+
+		t.WithNewStep("add, get, list and delete", func(s provider.StepCtx) {
+			kr := New(filepath.Join(t.TempDir(), "keys"))
+			keys, err := kr.List()
+			s.Require().NoError(err)
+			s.Assert().Empty(keys)
+
+			secret := make([]byte, 32)
+			s.Require().NoError(kr.Add(&Key{Name: "backup", Algorithm: "aes", Secret: secret}))
+			s.Require().NoError(kr.Add(&Key{Name: "alice", Algorithm: "ed25519", Secret: []byte("priv"), Public: []byte("pub")}))
+			s.Require().NoError(kr.Add(&Key{Name: "bob", Algorithm: "rsa", Public: []byte("pub")}))
+
+			info, err := os.Stat(filepath.Join(kr.Dir(), "backup.json"))
+			s.Require().NoError(err)
+			s.Assert().Equal(os.FileMode(0600), info.Mode().Perm())
+			info, err = os.Stat(kr.Dir())
+			s.Require().NoError(err)
+			s.Assert().Equal(os.FileMode(0700), info.Mode().Perm())
+
+			err = kr.Add(&Key{Name: "backup", Algorithm: "aes", Secret: secret})
+			s.Assert().True(errors.Is(err, ErrExists))
+			s.Assert().Error(kr.Add(&Key{Name: "../escape", Algorithm: "aes", Secret: secret}))
+			s.Assert().Error(kr.Add(&Key{Name: "empty", Algorithm: "aes"}))
+
+			keys, err = kr.List()
+			s.Require().NoError(err)
+			s.Require().Len(keys, 3)
+			s.Assert().Equal([]string{"alice", "backup", "bob"}, []string{keys[0].Name, keys[1].Name, keys[2].Name})
+			s.Assert().Equal([]string{"pair", "secret", "public"}, []string{keys[0].Kind(), keys[1].Kind(), keys[2].Kind()})
+			s.Assert().False(keys[1].Created.IsZero())
+			s.Assert().Equal(Fingerprint("aes", secret, nil), keys[1].Fingerprint)
+			s.Assert().Len(keys[1].Fingerprint, 16)
+			plain := sha256.Sum256(secret)
+			s.Assert().NotEqual(hex.EncodeToString(plain[:8]), Fingerprint("chacha20", secret, nil), "secrets are not fingerprinted with a plain hash")
+			s.Assert().NotEqual(Fingerprint("chacha20", secret, nil), Fingerprint("aes-siv", secret, nil))
+
+			algorithm, key, err := kr.LoadKey("alice", true)
+			s.Require().NoError(err)
+			s.Assert().Equal("ed25519", algorithm)
+			s.Assert().Equal("pub", string(key))
+			_, key, err = kr.LoadKey("alice", false)
+			s.Require().NoError(err)
+			s.Assert().Equal("priv", string(key))
+			_, key, err = kr.LoadKey("backup", true)
+			s.Require().NoError(err)
+			s.Assert().Equal(secret, key, "symmetric keys are their own public half")
+			clear(key)
+			_, key, err = kr.LoadKey("backup", false)
+			s.Require().NoError(err)
+			s.Assert().Equal(secret, key, "every load returns a copy the caller may wipe")
+			_, _, err = kr.LoadKey("bob", false)
+			s.Assert().Error(err, "a public key cannot decrypt or sign")
+
+			s.Require().NoError(kr.Delete("bob"))
+			_, err = kr.Get("bob")
+			s.Assert().True(errors.Is(err, ErrNotFound))
+			s.Assert().True(errors.Is(kr.Delete("bob"), ErrNotFound))
+		})
+
+		t.WithNewStep("shares carry checksums, commitments and fingerprints", func(s provider.StepCtx) {
+			key := &Key{Name: "escrow", Algorithm: "ed25519", Secret: []byte("private key"), Public: []byte("public key")}
+			shares, err := Split(key, 4, 2)
+			s.Require().NoError(err)
+			s.Require().Len(shares, 4)
+
+			var parsed []*Share
+			for _, share := range shares {
+				text, err := share.MarshalText()
+				s.Require().NoError(err)
+				s.Assert().Contains(string(text), "Fingerprint: "+Fingerprint("ed25519", key.Secret, key.Public))
+				p, err := ParseShare(text)
+				s.Require().NoError(err)
+				parsed = append(parsed, p)
+			}
+			rebuilt, err := Combine([]*Share{parsed[3], parsed[1]})
+			s.Require().NoError(err)
+			s.Assert().Equal(key.Secret, rebuilt.Secret)
+			s.Assert().Equal(key.Public, rebuilt.Public)
+			s.Assert().Equal("escrow", rebuilt.Name)
+
+			_, err = Combine(parsed[:1])
+			s.Assert().Error(err, "fewer shares than the threshold")
+
+			text, err := shares[0].MarshalText()
+			s.Require().NoError(err)
+			_, err = ParseShare(bytes.Replace(text, []byte("Index: 1"), []byte("Index: 3"), 1))
+			s.Assert().True(errors.Is(err, ErrShareChecksum))
+			_, err = ParseShare([]byte("Name: nothing else"))
+			s.Assert().Error(err)
+
+			other, err := Split(key, 4, 2)
+			s.Require().NoError(err)
+			_, err = Combine([]*Share{shares[0], other[1]})
+			s.Assert().Error(err, "shares of different splits do not mix")
+
+			forged := *shares[1]
+			forged.Value = bytes.Clone(forged.Value)
+			forged.Value[0] ^= 1
+			_, err = Combine([]*Share{shares[0], &forged})
+			s.Require().Error(err)
+			s.Assert().Contains(err.Error(), "commitment")
+		})
+
+		t.WithNewStep("shares of symmetric keys do not reveal a hash of the secret", func(s provider.StepCtx) {
+			secret := bytes.Repeat([]byte{7}, 32)
+			key := &Key{Name: "vault", Algorithm: "aes", Secret: secret}
+			key.Fingerprint = Fingerprint(key.Algorithm, key.Secret, nil)
+			shares, err := Split(key, 3, 2)
+			s.Require().NoError(err)
+
+			var parsed []*Share
+			for _, share := range shares {
+				text, err := share.MarshalText()
+				s.Require().NoError(err)
+				s.Assert().NotContains(string(text), key.Fingerprint)
+				s.Assert().NotContains(string(text), "Fingerprint:")
+				p, err := ParseShare(text)
+				s.Require().NoError(err)
+				parsed = append(parsed, p)
+			}
+			rebuilt, err := Combine(parsed[1:])
+			s.Require().NoError(err)
+			s.Assert().Equal(secret, rebuilt.Secret)
+			s.Assert().Equal(key.Fingerprint, rebuilt.Fingerprint)
+
+			again, err := Split(key, 3, 2)
+			s.Require().NoError(err)
+			s.Assert().NotEqual(shares[0].Commitment, again[0].Commitment, "commitments differ between splits")
+		})
+	})
+}
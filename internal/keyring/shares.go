@@ -0,0 +1,211 @@
+package keyring
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dzibukalexander/file-processing/internal/shamir"
+)
+
+const shareVersion = 1
+
+// ErrShareChecksum is returned for share files that were changed or damaged.
+var ErrShareChecksum = errors.New("share checksum mismatch")
+
+// Share is one Shamir share of a key's secret, for escrow. Every share of a
+// split records a random split ID, so shares of different splits are not
+// mixed, and a commitment to the secret keyed by that ID, so the rebuilt key
+// can be checked without the shares revealing a hash of the secret. Shares
+// of key pairs also record the public key's fingerprint.
+type Share struct {
+	Name        string
+	Algorithm   string
+	Fingerprint string // empty for symmetric keys, whose fingerprint derives from the secret
+	Commitment  string
+	SplitID     string
+	Threshold   int
+	Total       int
+	Index       int
+	Public      []byte
+	Value       []byte
+}
+
+// Split divides the secret of key into n shares, any k of which rebuild it.
+func Split(key *Key, n, k int) ([]*Share, error) {
+	if key.Secret == nil {
+		return nil, fmt.Errorf("key %s has no secret to split", key.Name)
+	}
+	parts, err := shamir.Split(key.Secret, n, k)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	splitID := hex.EncodeToString(id)
+	var fingerprint string
+	if key.Public != nil {
+		fingerprint = Fingerprint(key.Algorithm, nil, key.Public)
+	}
+
+	shares := make([]*Share, len(parts))
+	for i, part := range parts {
+		shares[i] = &Share{
+			Name:        key.Name,
+			Algorithm:   key.Algorithm,
+			Fingerprint: fingerprint,
+			Commitment:  commitment(splitID, key.Secret),
+			SplitID:     splitID,
+			Threshold:   k,
+			Total:       n,
+			Index:       int(part.X),
+			Public:      key.Public,
+			Value:       part.Y,
+		}
+	}
+	return shares, nil
+}
+
+// Combine rebuilds a key from shares of one split. It fails unless the
+// rebuilt key matches the commitment and fingerprint recorded in the shares.
+func Combine(shares []*Share) (*Key, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("no shares given")
+	}
+	first := shares[0]
+	parts := make([]shamir.Share, len(shares))
+	for i, share := range shares {
+		if share.SplitID != first.SplitID || share.Fingerprint != first.Fingerprint || share.Commitment != first.Commitment {
+			return nil, fmt.Errorf("share %d of key %s is from a different split than share %d of key %s", share.Index, share.Name, first.Index, first.Name)
+		}
+		parts[i] = shamir.Share{X: byte(share.Index), Y: share.Value}
+	}
+	if len(shares) < first.Threshold {
+		return nil, fmt.Errorf("key %s needs %d shares, only %d given", first.Name, first.Threshold, len(shares))
+	}
+
+	secret, err := shamir.Combine(parts)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(commitment(first.SplitID, secret)), []byte(first.Commitment)) {
+		return nil, errors.New("rebuilt key does not match the shares' commitment; a share is damaged or from another key")
+	}
+	key := &Key{Name: first.Name, Algorithm: first.Algorithm, Secret: secret, Public: first.Public}
+	key.Fingerprint = Fingerprint(key.Algorithm, key.Secret, key.Public)
+	if first.Fingerprint != "" && key.Fingerprint != first.Fingerprint {
+		return nil, fmt.Errorf("rebuilt key does not match fingerprint %s; a share is damaged or from another key", first.Fingerprint)
+	}
+	return key, nil
+}
+
+// commitment binds a split to its secret. It is an HMAC keyed by the random
+// split ID, so unlike a plain hash it cannot be looked up or matched across
+// splits of the same key.
+func commitment(splitID string, secret []byte) string {
+	mac := hmac.New(sha256.New, []byte("file-processing key share\x00"+splitID))
+	mac.Write(secret)
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// checksum covers every field of the share, so a damaged or edited share
+// file is caught before it is combined.
+func (s *Share) checksum() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%s\x00%s\x00%s\x00%s\x00%s\x00%d\x00%d\x00%d\x00", shareVersion, s.Name, s.Algorithm, s.Fingerprint, s.Commitment, s.SplitID, s.Threshold, s.Total, s.Index)
+	h.Write(s.Public)
+	h.Write([]byte{0})
+	h.Write(s.Value)
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// MarshalText encodes the share as the text of a share file.
+func (s *Share) MarshalText() ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# file-processing key share %d of %d for key %q.\n", s.Index, s.Total, s.Name)
+	fmt.Fprintf(&b, "# Any %d shares rebuild the key with: keys combine <share files...>\n", s.Threshold)
+	fmt.Fprintf(&b, "Version: %d\n", shareVersion)
+	fmt.Fprintf(&b, "Name: %s\n", s.Name)
+	fmt.Fprintf(&b, "Algorithm: %s\n", s.Algorithm)
+	if s.Fingerprint != "" {
+		fmt.Fprintf(&b, "Fingerprint: %s\n", s.Fingerprint)
+	}
+	fmt.Fprintf(&b, "Commitment: %s\n", s.Commitment)
+	fmt.Fprintf(&b, "Split-ID: %s\n", s.SplitID)
+	fmt.Fprintf(&b, "Threshold: %d\n", s.Threshold)
+	fmt.Fprintf(&b, "Shares: %d\n", s.Total)
+	fmt.Fprintf(&b, "Index: %d\n", s.Index)
+	if s.Public != nil {
+		fmt.Fprintf(&b, "Public: %s\n", base64.StdEncoding.EncodeToString(s.Public))
+	}
+	fmt.Fprintf(&b, "Share: %s\n", base64.StdEncoding.EncodeToString(s.Value))
+	fmt.Fprintf(&b, "Checksum: %s\n", s.checksum())
+	return b.Bytes(), nil
+}
+
+// ParseShare decodes a share file and verifies its checksum.
+func ParseShare(data []byte) (*Share, error) {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid share line: %q", line)
+		}
+		fields[name] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"Version", "Name", "Algorithm", "Commitment", "Split-ID", "Threshold", "Shares", "Index", "Share", "Checksum"} {
+		if _, ok := fields[name]; !ok {
+			return nil, fmt.Errorf("not a key share: missing %s", name)
+		}
+	}
+	if fields["Version"] != strconv.Itoa(shareVersion) {
+		return nil, fmt.Errorf("unsupported share version %s", fields["Version"])
+	}
+
+	s := &Share{
+		Name:        fields["Name"],
+		Algorithm:   fields["Algorithm"],
+		Fingerprint: fields["Fingerprint"],
+		Commitment:  fields["Commitment"],
+		SplitID:     fields["Split-ID"],
+	}
+	var err error
+	for name, dst := range map[string]*int{"Threshold": &s.Threshold, "Shares": &s.Total, "Index": &s.Index} {
+		if *dst, err = strconv.Atoi(fields[name]); err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", name, fields[name])
+		}
+	}
+	if s.Index < 1 || s.Index > shamir.MaxShares {
+		return nil, fmt.Errorf("invalid share index %d", s.Index)
+	}
+	if public, ok := fields["Public"]; ok {
+		if s.Public, err = base64.StdEncoding.DecodeString(public); err != nil {
+			return nil, fmt.Errorf("invalid public key: %w", err)
+		}
+	}
+	if s.Value, err = base64.StdEncoding.DecodeString(fields["Share"]); err != nil {
+		return nil, fmt.Errorf("invalid share: %w", err)
+	}
+	if s.checksum() != fields["Checksum"] {
+		return nil, fmt.Errorf("%w in share %d of key %s", ErrShareChecksum, s.Index, s.Name)
+	}
+	return s, nil
+}
@@ -1,17 +1,32 @@
 package logger
 
 import (
-	"io/ioutil"
+	"fmt"
+	"io"
 	"os"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/dzibukalexander/file-processing/internal/config"
 	"github.com/sirupsen/logrus"
 )
 
+// PackageField is the field For adds to entries, naming the package that
+// logged them.
+const PackageField = "package"
+
+// Packages lists the names accepted by For and by the per-package levels of
+// the logging config.
+var Packages = []string{"calculation", "compression", "core", "encryption", "fileio", "fileprocessing", "integrity", "signature"}
+
 var (
 	log  *logrus.Logger
 	once sync.Once
+
+	// file is the log file opened by SetupLogger, closed when it is
+	// reconfigured.
+	file io.Closer
 )
 
 // GetInstance returns the singleton logger instance.
@@ -19,22 +34,130 @@ func GetInstance() *logrus.Logger {
 	once.Do(func() {
 		log = logrus.New()
 		// Default to discarding logs until config is loaded.
-		log.SetOutput(ioutil.Discard)
+		log.SetOutput(io.Discard)
 	})
 	return log
 }
 
+// For returns the logger for the named package, one of Packages. Its entries
+// carry the package name and follow the package's level in the config.
+func For(pkg string) *logrus.Entry {
+	return GetInstance().WithField(PackageField, pkg)
+}
+
 // SetupLogger configures the logger based on the application config.
 // This should be called after loading the config.
-func SetupLogger() {
+func SetupLogger() error {
 	logger := GetInstance()
-	if config.AppConfig != nil && config.AppConfig.EnableLogging {
+	if file != nil {
+		file.Close()
+		file = nil
+	}
+	if config.AppConfig == nil || !config.AppConfig.EnableLogging {
+		logger.SetOutput(io.Discard)
+		return nil
+	}
+	cfg := config.AppConfig.Logging
+
+	filter, err := newLevelFilter(cfg)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		filter.Formatter = &logrus.TextFormatter{FullTimestamp: true}
+	case "json":
+		filter.Formatter = &logrus.JSONFormatter{}
+	case "logfmt":
+		filter.Formatter = &logrus.TextFormatter{FullTimestamp: true, DisableColors: true, QuoteEmptyFields: true}
+	default:
+		return fmt.Errorf("unknown log format: %s (want text, json or logfmt)", cfg.Format)
+	}
+
+	switch strings.ToLower(cfg.Output) {
+	case "", "stdout":
 		logger.SetOutput(os.Stdout)
-		logger.SetLevel(logrus.InfoLevel)
-		logger.SetFormatter(&logrus.TextFormatter{
-			FullTimestamp: true,
-		})
-	} else {
-		logger.SetOutput(ioutil.Discard)
+	case "stderr":
+		logger.SetOutput(os.Stderr)
+	default:
+		if cfg.MaxSizeMB < 0 || cfg.MaxBackups < 0 {
+			return fmt.Errorf("log rotation sizes must not be negative")
+		}
+		w, err := openRotatingFile(cfg.Output, int64(cfg.MaxSizeMB)<<20, cfg.MaxBackups)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		file = w
+		logger.SetOutput(w)
+	}
+	logger.SetFormatter(filter)
+	logger.SetLevel(filter.mostVerbose())
+	return nil
+}
+
+// levelFilter drops entries below the level of the package that logged them,
+// so one package can log at debug while the rest stay at info.
+type levelFilter struct {
+	logrus.Formatter
+	level    logrus.Level
+	packages map[string]logrus.Level
+}
+
+func newLevelFilter(cfg config.LoggingConfig) (*levelFilter, error) {
+	f := &levelFilter{level: logrus.InfoLevel, packages: map[string]logrus.Level{}}
+	if cfg.Level != "" {
+		level, err := parseLevel(cfg.Level)
+		if err != nil {
+			return nil, err
+		}
+		f.level = level
+	}
+	for pkg, name := range cfg.Packages {
+		if !slices.Contains(Packages, pkg) {
+			return nil, fmt.Errorf("unknown package %q in log levels (known: %s)", pkg, strings.Join(Packages, ", "))
+		}
+		level, err := parseLevel(name)
+		if err != nil {
+			return nil, fmt.Errorf("package %s: %w", pkg, err)
+		}
+		f.packages[pkg] = level
+	}
+	return f, nil
+}
+
+func parseLevel(name string) (logrus.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return logrus.DebugLevel, nil
+	case "info":
+		return logrus.InfoLevel, nil
+	case "warn", "warning":
+		return logrus.WarnLevel, nil
+	case "error":
+		return logrus.ErrorLevel, nil
+	}
+	return 0, fmt.Errorf("unknown log level: %s (want debug, info, warn or error)", name)
+}
+
+// mostVerbose returns the lowest level any package logs at, which the logger
+// itself must allow.
+func (f *levelFilter) mostVerbose() logrus.Level {
+	level := f.level
+	for _, l := range f.packages {
+		level = max(level, l)
+	}
+	return level
+}
+
+func (f *levelFilter) Format(entry *logrus.Entry) ([]byte, error) {
+	level := f.level
+	if pkg, ok := entry.Data[PackageField].(string); ok {
+		if l, ok := f.packages[pkg]; ok {
+			level = l
+		}
+	}
+	if entry.Level > level {
+		return nil, nil
 	}
+	return f.Formatter.Format(entry)
 }
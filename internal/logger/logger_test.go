@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dzibukalexander/file-processing/internal/config"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+)
+
+func setup(cfg config.LoggingConfig) (*bytes.Buffer, error) {
+	config.AppConfig = &config.Config{EnableLogging: true, Logging: cfg}
+	if err := SetupLogger(); err != nil {
+		return nil, err
+	}
+	out := new(bytes.Buffer)
+	GetInstance().SetOutput(out)
+	return out, nil
+}
+
+func TestSetupLogger(t *testing.T) {
+	runner.Run(t, "Logging config", func(t provider.T) {
+		defer func() {
+			config.AppConfig = nil
+			SetupLogger()
+		}()
+
+		t.WithNewStep("per-package levels", func(s provider.StepCtx) {
+			out, err := setup(config.LoggingConfig{Level: "warn", Packages: map[string]string{"compression": "debug"}})
+			s.Require().NoError(err)
+			For("compression").Debug("compression detail")
+			For("encryption").Info("encryption progress")
+			For("encryption").Warn("encryption warning")
+			GetInstance().Info("application progress")
+
+			s.Assert().Contains(out.String(), "compression detail")
+			s.Assert().Contains(out.String(), "package=compression")
+			s.Assert().Contains(out.String(), "encryption warning")
+			s.Assert().NotContains(out.String(), "encryption progress")
+			s.Assert().NotContains(out.String(), "application progress")
+		})
+
+		t.WithNewStep("json format", func(s provider.StepCtx) {
+			out, err := setup(config.LoggingConfig{Format: "json"})
+			s.Require().NoError(err)
+			For("core").WithField("size", 3).Info("loaded")
+
+			var entry map[string]interface{}
+			s.Require().NoError(json.Unmarshal(out.Bytes(), &entry))
+			s.Assert().Equal("loaded", entry["msg"])
+			s.Assert().Equal("core", entry["package"])
+			s.Assert().Equal(float64(3), entry["size"])
+		})
+
+		t.WithNewStep("logfmt format", func(s provider.StepCtx) {
+			out, err := setup(config.LoggingConfig{Format: "logfmt"})
+			s.Require().NoError(err)
+			For("fileio").WithField("path", "").Info("read")
+			s.Assert().Contains(out.String(), `level=info msg=read package=fileio path=""`)
+		})
+
+		t.WithNewStep("invalid config", func(s provider.StepCtx) {
+			for _, cfg := range []config.LoggingConfig{
+				{Level: "verbose"},
+				{Format: "xml"},
+				{Packages: map[string]string{"compresion": "debug"}},
+				{Packages: map[string]string{"compression": "loud"}},
+				{Output: filepath.Join(t.TempDir(), "missing", "app.log")},
+			} {
+				_, err := setup(cfg)
+				s.Assert().Error(err, "%+v", cfg)
+			}
+		})
+
+		t.WithNewStep("disabled logging", func(s provider.StepCtx) {
+			config.AppConfig = &config.Config{Logging: config.LoggingConfig{Level: "debug"}}
+			s.Require().NoError(SetupLogger())
+			s.Assert().Equal(io.Discard, GetInstance().Out)
+		})
+	})
+}
+
+func TestRotatingFile(t *testing.T) {
+	runner.Run(t, "Log file rotation", func(t provider.T) {
+		t.WithNewStep("rotates by size and keeps backups", func(s provider.StepCtx) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			w, err := openRotatingFile(path, 10, 2)
+			s.Require().NoError(err)
+			defer w.Close()
+
+			for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+				_, err := w.Write([]byte(line))
+				s.Require().NoError(err)
+			}
+			read := func(name string) string {
+				data, err := os.ReadFile(name)
+				s.Require().NoError(err)
+				return string(data)
+			}
+			s.Assert().Equal("fourth\n", read(path))
+			s.Assert().Equal("third\n", read(path+".1"))
+			s.Assert().Equal("second\n", read(path+".2"))
+			_, err = os.Stat(path + ".3")
+			s.Assert().True(os.IsNotExist(err), "only max_backups files are kept")
+
+			info, err := os.Stat(path)
+			s.Require().NoError(err)
+			s.Assert().Equal(os.FileMode(0600), info.Mode().Perm())
+		})
+
+		t.WithNewStep("appends to an existing file", func(s provider.StepCtx) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			s.Require().NoError(os.WriteFile(path, []byte("old\n"), 0600))
+			w, err := openRotatingFile(path, 0, 0)
+			s.Require().NoError(err)
+			_, err = w.Write([]byte(strings.Repeat("x", 100) + "\n"))
+			s.Require().NoError(err)
+			s.Require().NoError(w.Close())
+
+			data, err := os.ReadFile(path)
+			s.Require().NoError(err)
+			s.Assert().True(strings.HasPrefix(string(data), "old\nxxx"))
+		})
+
+		t.WithNewStep("setup writes to the configured file", func(s provider.StepCtx) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			config.AppConfig = &config.Config{EnableLogging: true, Logging: config.LoggingConfig{Output: path, MaxSizeMB: 1}}
+			s.Require().NoError(SetupLogger())
+			defer func() {
+				config.AppConfig = nil
+				SetupLogger()
+			}()
+			For("core").Info("to file")
+
+			data, err := os.ReadFile(path)
+			s.Require().NoError(err)
+			s.Assert().Contains(string(data), "to file")
+		})
+	})
+}
@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// rotatingFile appends to a log file, moving it aside to <path>.1 once it
+// would grow past maxSize. Older files shift up to <path>.<maxBackups> and
+// the oldest is removed.
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64 // 0 never rotates
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func openRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	w := &rotatingFile{path: path, maxSize: maxSize, maxBackups: max(maxBackups, 1)}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *rotatingFile) open() error {
+	// Logs can name files and keys, so keep them private.
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.size = f, info.Size()
+	return nil
+}
+
+func (w *rotatingFile) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var rotateErr error
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if rotateErr = w.rotate(); rotateErr != nil {
+			if w.file == nil {
+				return 0, fmt.Errorf("failed to rotate log file: %w", rotateErr)
+			}
+			// Keep logging to the current file.
+			rotateErr = fmt.Errorf("failed to rotate log file: %w", rotateErr)
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, errors.Join(rotateErr, err)
+}
+
+// rotate moves the log file aside and reopens it. If the files cannot be
+// moved, the current file is reopened for appending.
+func (w *rotatingFile) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+	shiftErr := w.shift()
+	if err := w.open(); err != nil {
+		return errors.Join(shiftErr, err)
+	}
+	return shiftErr
+}
+
+func (w *rotatingFile) shift() error {
+	backup := func(i int) string { return fmt.Sprintf("%s.%d", w.path, i) }
+	if err := os.Remove(backup(w.maxBackups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := w.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backup(i), backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(w.path, backup(1))
+}
+
+func (w *rotatingFile) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
@@ -0,0 +1,20 @@
+package secmem
+
+import "unsafe"
+
+// alignOffset returns the index of the first page boundary in raw.
+func alignOffset(raw []byte) int {
+	addr := uintptr(unsafe.Pointer(unsafe.SliceData(raw)))
+	return int((uintptr(pageSize) - addr%uintptr(pageSize)) % uintptr(pageSize))
+}
+
+// Overlaps reports whether a and b share any memory, counting their capacity
+// as well as their length.
+func Overlaps(a, b []byte) bool {
+	if cap(a) == 0 || cap(b) == 0 {
+		return false
+	}
+	a0 := uintptr(unsafe.Pointer(unsafe.SliceData(a)))
+	b0 := uintptr(unsafe.Pointer(unsafe.SliceData(b)))
+	return a0 < b0+uintptr(cap(b)) && b0 < a0+uintptr(cap(a))
+}
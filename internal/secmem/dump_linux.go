@@ -0,0 +1,12 @@
+package secmem
+
+import "golang.org/x/sys/unix"
+
+func excludeFromDumps(region []byte) {
+	_ = unix.Madvise(region, unix.MADV_DONTDUMP)
+}
+
+// includeInDumps undoes excludeFromDumps before the memory is reused.
+func includeInDumps(region []byte) {
+	_ = unix.Madvise(region, unix.MADV_DODUMP)
+}
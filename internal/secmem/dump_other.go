@@ -0,0 +1,8 @@
+//go:build unix && !linux
+
+package secmem
+
+// Only Linux can leave single regions out of core dumps.
+func excludeFromDumps(region []byte) {}
+
+func includeInDumps(region []byte) {}
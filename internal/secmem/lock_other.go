@@ -0,0 +1,11 @@
+//go:build !unix
+
+package secmem
+
+import "errors"
+
+func lock(region []byte) error {
+	return errors.New("memory locking is not supported on this platform")
+}
+
+func unlock(region []byte) {}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package secmem
+
+import "golang.org/x/sys/unix"
+
+func lock(region []byte) error {
+	if err := unix.Mlock(region); err != nil {
+		return err
+	}
+	excludeFromDumps(region)
+	return nil
+}
+
+func unlock(region []byte) {
+	includeInDumps(region)
+	_ = unix.Munlock(region)
+}
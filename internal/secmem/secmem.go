@@ -0,0 +1,82 @@
+// Package secmem keeps keys and other sensitive data in buffers that are
+// wiped when released and, where the platform allows, locked into RAM
+// (mlock) and left out of core dumps.
+//
+// Buffers live on the Go heap, which never moves objects, so slices of a
+// buffer stay valid after Destroy; they only read zeros. Locking covers the
+// whole pages inside the buffer's own allocation, never a neighbour's.
+package secmem
+
+import (
+	"os"
+	"runtime"
+)
+
+var pageSize = os.Getpagesize()
+
+// Buffer holds sensitive bytes until Destroy wipes them.
+type Buffer struct {
+	data   []byte
+	region []byte // the page-aligned span of data that is locked
+	locked bool
+}
+
+// New returns a zeroed buffer of size bytes, locked into memory if possible.
+func New(size int) *Buffer {
+	if size == 0 {
+		return &Buffer{data: []byte{}}
+	}
+	// Over-allocate so that data starts on a page boundary and its pages
+	// belong to this allocation alone.
+	pages := (size + pageSize - 1) / pageSize
+	raw := make([]byte, (pages+1)*pageSize)
+	offset := alignOffset(raw)
+	b := &Buffer{
+		data:   raw[offset : offset+size : offset+size],
+		region: raw[offset : offset+pages*pageSize],
+	}
+	b.locked = lock(b.region) == nil
+	return b
+}
+
+// Copy returns a buffer holding a copy of data.
+func Copy(data []byte) *Buffer {
+	b := New(len(data))
+	copy(b.data, data)
+	return b
+}
+
+// Bytes returns the contents of the buffer.
+func (b *Buffer) Bytes() []byte {
+	if b == nil {
+		return nil
+	}
+	return b.data
+}
+
+// Locked reports whether the buffer is locked into memory and excluded from
+// core dumps. Locking fails when the platform lacks mlock or the process
+// exceeds its limit of locked memory (RLIMIT_MEMLOCK).
+func (b *Buffer) Locked() bool {
+	return b != nil && b.locked
+}
+
+// Destroy wipes the buffer and unlocks it. It is safe to call more than once.
+func (b *Buffer) Destroy() {
+	if b == nil || b.region == nil && len(b.data) == 0 {
+		return
+	}
+	Wipe(b.data)
+	if b.locked {
+		unlock(b.region)
+		b.locked = false
+	}
+	b.data, b.region = b.data[:0], nil
+}
+
+// Wipe overwrites data with zeros.
+func Wipe(data []byte) {
+	clear(data)
+	// Keep the writes from being optimised away as dead stores.
+	runtime.KeepAlive(data)
+}
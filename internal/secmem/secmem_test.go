@@ -0,0 +1,55 @@
+package secmem
+
+import (
+	"testing"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+)
+
+func TestBuffer(t *testing.T) {
+	runner.Run(t, "Secure memory buffers", func(t provider.T) {
+		t.WithNewStep("copy, lock and destroy", func(s provider.StepCtx) {
+			b := Copy([]byte("secret key"))
+			data := b.Bytes()
+			s.Assert().Equal("secret key", string(data))
+			s.Assert().Zero(alignOffset(b.region), "the locked region starts on a page boundary")
+			s.Assert().Zero(len(b.region) % pageSize)
+			if !b.Locked() {
+				t.Logf("buffer not locked, probably due to RLIMIT_MEMLOCK")
+			}
+
+			b.Destroy()
+			s.Assert().Equal(make([]byte, 10), data, "slices of the buffer read zeros")
+			s.Assert().Empty(b.Bytes())
+			s.Assert().False(b.Locked())
+			b.Destroy()
+		})
+
+		t.WithNewStep("buffers spanning pages", func(s provider.StepCtx) {
+			b := New(pageSize + 1)
+			s.Assert().Len(b.Bytes(), pageSize+1)
+			s.Assert().Len(b.region, 2*pageSize)
+			b.Destroy()
+
+			empty := Copy(nil)
+			s.Assert().Empty(empty.Bytes())
+			empty.Destroy()
+		})
+
+		t.WithNewStep("wipe", func(s provider.StepCtx) {
+			data := []byte{1, 2, 3}
+			Wipe(data)
+			s.Assert().Equal([]byte{0, 0, 0}, data)
+			Wipe(nil)
+		})
+
+		t.WithNewStep("overlaps", func(s provider.StepCtx) {
+			data := make([]byte, 8)
+			s.Assert().True(Overlaps(data, data[4:]))
+			s.Assert().True(Overlaps(data[:2], data[6:]), "capacity counts")
+			s.Assert().False(Overlaps(data, make([]byte, 8)))
+			s.Assert().False(Overlaps(data, nil))
+		})
+	})
+}
@@ -0,0 +1,118 @@
+// Package shamir splits secrets into shares with Shamir's secret sharing
+// over GF(256): any threshold of the shares rebuild the secret, and fewer
+// reveal nothing about it.
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// MaxShares is the largest number of shares, one per non-zero field element.
+const MaxShares = 255
+
+// Share is one point on the polynomials that hide each secret byte: X is the
+// share's index (1 to MaxShares) and Y holds one value per secret byte.
+type Share struct {
+	X byte
+	Y []byte
+}
+
+// Split divides secret into n shares, any k of which rebuild it.
+func Split(secret []byte, n, k int) ([]Share, error) {
+	if k < 2 || k > n || n > MaxShares {
+		return nil, fmt.Errorf("invalid split: need 2 <= k <= n <= %d, got n=%d k=%d", MaxShares, n, k)
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("cannot split an empty secret")
+	}
+
+	shares := make([]Share, n)
+	for i := range shares {
+		shares[i] = Share{X: byte(i + 1), Y: make([]byte, len(secret))}
+	}
+	coefficients := make([]byte, k)
+	for b, s := range secret {
+		// A random polynomial of degree k-1 with the secret byte at x=0.
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, err
+		}
+		coefficients[0] = s
+		for i := range shares {
+			shares[i].Y[b] = evaluate(coefficients, shares[i].X)
+		}
+	}
+	clear(coefficients)
+	return shares, nil
+}
+
+// Combine rebuilds the secret from at least threshold shares of one split.
+// With fewer shares it returns a wrong secret, which callers detect with a
+// fingerprint of the original.
+func Combine(shares []Share) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("at least two shares are required")
+	}
+	size := len(shares[0].Y)
+	seen := make(map[byte]bool, len(shares))
+	for _, share := range shares {
+		if share.X == 0 {
+			return nil, errors.New("invalid share index 0")
+		}
+		if seen[share.X] {
+			return nil, fmt.Errorf("share %d is given twice", share.X)
+		}
+		seen[share.X] = true
+		if len(share.Y) != size {
+			return nil, errors.New("shares have different lengths")
+		}
+	}
+
+	// Lagrange interpolation at x=0; subtraction is XOR in GF(256).
+	secret := make([]byte, size)
+	for i, si := range shares {
+		basis := byte(1)
+		for j, sj := range shares {
+			if i != j {
+				basis = mul(basis, div(sj.X, sj.X^si.X))
+			}
+		}
+		for b := range secret {
+			secret[b] ^= mul(si.Y[b], basis)
+		}
+	}
+	return secret, nil
+}
+
+// evaluate computes the polynomial with the given coefficients at x using
+// Horner's rule.
+func evaluate(coefficients []byte, x byte) byte {
+	var y byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coefficients[i]
+	}
+	return y
+}
+
+// mul multiplies in GF(256) modulo the AES polynomial x^8+x^4+x^3+x+1,
+// without branching on the operands.
+func mul(a, b byte) byte {
+	var p byte
+	for range 8 {
+		p ^= a & -(b & 1)
+		carry := -(a >> 7)
+		a = a<<1 ^ 0x1b&carry
+		b >>= 1
+	}
+	return p
+}
+
+// div divides a by b, which must not be zero, using b^254 as its inverse.
+func div(a, b byte) byte {
+	inverse := b
+	for range 6 {
+		inverse = mul(mul(inverse, inverse), b)
+	}
+	return mul(a, mul(inverse, inverse))
+}
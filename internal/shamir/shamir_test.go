@@ -0,0 +1,71 @@
+package shamir
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+)
+
+func TestShamir(t *testing.T) {
+	runner.Run(t, "Shamir secret sharing", func(t provider.T) {
+		t.WithNewStep("GF(256) arithmetic", func(s provider.StepCtx) {
+			// FIPS 197, section 4.2.
+			s.Assert().Equal(byte(0xc1), mul(0x57, 0x83))
+			s.Assert().Equal(byte(0xfe), mul(0x57, 0x13))
+			for a := 1; a < 256; a++ {
+				s.Require().Equal(byte(1), div(byte(a), byte(a)), "a/a for a=%d", a)
+				s.Require().Equal(byte(a), mul(div(byte(a), 0x53), 0x53))
+			}
+		})
+
+		t.WithNewStep("any k shares rebuild the secret", func(s provider.StepCtx) {
+			secret := []byte("0123456789abcdef0123456789abcdef")
+			shares, err := Split(secret, 5, 3)
+			s.Require().NoError(err)
+			s.Require().Len(shares, 5)
+			for i := range shares {
+				s.Assert().Equal(byte(i+1), shares[i].X)
+				s.Assert().NotEqual(secret, shares[i].Y)
+			}
+
+			for a := 0; a < 5; a++ {
+				for b := a + 1; b < 5; b++ {
+					for c := b + 1; c < 5; c++ {
+						got, err := Combine([]Share{shares[c], shares[a], shares[b]})
+						s.Require().NoError(err)
+						s.Assert().Equal(secret, got)
+					}
+				}
+			}
+			got, err := Combine(shares)
+			s.Require().NoError(err)
+			s.Assert().Equal(secret, got, "more than k shares work too")
+
+			got, err = Combine(shares[:2])
+			s.Require().NoError(err)
+			s.Assert().False(bytes.Equal(secret, got), "k-1 shares do not reveal the secret")
+		})
+
+		t.WithNewStep("invalid input", func(s provider.StepCtx) {
+			_, err := Split([]byte("x"), 3, 1)
+			s.Assert().Error(err)
+			_, err = Split([]byte("x"), 2, 3)
+			s.Assert().Error(err)
+			_, err = Split([]byte("x"), 256, 2)
+			s.Assert().Error(err)
+			_, err = Split(nil, 3, 2)
+			s.Assert().Error(err)
+
+			shares, err := Split([]byte("secret"), 3, 2)
+			s.Require().NoError(err)
+			_, err = Combine([]Share{shares[0], shares[0]})
+			s.Assert().Error(err)
+			_, err = Combine(shares[:1])
+			s.Assert().Error(err)
+			_, err = Combine([]Share{shares[0], {X: 2, Y: []byte("short")}})
+			s.Assert().Error(err)
+		})
+	})
+}
@@ -0,0 +1,53 @@
+package signature
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/dzibukalexander/file-processing/internal/signature/constants"
+)
+
+// attachMagic starts data that carries its signature. The layout is
+// magic | type length (1 byte) | type | signature length (2 bytes, big-endian) | signature | data.
+var attachMagic = []byte("FPS1")
+
+// Attach prefixes data with its signature and the signature type.
+func Attach(sigType constants.SignatureType, signature, data []byte) ([]byte, error) {
+	if len(signature) > 0xffff {
+		return nil, errors.New("signature is too large to attach")
+	}
+	out := make([]byte, 0, len(attachMagic)+3+len(sigType)+len(signature)+len(data))
+	out = append(out, attachMagic...)
+	out = append(out, byte(len(sigType)))
+	out = append(out, sigType...)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(signature)))
+	out = append(out, signature...)
+	return append(out, data...), nil
+}
+
+// Detach splits data written by Attach into the signature type, signature and payload.
+func Detach(data []byte) (constants.SignatureType, []byte, []byte, error) {
+	truncated := errors.New("attached signature header is truncated")
+	if !bytes.HasPrefix(data, attachMagic) {
+		return constants.NONE, nil, nil, errors.New("data has no attached signature")
+	}
+	rest := data[len(attachMagic):]
+	if len(rest) < 1 || len(rest) < 1+int(rest[0]) {
+		return constants.NONE, nil, nil, truncated
+	}
+	sigType, err := constants.SignatureTypeFromString(string(rest[1 : 1+int(rest[0])]))
+	if err != nil {
+		return constants.NONE, nil, nil, fmt.Errorf("attached signature: %w", err)
+	}
+	rest = rest[1+int(rest[0]):]
+	if len(rest) < 2 {
+		return constants.NONE, nil, nil, truncated
+	}
+	size := int(binary.BigEndian.Uint16(rest))
+	if len(rest) < 2+size {
+		return constants.NONE, nil, nil, truncated
+	}
+	return sigType, rest[2 : 2+size], rest[2+size:], nil
+}
@@ -0,0 +1,36 @@
+// internal/signature/constants/constants.go
+package constants
+
+import (
+	"errors"
+	"fmt"
+)
+
+type SignatureType string
+
+const (
+	NONE    SignatureType = "NONE"
+	ED25519 SignatureType = "ED25519"
+	// ECDSA signs SHA-256 digests with P-256 keys.
+	ECDSA SignatureType = "ECDSA"
+	// RSAPSS signs SHA-256 digests with RSA keys, including those made by "gen-key rsa".
+	RSAPSS SignatureType = "RSA-PSS"
+)
+
+func SignatureTypeFromString(s string) (SignatureType, error) {
+	switch s {
+	case "NONE":
+		return NONE, nil
+	case "ED25519":
+		return ED25519, nil
+	case "ECDSA":
+		return ECDSA, nil
+	case "RSA-PSS":
+		return RSAPSS, nil
+	default:
+		return NONE, fmt.Errorf("unknown signature type: %s", s)
+	}
+}
+
+// ErrInvalidSignature is returned when a signature does not match the data and key.
+var ErrInvalidSignature = errors.New("invalid signature")
@@ -0,0 +1,63 @@
+// internal/signature/ecdsa/ecdsa.go
+package ecdsa
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/dzibukalexander/file-processing/internal/keyfile"
+	"github.com/dzibukalexander/file-processing/internal/signature/constants"
+)
+
+// ECDSASigner produces ASN.1 signatures of the SHA-256 digest with a P-256 key.
+type ECDSASigner struct{}
+
+func (s *ECDSASigner) Sign(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	parsed, err := keyfile.ParsePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok || priv.Curve != elliptic.P256() {
+		return nil, errors.New("private key is not an ECDSA P-256 key")
+	}
+	digest := sha256.Sum256(data)
+	return ecdsa.SignASN1(rand.Reader, priv, digest[:])
+}
+
+type ECDSAVerifier struct{}
+
+func (v *ECDSAVerifier) Verify(ctx context.Context, data []byte, signature []byte, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	parsed, err := keyfile.ParsePublicKey(key)
+	if err != nil {
+		return err
+	}
+	pub, ok := parsed.(*ecdsa.PublicKey)
+	if !ok || pub.Curve != elliptic.P256() {
+		return errors.New("public key is not an ECDSA P-256 key")
+	}
+	digest := sha256.Sum256(data)
+	if !ecdsa.VerifyASN1(pub, digest[:], signature) {
+		return constants.ErrInvalidSignature
+	}
+	return nil
+}
+
+func (s *ECDSASigner) GenerateKey(path string) error {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate ECDSA key pair: %w", err)
+	}
+	return keyfile.WriteKeyPair(path, priv)
+}
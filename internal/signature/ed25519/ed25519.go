@@ -0,0 +1,57 @@
+// internal/signature/ed25519/ed25519.go
+package ed25519
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/dzibukalexander/file-processing/internal/keyfile"
+	"github.com/dzibukalexander/file-processing/internal/signature/constants"
+)
+
+type Ed25519Signer struct{}
+
+func (s *Ed25519Signer) Sign(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	parsed, err := keyfile.ParsePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is %T, not an Ed25519 key", parsed)
+	}
+	return ed25519.Sign(priv, data), nil
+}
+
+type Ed25519Verifier struct{}
+
+func (v *Ed25519Verifier) Verify(ctx context.Context, data []byte, signature []byte, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	parsed, err := keyfile.ParsePublicKey(key)
+	if err != nil {
+		return err
+	}
+	pub, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return fmt.Errorf("public key is %T, not an Ed25519 key", parsed)
+	}
+	if !ed25519.Verify(pub, data, signature) {
+		return constants.ErrInvalidSignature
+	}
+	return nil
+}
+
+func (s *Ed25519Signer) GenerateKey(path string) error {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate Ed25519 key pair: %w", err)
+	}
+	return keyfile.WriteKeyPair(path, priv)
+}
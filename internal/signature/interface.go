@@ -0,0 +1,67 @@
+// internal/signature/interface.go
+package signature
+
+import (
+	"context"
+
+	. "github.com/dzibukalexander/file-processing/internal/signature/constants"
+	"github.com/dzibukalexander/file-processing/internal/signature/ecdsa"
+	"github.com/dzibukalexander/file-processing/internal/signature/ed25519"
+	"github.com/dzibukalexander/file-processing/internal/signature/rsapss"
+)
+
+type Signer interface {
+	Sign(ctx context.Context, data []byte, key []byte) ([]byte, error)
+}
+
+type Verifier interface {
+	Verify(ctx context.Context, data []byte, signature []byte, key []byte) error
+}
+
+type KeyGenerator interface {
+	GenerateKey(path string) error
+}
+
+func NewSigner(sigType SignatureType) Signer {
+	var signer Signer
+	switch sigType {
+	case ED25519:
+		signer = &ed25519.Ed25519Signer{}
+	case ECDSA:
+		signer = &ecdsa.ECDSASigner{}
+	case RSAPSS:
+		signer = &rsapss.RSAPSSSigner{}
+	default:
+		return nil
+	}
+	return NewLoggingSigner(signer)
+}
+
+func NewVerifier(sigType SignatureType) Verifier {
+	var verifier Verifier
+	switch sigType {
+	case ED25519:
+		verifier = &ed25519.Ed25519Verifier{}
+	case ECDSA:
+		verifier = &ecdsa.ECDSAVerifier{}
+	case RSAPSS:
+		verifier = &rsapss.RSAPSSVerifier{}
+	default:
+		return nil
+	}
+	return NewLoggingVerifier(verifier)
+}
+
+// NewKeyGenerator returns a generator that writes path.priv and path.pub.
+func NewKeyGenerator(sigType SignatureType) KeyGenerator {
+	switch sigType {
+	case ED25519:
+		return &ed25519.Ed25519Signer{}
+	case ECDSA:
+		return &ecdsa.ECDSASigner{}
+	case RSAPSS:
+		return &rsapss.RSAPSSSigner{}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,57 @@
+package signature
+
+import (
+	"context"
+	"time"
+
+	"github.com/dzibukalexander/file-processing/internal/logger"
+)
+
+type loggingSigner struct {
+	signer Signer
+}
+
+func NewLoggingSigner(signer Signer) Signer {
+	return &loggingSigner{signer: signer}
+}
+
+func (l *loggingSigner) Sign(ctx context.Context, data []byte, key []byte) (result []byte, err error) {
+	log := logger.For("signature").WithField("input_size", len(data))
+	log.Info("Starting signing")
+
+	defer func(begin time.Time) {
+		if err != nil {
+			log.WithError(err).Error("Signing failed")
+		} else {
+			log.WithFields(map[string]interface{}{
+				"duration":       time.Since(begin),
+				"signature_size": len(result),
+			}).Info("Signing finished")
+		}
+	}(time.Now())
+
+	return l.signer.Sign(ctx, data, key)
+}
+
+type loggingVerifier struct {
+	verifier Verifier
+}
+
+func NewLoggingVerifier(verifier Verifier) Verifier {
+	return &loggingVerifier{verifier: verifier}
+}
+
+func (l *loggingVerifier) Verify(ctx context.Context, data []byte, signature []byte, key []byte) (err error) {
+	log := logger.For("signature").WithField("input_size", len(data))
+	log.Info("Starting signature verification")
+
+	defer func(begin time.Time) {
+		if err != nil {
+			log.WithError(err).Error("Signature verification failed")
+		} else {
+			log.WithField("duration", time.Since(begin)).Info("Signature verified")
+		}
+	}(time.Now())
+
+	return l.verifier.Verify(ctx, data, signature, key)
+}
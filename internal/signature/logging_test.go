@@ -0,0 +1,84 @@
+package signature
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dzibukalexander/file-processing/internal/config"
+	"github.com/dzibukalexander/file-processing/internal/logger"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSigner is a mock for the Signer interface
+type MockSigner struct {
+	mock.Mock
+}
+
+func (m *MockSigner) Sign(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	args := m.Called(ctx, data, key)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+// MockVerifier is a mock for the Verifier interface
+type MockVerifier struct {
+	mock.Mock
+}
+
+func (m *MockVerifier) Verify(ctx context.Context, data []byte, signature []byte, key []byte) error {
+	args := m.Called(ctx, data, signature, key)
+	return args.Error(0)
+}
+
+func setupLoggingTest() *bytes.Buffer {
+	config.AppConfig = &config.Config{EnableLogging: true}
+	logger.SetupLogger()
+
+	logOutput := new(bytes.Buffer)
+	logger.GetInstance().SetOutput(logOutput)
+	return logOutput
+}
+
+func TestLoggingSigner(t *testing.T) {
+	runner.Run(t, "LoggingSigner", func(t provider.T) {
+		t.WithNewStep("success path", func(s provider.StepCtx) {
+			logOutput := setupLoggingTest()
+			origOut := logger.GetInstance().Out
+			defer logger.GetInstance().SetOutput(origOut)
+
+			mockSigner := new(MockSigner)
+			input, key := []byte("data"), []byte("key")
+			mockSigner.On("Sign", mock.Anything, input, key).Return([]byte("sig"), nil)
+
+			_, err := NewLoggingSigner(mockSigner).Sign(context.Background(), input, key)
+
+			s.Assert().NoError(err)
+			s.Assert().Contains(logOutput.String(), "Starting signing")
+			s.Assert().Contains(logOutput.String(), "Signing finished")
+			mockSigner.AssertExpectations(t)
+		})
+	})
+}
+
+func TestLoggingVerifier_Error(t *testing.T) {
+	runner.Run(t, "LoggingVerifier", func(t provider.T) {
+		t.WithNewStep("error path", func(s provider.StepCtx) {
+			logOutput := setupLoggingTest()
+			origOut := logger.GetInstance().Out
+			defer logger.GetInstance().SetOutput(origOut)
+
+			mockVerifier := new(MockVerifier)
+			input, sig, key := []byte("data"), []byte("sig"), []byte("key")
+			mockVerifier.On("Verify", mock.Anything, input, sig, key).Return(errors.New("bad signature"))
+
+			err := NewLoggingVerifier(mockVerifier).Verify(context.Background(), input, sig, key)
+
+			s.Assert().Error(err)
+			s.Assert().Contains(logOutput.String(), "Signature verification failed")
+			mockVerifier.AssertExpectations(t)
+		})
+	})
+}
@@ -0,0 +1,65 @@
+// internal/signature/rsapss/rsapss.go
+package rsapss
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/dzibukalexander/file-processing/internal/keyfile"
+	"github.com/dzibukalexander/file-processing/internal/signature/constants"
+)
+
+// pssOptions uses a salt as long as the SHA-256 digest, which is what most
+// other tools expect.
+var pssOptions = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+
+type RSAPSSSigner struct{}
+
+func (s *RSAPSSSigner) Sign(ctx context.Context, data []byte, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	parsed, err := keyfile.ParsePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is %T, not an RSA key", parsed)
+	}
+	digest := sha256.Sum256(data)
+	return rsa.SignPSS(rand.Reader, priv, crypto.SHA256, digest[:], pssOptions)
+}
+
+type RSAPSSVerifier struct{}
+
+func (v *RSAPSSVerifier) Verify(ctx context.Context, data []byte, signature []byte, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	parsed, err := keyfile.ParsePublicKey(key)
+	if err != nil {
+		return err
+	}
+	pub, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("public key is %T, not an RSA key", parsed)
+	}
+	digest := sha256.Sum256(data)
+	if err := rsa.VerifyPSS(pub, crypto.SHA256, digest[:], signature, pssOptions); err != nil {
+		return constants.ErrInvalidSignature
+	}
+	return nil
+}
+
+func (s *RSAPSSSigner) GenerateKey(path string) error {
+	priv, err := rsa.GenerateKey(rand.Reader, 3072)
+	if err != nil {
+		return fmt.Errorf("failed to generate RSA key pair: %w", err)
+	}
+	return keyfile.WriteKeyPair(path, priv)
+}
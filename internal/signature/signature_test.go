@@ -0,0 +1,82 @@
+package signature
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dzibukalexander/file-processing/internal/encryption/rsa"
+	"github.com/dzibukalexander/file-processing/internal/signature/constants"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+)
+
+func TestSignVerify(t *testing.T) {
+	dir := t.TempDir()
+	readPair := func(s provider.StepCtx, path string) ([]byte, []byte) {
+		priv, err := os.ReadFile(path + ".priv")
+		s.Require().NoError(err)
+		pub, err := os.ReadFile(path + ".pub")
+		s.Require().NoError(err)
+		return priv, pub
+	}
+
+	runner.Run(t, "Signature roundtrips", func(t provider.T) {
+		ctx := context.Background()
+		data := []byte("archive contents")
+
+		for _, sigType := range []constants.SignatureType{constants.ED25519, constants.ECDSA, constants.RSAPSS} {
+			t.WithNewStep(string(sigType), func(s provider.StepCtx) {
+				path := filepath.Join(dir, string(sigType))
+				s.Require().NoError(NewKeyGenerator(sigType).GenerateKey(path))
+				info, err := os.Stat(path + ".priv")
+				s.Require().NoError(err)
+				s.Assert().Equal(os.FileMode(0600), info.Mode().Perm())
+				priv, pub := readPair(s, path)
+
+				sig, err := NewSigner(sigType).Sign(ctx, data, priv)
+				s.Require().NoError(err)
+				verifier := NewVerifier(sigType)
+				s.Assert().NoError(verifier.Verify(ctx, data, sig, pub))
+				s.Assert().ErrorIs(verifier.Verify(ctx, []byte("tampered"), sig, pub), constants.ErrInvalidSignature)
+
+				_, err = NewSigner(sigType).Sign(ctx, data, pub)
+				s.Assert().Error(err, "a public key cannot sign")
+			})
+		}
+
+		t.WithNewStep("key type mismatch", func(s provider.StepCtx) {
+			priv, pub := readPair(s, filepath.Join(dir, string(constants.ED25519)))
+			_, err := NewSigner(constants.ECDSA).Sign(ctx, data, priv)
+			s.Assert().Error(err)
+			s.Assert().Error(NewVerifier(constants.RSAPSS).Verify(ctx, data, []byte("sig"), pub))
+			s.Assert().Nil(NewSigner(constants.NONE))
+		})
+
+		t.WithNewStep("rsa-pss with keys from gen-key rsa", func(s provider.StepCtx) {
+			path := filepath.Join(dir, "legacy")
+			s.Require().NoError((&rsa.RSAEncryptor{}).GenerateKey(path))
+			priv, pub := readPair(s, path)
+			sig, err := NewSigner(constants.RSAPSS).Sign(ctx, data, priv)
+			s.Require().NoError(err)
+			s.Assert().NoError(NewVerifier(constants.RSAPSS).Verify(ctx, data, sig, pub))
+		})
+
+		t.WithNewStep("attach and detach", func(s provider.StepCtx) {
+			sig := make([]byte, 512)
+			attached, err := Attach(constants.RSAPSS, sig, data)
+			s.Require().NoError(err)
+			sigType, detached, payload, err := Detach(attached)
+			s.Require().NoError(err)
+			s.Assert().Equal(constants.RSAPSS, sigType)
+			s.Assert().Len(detached, 512)
+			s.Assert().Equal(data, payload)
+
+			_, _, _, err = Detach(data)
+			s.Assert().Error(err)
+			_, _, _, err = Detach(attached[:20])
+			s.Assert().Error(err)
+		})
+	})
+}
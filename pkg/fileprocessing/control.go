@@ -0,0 +1,179 @@
+package fileprocessing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dzibukalexander/file-processing/internal/detect"
+	"github.com/dzibukalexander/file-processing/internal/logger"
+)
+
+// IsBlock reports whether operation name opens a block of nested steps
+// ("if" and "try").
+func IsBlock(name string) bool {
+	return name == "if" || name == "try"
+}
+
+// predicate tests the data flowing into an "if" step.
+type predicate func(data []byte) bool
+
+// ifStep runs Steps when every predicate in the params holds and Else otherwise.
+// Predicates: type=<json|xml|yaml|html|text|binary|gzip|zip|empty>,
+// compression=<gzip|zip|none>, size_gt=<bytes>, size_lt=<bytes>,
+// match=<regexp>, and not=true to negate the result.
+func (p *Pipeline) ifStep(op *Operation) (step, error) {
+	predicates, negate, err := parsePredicates(op.Params)
+	if err != nil {
+		return nil, err
+	}
+	then, otherwise := p.branch(op.Steps), p.branch(op.Else)
+
+	return func(ctx context.Context, data []byte) ([]byte, error) {
+		holds := true
+		for _, pred := range predicates {
+			if !pred(data) {
+				holds = false
+				break
+			}
+		}
+		if negate {
+			holds = !holds
+		}
+		logger.For("fileprocessing").WithField("result", holds).Debug("Evaluated if predicate")
+		if holds {
+			return then.Run(ctx, data)
+		}
+		return otherwise.Run(ctx, data)
+	}, nil
+}
+
+// tryStep runs Steps and, if they fail, runs Else on the original input.
+// Cancellation is not treated as a failure.
+func (p *Pipeline) tryStep(op *Operation) (step, error) {
+	attempt, fallback := p.branch(op.Steps), p.branch(op.Else)
+
+	return func(ctx context.Context, data []byte) ([]byte, error) {
+		result, err := attempt.Run(ctx, data)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		logger.For("fileprocessing").WithError(err).Warn("Try block failed, running fallback")
+		result, fallbackErr := fallback.Run(ctx, data)
+		if fallbackErr != nil {
+			return nil, fmt.Errorf("fallback failed: %w (after: %v)", fallbackErr, err)
+		}
+		return result, nil
+	}, nil
+}
+
+// teeStep writes the data, transformed by Steps if any, to the path param
+// and passes the original data on unchanged. The file is readable only by its
+// owner, since the branch may hold decrypted data.
+func (p *Pipeline) teeStep(op *Operation) (step, error) {
+	path := op.Params["path"]
+	if path == "" {
+		return nil, errors.New("tee requires a path param")
+	}
+	branch := p.branch(op.Steps)
+
+	return func(ctx context.Context, data []byte) ([]byte, error) {
+		out, err := branch.Run(ctx, data)
+		if err != nil {
+			return nil, fmt.Errorf("tee branch: %w", err)
+		}
+		if err := os.WriteFile(path, out, 0600); err != nil {
+			return nil, fmt.Errorf("tee: %w", err)
+		}
+		logger.For("fileprocessing").WithFields(map[string]interface{}{
+			"path": path,
+			"size": len(out),
+		}).Info("Tee output written")
+		return data, nil
+	}, nil
+}
+
+// branch builds a nested pipeline that shares p's options, except the step
+// hook which only observes top-level steps.
+func (p *Pipeline) branch(ops []*Operation) *Pipeline {
+	nested := FromOperations(ops)
+	nested.opts = p.opts
+	nested.opts.stepHook = nil
+	return nested
+}
+
+// dataKinds are the values detect.Detect can return, which "if type=..."
+// compares against.
+var dataKinds = []detect.Kind{
+	detect.JSON, detect.XML, detect.HTML, detect.YAML, detect.TEXT,
+	detect.BINARY, detect.EMPTY, detect.GZIP, detect.ZIP,
+}
+
+func parsePredicates(params map[string]string) ([]predicate, bool, error) {
+	var predicates []predicate
+	negate := false
+
+	for key, value := range params {
+		switch key {
+		case "type":
+			kind := detect.Kind(strings.ToUpper(value))
+			if !slices.Contains(dataKinds, kind) {
+				return nil, false, fmt.Errorf("invalid type: %s (want json, xml, html, yaml, text, binary, empty, gzip or zip)", value)
+			}
+			predicates = append(predicates, func(data []byte) bool {
+				return detect.Detect(data) == kind
+			})
+		case "compression":
+			kind := detect.Kind(strings.ToUpper(value))
+			switch kind {
+			case detect.GZIP, detect.ZIP:
+			case "NONE":
+				kind = ""
+			default:
+				return nil, false, fmt.Errorf("invalid compression: %s (want gzip, zip or none)", value)
+			}
+			predicates = append(predicates, func(data []byte) bool {
+				return detect.Compression(data) == kind
+			})
+		case "size_gt", "size_lt":
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 0 {
+				return nil, false, fmt.Errorf("invalid %s: %s", key, value)
+			}
+			greater := key == "size_gt"
+			predicates = append(predicates, func(data []byte) bool {
+				if greater {
+					return len(data) > limit
+				}
+				return len(data) < limit
+			})
+		case "match":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid match pattern: %w", err)
+			}
+			predicates = append(predicates, re.Match)
+		case "not":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid not: %s", value)
+			}
+			negate = b
+		case "timeout":
+		default:
+			return nil, false, fmt.Errorf("unknown if predicate: %s", key)
+		}
+	}
+	if len(predicates) == 0 {
+		return nil, false, errors.New("if requires at least one predicate")
+	}
+	return predicates, negate, nil
+}
@@ -0,0 +1,134 @@
+package fileprocessing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+)
+
+func TestPipeline_ControlFlow(t *testing.T) {
+	runner.Run(t, "Control-flow steps", func(t provider.T) {
+		ctx := context.Background()
+		compressIf := func(params map[string]string) *Operation {
+			op := NewOperation("if", params)
+			op.Steps = []*Operation{NewOperation("compress", map[string]string{"type": "gzip"})}
+			return op
+		}
+		isGzip := func(data []byte) bool {
+			return len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b
+		}
+
+		t.WithNewStep("if predicates", func(s provider.StepCtx) {
+			cases := []struct {
+				params map[string]string
+				data   string
+				taken  bool
+			}{
+				{map[string]string{"type": "json"}, `{"a": 1}`, true},
+				{map[string]string{"type": "json"}, "plain text", false},
+				{map[string]string{"compression": "none"}, "plain text", true},
+				{map[string]string{"size_gt": "3"}, "abcd", true},
+				{map[string]string{"size_lt": "3"}, "abcd", false},
+				{map[string]string{"match": "^ab"}, "abcd", true},
+				{map[string]string{"match": "^ab", "not": "true"}, "abcd", false},
+				{map[string]string{"size_gt": "1", "match": "x"}, "abcd", false},
+			}
+			for _, c := range cases {
+				out, err := FromOperations([]*Operation{compressIf(c.params)}).Run(ctx, []byte(c.data))
+				s.Require().NoError(err, c.params)
+				s.Assert().Equal(c.taken, isGzip(out), c.params)
+			}
+		})
+
+		t.WithNewStep("if else and compression predicate", func(s provider.StepCtx) {
+			op := NewOperation("if", map[string]string{"compression": "gzip"})
+			op.Steps = []*Operation{NewOperation("decompress", map[string]string{"type": "gzip"})}
+			op.Else = []*Operation{NewOperation("calculate", map[string]string{"type": "library"})}
+
+			compressed, err := NewPipeline().Compress(Gzip).Run(ctx, []byte("2 + 3"))
+			s.Require().NoError(err)
+			out, err := FromOperations([]*Operation{op}).Run(ctx, compressed)
+			s.Require().NoError(err)
+			s.Assert().Equal("2 + 3", string(out))
+
+			out, err = FromOperations([]*Operation{op}).Run(ctx, []byte("2 + 3"))
+			s.Require().NoError(err)
+			s.Assert().Equal("5", string(out))
+		})
+
+		t.WithNewStep("invalid if", func(s provider.StepCtx) {
+			for _, params := range []map[string]string{
+				{},
+				{"size_gt": "big"},
+				{"match": "("},
+				{"colour": "red"},
+				{"type": "jsn"},
+				{"compression": "json"},
+			} {
+				_, err := FromOperations([]*Operation{compressIf(params)}).Run(ctx, []byte("data"))
+				s.Assert().Error(err, params)
+			}
+		})
+
+		t.WithNewStep("try fallback", func(s provider.StepCtx) {
+			op := NewOperation("try", nil)
+			op.Steps = []*Operation{NewOperation("decompress", map[string]string{"type": "gzip"})}
+			op.Else = []*Operation{NewOperation("compress", map[string]string{"type": "zip"})}
+
+			out, err := FromOperations([]*Operation{op}).Run(ctx, []byte("not gzip"))
+			s.Require().NoError(err)
+			s.Assert().Equal("PK", string(out[:2]))
+
+			op.Else = nil
+			_, err = FromOperations([]*Operation{op}).Run(ctx, []byte("not gzip"))
+			s.Assert().NoError(err, "an empty fallback passes the input through")
+
+			op.Else = []*Operation{NewOperation("decompress", map[string]string{"type": "zip"})}
+			_, err = FromOperations([]*Operation{op}).Run(ctx, []byte("not gzip"))
+			s.Require().Error(err)
+			s.Assert().Contains(err.Error(), "fallback failed")
+		})
+
+		t.WithNewStep("tee", func(s provider.StepCtx) {
+			dir := t.TempDir()
+			plain := filepath.Join(dir, "plain.txt")
+			packed := filepath.Join(dir, "packed.gz")
+			branch := NewOperation("tee", map[string]string{"path": packed})
+			branch.Steps = []*Operation{NewOperation("compress", map[string]string{"type": "gzip"})}
+
+			out, err := NewPipeline().
+				Then("tee", map[string]string{"path": plain}).
+				Then("calculate", map[string]string{"type": "library"}).
+				Run(ctx, []byte("1 + 1"))
+			s.Require().NoError(err)
+			s.Assert().Equal("2", string(out))
+			written, err := os.ReadFile(plain)
+			s.Require().NoError(err)
+			s.Assert().Equal("1 + 1", string(written))
+			info, err := os.Stat(plain)
+			s.Require().NoError(err)
+			s.Assert().Equal(os.FileMode(0600), info.Mode().Perm())
+
+			out, err = FromOperations([]*Operation{branch}).Run(ctx, []byte("data"))
+			s.Require().NoError(err)
+			s.Assert().Equal("data", string(out))
+			written, err = os.ReadFile(packed)
+			s.Require().NoError(err)
+			s.Assert().True(isGzip(written))
+
+			_, err = NewPipeline().Then("tee", nil).Run(ctx, []byte("data"))
+			s.Assert().Error(err)
+		})
+
+		t.WithNewStep("nested steps only on block operations", func(s provider.StepCtx) {
+			op := NewOperation("compress", map[string]string{"type": "gzip"})
+			op.Steps = []*Operation{NewOperation("compress", map[string]string{"type": "gzip"})}
+			_, err := FromOperations([]*Operation{op}).Run(ctx, []byte("data"))
+			s.Assert().Error(err)
+		})
+	})
+}
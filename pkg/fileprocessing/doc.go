@@ -0,0 +1,15 @@
+// Package fileprocessing is the public API for building and running file
+// processing pipelines: compression, encryption and calculation steps applied
+// in order to a buffer, a stream or a file.
+//
+// A pipeline is assembled with a fluent builder:
+//
+//	p := fileprocessing.NewPipeline(fileprocessing.WithIndent(2)).
+//		Calculate(fileprocessing.Library).
+//		Compress(fileprocessing.Gzip).
+//		Encrypt(fileprocessing.AES, key)
+//	out, err := p.Run(ctx, data)
+//
+// Builder errors, such as an unknown algorithm, are reported by Err and by
+// the first call that runs the pipeline.
+package fileprocessing
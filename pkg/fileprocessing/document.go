@@ -0,0 +1,293 @@
+package fileprocessing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the newest pipeline file schema this version reads.
+// Documents are written in the oldest version that can hold them, so older
+// releases can still read pipelines that use none of the newer features.
+const SchemaVersion = 4
+
+// Document is the on-disk form of a pipeline: its steps plus metadata.
+// Files ending in .yaml or .yml are YAML, anything else is JSON.
+type Document struct {
+	SchemaVersion int          `json:"schema_version" yaml:"schema_version"`
+	Name          string       `json:"name,omitempty" yaml:"name,omitempty"`
+	Description   string       `json:"description,omitempty" yaml:"description,omitempty"`
+	Author        string       `json:"author,omitempty" yaml:"author,omitempty"`
+	Tags          []string     `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Created       time.Time    `json:"created" yaml:"created"`
+	Variables     []Variable   `json:"variables,omitempty" yaml:"variables,omitempty"`
+	Operations    []*Operation `json:"operations" yaml:"operations"`
+}
+
+// migration upgrades a decoded document from one schema version to the next.
+type migration func(raw interface{}) (map[string]interface{}, error)
+
+// migrations[v] upgrades a version v document to version v+1.
+var migrations = map[int]migration{
+	0: migrateV0,
+	// Version 2 added optional template variables.
+	1: migrateV1,
+	// Version 3 added nested steps and else branches for control flow.
+	2: bumpVersion(3),
+	// Version 4 added tags for searching a pipeline catalog.
+	3: bumpVersion(4),
+}
+
+// migrateV0 wraps the original format, a bare array of operations, in a version 1 document.
+func migrateV0(raw interface{}) (map[string]interface{}, error) {
+	ops, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("version 0 pipeline must be a list of operations")
+	}
+	return map[string]interface{}{
+		"schema_version": 1,
+		"operations":     ops,
+	}, nil
+}
+
+// migrateV1 escapes every $ in params as $$, so params written before
+// template variables existed keep their literal values when resolved.
+func migrateV1(raw interface{}) (map[string]interface{}, error) {
+	obj := raw.(map[string]interface{})
+	ops, _ := obj["operations"].([]interface{})
+	for _, op := range ops {
+		fields, _ := op.(map[string]interface{})
+		params, _ := fields["params"].(map[string]interface{})
+		for key, value := range params {
+			if s, ok := value.(string); ok {
+				params[key] = strings.ReplaceAll(s, "$", "$$")
+			}
+		}
+	}
+	obj["schema_version"] = 2
+	return obj, nil
+}
+
+// bumpVersion is the migration for versions that only added optional fields.
+func bumpVersion(to int) migration {
+	return func(raw interface{}) (map[string]interface{}, error) {
+		obj := raw.(map[string]interface{})
+		obj["schema_version"] = to
+		return obj, nil
+	}
+}
+
+// ReadDocument loads a pipeline file, migrating older schema versions.
+func ReadDocument(filePath string) (*Document, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := ParseDocument(data, isYAMLPath(filePath))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pipeline file %s: %w", filePath, err)
+	}
+	return doc, nil
+}
+
+// ParseDocument decodes a pipeline document from JSON, or YAML if isYAML is set.
+func ParseDocument(data []byte, isYAML bool) (*Document, error) {
+	var raw interface{}
+	if isYAML {
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	raw, err := migrate(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode strictly through JSON so both formats report unknown fields the same way.
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(normalized))
+	decoder.DisallowUnknownFields()
+	var doc Document
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, describeDecodeError(err)
+	}
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Validate checks that the document can be run.
+func (d *Document) Validate() error {
+	seen := map[string]bool{}
+	for _, v := range d.Variables {
+		if !variableName.MatchString(v.Name) {
+			return fmt.Errorf("invalid variable name %q", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("variable %s declared more than once", v.Name)
+		}
+		seen[v.Name] = true
+	}
+	return validateOperations(d.Operations, "")
+}
+
+func validateOperations(ops []*Operation, parent string) error {
+	for i, op := range ops {
+		position := fmt.Sprintf("%s%d", parent, i+1)
+		if op == nil || strings.TrimSpace(op.Name) == "" {
+			return fmt.Errorf("operation %s has no name", position)
+		}
+		nests := IsBlock(op.Name) || op.Name == "tee" || (IsInclude(op.Name) && op.expanded)
+		if (len(op.Steps) > 0 || len(op.Else) > 0) && !nests {
+			return fmt.Errorf("operation %s (%s) cannot have nested steps", position, op.Name)
+		}
+		if len(op.Else) > 0 && !IsBlock(op.Name) {
+			return fmt.Errorf("operation %s (%s) cannot have an else branch", position, op.Name)
+		}
+		if err := validateOperations(op.Steps, position+"."); err != nil {
+			return err
+		}
+		if err := validateOperations(op.Else, position+".else."); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFile saves the document as JSON, or YAML for .yaml/.yml paths.
+// SchemaVersion is set to the oldest version that can represent the
+// document and an unset Created time is filled in. Include and use
+// operations are written as references, without their expanded steps.
+func (d *Document) WriteFile(filePath string) error {
+	if d.Created.IsZero() {
+		d.Created = time.Now().UTC().Truncate(time.Second)
+	}
+	out := *d
+	out.Operations = collapseIncludes(d.Operations)
+	out.SchemaVersion = out.requiredVersion()
+	d.SchemaVersion = out.SchemaVersion
+	if out.Operations == nil {
+		out.Operations = []*Operation{}
+	}
+
+	var data []byte
+	var err error
+	if isYAMLPath(filePath) {
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err = encoder.Encode(&out); err == nil {
+			err = encoder.Close()
+		}
+		data = buf.Bytes()
+	} else {
+		data, err = json.MarshalIndent(&out, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0644)
+}
+
+// requiredVersion returns the oldest schema version that holds everything in
+// d, following the history recorded in migrations.
+func (d *Document) requiredVersion() int {
+	version := 1
+	if len(d.Variables) > 0 {
+		version = 2
+	}
+	for _, op := range allOperations(d.Operations) {
+		if len(op.Steps) > 0 || len(op.Else) > 0 {
+			version = max(version, 3)
+		}
+		for _, value := range op.Params {
+			// Version 1 params have no variables, and no $$ escapes.
+			if strings.Contains(value, "$") {
+				version = max(version, 2)
+			}
+		}
+	}
+	if len(d.Tags) > 0 {
+		version = 4
+	}
+	return version
+}
+
+// LoadPipeline reads a pipeline file into a runnable Pipeline, resolving
+// template variables from vars and the environment and expanding include
+// and use operations.
+func LoadPipeline(filePath string, vars map[string]string, opts ...Option) (*Pipeline, error) {
+	doc, err := ReadDocument(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Resolve(vars, os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := doc.ExpandIncludes(filePath, nil, os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return FromOperations(doc.Operations, opts...), nil
+}
+
+func migrate(raw interface{}) (interface{}, error) {
+	version := 0
+	if obj, ok := raw.(map[string]interface{}); ok {
+		v, ok := obj["schema_version"].(float64)
+		if !ok {
+			if n, isInt := obj["schema_version"].(int); isInt {
+				v, ok = float64(n), true
+			}
+		}
+		if !ok || v != float64(int(v)) || v < 1 {
+			return nil, fmt.Errorf("missing or invalid schema_version")
+		}
+		version = int(v)
+	}
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("schema version %d is newer than the supported version %d", version, SchemaVersion)
+	}
+
+	for ; version < SchemaVersion; version++ {
+		upgraded, err := migrations[version](raw)
+		if err != nil {
+			return nil, fmt.Errorf("migrating from schema version %d: %w", version, err)
+		}
+		raw = upgraded
+	}
+	return raw, nil
+}
+
+func describeDecodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr):
+		return fmt.Errorf("field %q must be %s, not %s", typeErr.Field, typeErr.Type, typeErr.Value)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return fmt.Errorf("unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	default:
+		return err
+	}
+}
+
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func isYAMLPath(filePath string) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	return ext == ".yaml" || ext == ".yml"
+}
@@ -0,0 +1,115 @@
+package fileprocessing
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+)
+
+func TestDocument(t *testing.T) {
+	tempDir := t.TempDir()
+
+	runner.Run(t, "Pipeline documents", func(t provider.T) {
+		for _, name := range []string{"pipeline.json", "pipeline.yaml"} {
+			path := filepath.Join(tempDir, name)
+			t.WithNewStep("roundtrip "+name, func(s provider.StepCtx) {
+				doc := &Document{
+					Name:        "archive",
+					Description: "compress then encrypt",
+					Author:      "ops",
+					Operations: []*Operation{
+						NewOperation("compress", map[string]string{"type": "gzip"}),
+						NewOperation("encrypt", map[string]string{"type": "aes", "key_file": "k.bin"}),
+					},
+				}
+				s.Require().NoError(doc.WriteFile(path))
+
+				loaded, err := ReadDocument(path)
+				s.Require().NoError(err)
+				s.Assert().Equal(SchemaVersion, loaded.SchemaVersion)
+				s.Assert().Equal("archive", loaded.Name)
+				s.Assert().Equal("ops", loaded.Author)
+				s.Assert().True(doc.Created.Equal(loaded.Created))
+				s.Assert().Equal(doc.Operations[1].Params, loaded.Operations[1].Params)
+			})
+		}
+
+		t.WithNewStep("written in the oldest version that holds it", func(s provider.StepCtx) {
+			path := filepath.Join(tempDir, "versioned.json")
+			written := func(doc *Document) int {
+				s.Require().NoError(doc.WriteFile(path))
+				raw, err := os.ReadFile(path)
+				s.Require().NoError(err)
+				var head struct {
+					SchemaVersion int `json:"schema_version"`
+				}
+				s.Require().NoError(json.Unmarshal(raw, &head))
+				return head.SchemaVersion
+			}
+			compress := func(params map[string]string) []*Operation {
+				return []*Operation{NewOperation("compress", params)}
+			}
+
+			s.Assert().Equal(1, written(&Document{Operations: compress(map[string]string{"type": "gzip"})}))
+			s.Assert().Equal(2, written(&Document{Operations: compress(map[string]string{"type": "${TYPE}"})}))
+			s.Assert().Equal(2, written(&Document{Variables: []Variable{{Name: "TYPE"}}, Operations: compress(nil)}))
+			tryOp := NewOperation("try", nil)
+			tryOp.Steps = compress(nil)
+			s.Assert().Equal(3, written(&Document{Operations: []*Operation{tryOp}}))
+			s.Assert().Equal(4, written(&Document{Tags: []string{"backup"}, Operations: compress(nil)}))
+		})
+
+		t.WithNewStep("migrate version 0", func(s provider.StepCtx) {
+			doc, err := ParseDocument([]byte(`[{"name":"compress","params":{"type":"zip"}}]`), false)
+			s.Require().NoError(err)
+			s.Assert().Equal(SchemaVersion, doc.SchemaVersion)
+			s.Assert().Equal("compress", doc.Operations[0].Name)
+
+			doc, err = ParseDocument([]byte("- name: compress\n  params:\n    type: zip\n"), true)
+			s.Require().NoError(err)
+			s.Assert().Equal("zip", doc.Operations[0].Params["type"])
+		})
+
+		t.WithNewStep("params from before variables stay literal", func(s provider.StepCtx) {
+			doc, err := ParseDocument([]byte(`{"schema_version":1,"operations":[{"name":"encrypt","params":{"key_file":"${HOME}/$k"}}]}`), false)
+			s.Require().NoError(err)
+			s.Require().NoError(doc.Resolve(nil, func(string) (string, bool) { return "/root", true }))
+			s.Assert().Equal("${HOME}/$k", doc.Operations[0].Params["key_file"])
+		})
+
+		t.WithNewStep("validation errors", func(s provider.StepCtx) {
+			_, err := ParseDocument([]byte(`{"schema_version":1,"operations":[],"nmae":"x"}`), false)
+			s.Require().Error(err)
+			s.Assert().Contains(err.Error(), `unknown field "nmae"`)
+
+			_, err = ParseDocument([]byte("schema_version: 1\noperations:\n  - name: compress\n    parms: {}\n"), true)
+			s.Require().Error(err)
+			s.Assert().Contains(err.Error(), `unknown field "parms"`)
+
+			_, err = ParseDocument([]byte(`{"schema_version":99,"operations":[]}`), false)
+			s.Require().Error(err)
+			s.Assert().Contains(err.Error(), "newer")
+
+			_, err = ParseDocument([]byte(`{"operations":[]}`), false)
+			s.Assert().Error(err)
+
+			_, err = ParseDocument([]byte(`{"schema_version":1,"operations":[{"params":{}}]}`), false)
+			s.Assert().Error(err)
+		})
+
+		t.WithNewStep("load pipeline", func(s provider.StepCtx) {
+			path := filepath.Join(tempDir, "calc.json")
+			s.Require().NoError(os.WriteFile(path, []byte(`[{"name":"calculate","params":{"type":"parser"}}]`), 0644))
+			p, err := LoadPipeline(path, nil)
+			s.Require().NoError(err)
+			out, err := p.Run(context.Background(), []byte("6 / 3"))
+			s.Require().NoError(err)
+			s.Assert().Equal("2", string(out))
+		})
+	})
+}
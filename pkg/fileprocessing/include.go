@@ -0,0 +1,261 @@
+package fileprocessing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dzibukalexander/file-processing/internal/logger"
+)
+
+// IsInclude reports whether operation name refers to another pipeline
+// ("include path=..." or "use name=...").
+func IsInclude(name string) bool {
+	return name == "include" || name == "use"
+}
+
+// includeParams are the params of an include or use operation that are not
+// passed to the referenced pipeline as variables.
+var includeParams = map[string]bool{"path": true, "name": true, "timeout": true}
+
+// ExpandIncludes loads the pipeline referenced by every include and use
+// operation in d and stores its steps as the operation's Steps. filePath is
+// the location of d, if any: relative include paths are resolved against its
+// directory and it is the first place searched for "use" names, followed by
+// dirs. The remaining params of an include are the variables of the included
+// pipeline; anything else is looked up through lookupEnv as usual.
+func (d *Document) ExpandIncludes(filePath string, dirs []string, lookupEnv func(string) (string, bool)) error {
+	return d.expandIncludes(&expander{dirs: dirs, lookupEnv: lookupEnv}, filePath)
+}
+
+// Resolved returns a copy of d with its variables resolved as by Resolve and
+// its include and use operations expanded as by ExpandIncludes, leaving d as
+// it is so it can still be saved with its ${NAME} references. Operations that
+// were already expanded are kept as they are.
+func (d *Document) Resolved(values map[string]string, lookupEnv func(string) (string, bool), filePath string, dirs []string) (*Document, error) {
+	resolved := *d
+	resolved.Operations = cloneOperations(d.Operations)
+	if err := resolved.Resolve(values, lookupEnv); err != nil {
+		return nil, err
+	}
+	e := &expander{dirs: dirs, lookupEnv: lookupEnv, keepExpanded: true}
+	if err := resolved.expandIncludes(e, filePath); err != nil {
+		return nil, err
+	}
+	return &resolved, nil
+}
+
+func (d *Document) expandIncludes(e *expander, filePath string) error {
+	baseDir := "."
+	if filePath != "" {
+		abs, err := filepath.Abs(filePath)
+		if err != nil {
+			return err
+		}
+		e.stack = []string{abs}
+		baseDir = filepath.Dir(abs)
+	}
+	return e.expand(d.Operations, baseDir)
+}
+
+// expander resolves include and use operations, tracking the chain of files
+// being expanded to detect cycles.
+type expander struct {
+	dirs      []string
+	lookupEnv func(string) (string, bool)
+	stack     []string
+
+	// keepExpanded leaves operations that already hold their steps alone.
+	keepExpanded bool
+}
+
+func (e *expander) expand(ops []*Operation, baseDir string) error {
+	for _, op := range ops {
+		if !IsInclude(op.Name) {
+			if err := e.expand(op.Steps, baseDir); err != nil {
+				return err
+			}
+			if err := e.expand(op.Else, baseDir); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if op.expanded && e.keepExpanded {
+			continue
+		}
+		path, err := e.locate(op, baseDir)
+		if err != nil {
+			return err
+		}
+		steps, err := e.load(op, path)
+		if err != nil {
+			return err
+		}
+		op.Steps = steps
+		op.expanded = true
+	}
+	return nil
+}
+
+// locate returns the absolute path of the pipeline an include or use operation refers to.
+func (e *expander) locate(op *Operation, baseDir string) (string, error) {
+	if op.Name == "include" {
+		path := op.Params["path"]
+		if path == "" {
+			return "", errors.New("include requires a path param")
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		return filepath.Abs(path)
+	}
+
+	name := op.Params["name"]
+	if name == "" {
+		return "", errors.New("use requires a name param")
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid pipeline name %q", name)
+	}
+	for _, dir := range append([]string{baseDir}, e.dirs...) {
+		for _, candidate := range pipelineFileNames(name) {
+			path := filepath.Join(dir, candidate)
+			if _, err := os.Stat(path); err == nil {
+				return filepath.Abs(path)
+			}
+		}
+	}
+	return "", fmt.Errorf("pipeline %q not found", name)
+}
+
+// load reads, resolves and recursively expands the pipeline at path.
+func (e *expander) load(op *Operation, path string) ([]*Operation, error) {
+	for i, seen := range e.stack {
+		if seen == path {
+			chain := append(append([]string{}, e.stack[i:]...), path)
+			for j := range chain {
+				chain[j] = filepath.Base(chain[j])
+			}
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	doc, err := ReadDocument(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", op.Name, path, err)
+	}
+	values := map[string]string{}
+	for key, value := range op.Params {
+		if !includeParams[key] {
+			values[key] = value
+		}
+	}
+	if err := checkIncludeVars(doc, values); err != nil {
+		return nil, fmt.Errorf("%s %s: %w", op.Name, path, err)
+	}
+	if err := doc.Resolve(values, e.lookupEnv); err != nil {
+		return nil, fmt.Errorf("%s %s: %w", op.Name, path, err)
+	}
+
+	e.stack = append(e.stack, path)
+	defer func() { e.stack = e.stack[:len(e.stack)-1] }()
+	if err := e.expand(doc.Operations, filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	logger.For("fileprocessing").WithFields(map[string]interface{}{
+		"path":  path,
+		"steps": len(doc.Operations),
+	}).Debug("Included pipeline expanded")
+	return doc.Operations, nil
+}
+
+// checkIncludeVars rejects params that the included pipeline neither declares
+// nor references, which are almost always typos.
+func checkIncludeVars(doc *Document, values map[string]string) error {
+	known := map[string]bool{}
+	for _, v := range doc.Variables {
+		known[v.Name] = true
+	}
+	for _, op := range allOperations(doc.Operations) {
+		for _, value := range op.Params {
+			for _, m := range variablePattern.FindAllStringSubmatch(value, -1) {
+				known[m[1]] = true
+			}
+		}
+	}
+
+	var unknown []string
+	for name := range values {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown pipeline variables: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// pipelineFileNames lists the file names a "use" name may be stored under.
+func pipelineFileNames(name string) []string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".yaml", ".yml":
+		return []string{name}
+	}
+	return []string{name + ".json", name + ".yaml", name + ".yml"}
+}
+
+// collapseIncludes returns ops with the expanded steps of include and use
+// operations removed, so saved pipelines keep the reference rather than a copy.
+func collapseIncludes(ops []*Operation) []*Operation {
+	if ops == nil {
+		return nil
+	}
+	out := make([]*Operation, len(ops))
+	for i, op := range ops {
+		clone := *op
+		if IsInclude(op.Name) {
+			clone.Steps = nil
+			clone.expanded = false
+		} else {
+			clone.Steps = collapseIncludes(op.Steps)
+			clone.Else = collapseIncludes(op.Else)
+		}
+		out[i] = &clone
+	}
+	return out
+}
+
+// cloneOperations deep-copies ops so their params can be changed freely.
+func cloneOperations(ops []*Operation) []*Operation {
+	if ops == nil {
+		return nil
+	}
+	out := make([]*Operation, len(ops))
+	for i, op := range ops {
+		clone := *op
+		clone.Params = maps.Clone(op.Params)
+		clone.Steps = cloneOperations(op.Steps)
+		clone.Else = cloneOperations(op.Else)
+		out[i] = &clone
+	}
+	return out
+}
+
+// includeStep runs the expanded steps of an include or use operation.
+func (p *Pipeline) includeStep(op *Operation) (step, error) {
+	if !op.expanded {
+		return nil, fmt.Errorf("%s was not expanded; load the pipeline with LoadPipeline", op.Name)
+	}
+	steps := p.branch(op.Steps)
+	return func(ctx context.Context, data []byte) ([]byte, error) {
+		return steps.Run(ctx, data)
+	}, nil
+}
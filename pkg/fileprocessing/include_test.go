@@ -0,0 +1,117 @@
+package fileprocessing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+)
+
+func TestIncludes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	write("shared/pack.yaml", `schema_version: 3
+variables:
+  - name: ALGO
+    default: gzip
+operations:
+  - name: compress
+    params: {type: "${ALGO}"}
+`)
+	write("shared/secure-archive.json", `{"schema_version": 3, "operations": [
+		{"name": "include", "params": {"path": "pack.yaml", "ALGO": "zip"}}
+	]}`)
+
+	runner.Run(t, "Pipeline includes", func(t provider.T) {
+		ctx := context.Background()
+
+		t.WithNewStep("include with params", func(s provider.StepCtx) {
+			path := write("main.json", `{"schema_version": 3, "operations": [
+				{"name": "calculate", "params": {"type": "library"}},
+				{"name": "include", "params": {"path": "shared/pack.yaml"}}
+			]}`)
+			p, err := LoadPipeline(path, nil)
+			s.Require().NoError(err)
+			out, err := p.Run(ctx, []byte("1 + 1"))
+			s.Require().NoError(err)
+			plain, err := NewPipeline().Decompress(Gzip).Run(ctx, out)
+			s.Require().NoError(err)
+			s.Assert().Equal("2", string(plain))
+
+			include := p.Operations()[1]
+			s.Require().Len(include.Steps, 1)
+			s.Assert().Equal("gzip", include.Steps[0].Params["type"])
+		})
+
+		t.WithNewStep("use by name and nested includes", func(s provider.StepCtx) {
+			path := write("shared/main.yaml", `schema_version: 3
+operations:
+  - name: use
+    params: {name: secure-archive}
+`)
+			p, err := LoadPipeline(path, nil)
+			s.Require().NoError(err)
+			steps := p.Operations()[0].Steps
+			s.Require().Len(steps, 1)
+			s.Require().Len(steps[0].Steps, 1)
+			s.Assert().Equal("zip", steps[0].Steps[0].Params["type"])
+
+			doc := &Document{Operations: []*Operation{NewOperation("use", map[string]string{"name": "secure-archive"})}}
+			s.Require().NoError(doc.ExpandIncludes("", []string{filepath.Join(dir, "shared")}, nil))
+			s.Assert().Len(doc.Operations[0].Steps, 1)
+		})
+
+		t.WithNewStep("saving keeps the reference", func(s provider.StepCtx) {
+			doc, err := ReadDocument(filepath.Join(dir, "shared", "secure-archive.json"))
+			s.Require().NoError(err)
+			s.Require().NoError(doc.ExpandIncludes(filepath.Join(dir, "shared", "secure-archive.json"), nil, nil))
+			out := filepath.Join(dir, "saved.json")
+			s.Require().NoError(doc.WriteFile(out))
+			saved, err := os.ReadFile(out)
+			s.Require().NoError(err)
+			s.Assert().NotContains(string(saved), "compress")
+			s.Assert().Len(doc.Operations[0].Steps, 1)
+		})
+
+		t.WithNewStep("cycles", func(s provider.StepCtx) {
+			write("a.json", `{"schema_version": 3, "operations": [{"name": "include", "params": {"path": "b.json"}}]}`)
+			b := write("b.json", `{"schema_version": 3, "operations": [{"name": "use", "params": {"name": "a"}}]}`)
+			_, err := LoadPipeline(b, nil)
+			s.Require().Error(err)
+			s.Assert().Contains(err.Error(), "include cycle: b.json -> a.json -> b.json")
+		})
+
+		t.WithNewStep("errors", func(s provider.StepCtx) {
+			for _, params := range []string{
+				`{"path": "missing.json"}`,
+				`{"path": "shared/pack.yaml", "ALGORITHM": "zip"}`,
+			} {
+				path := write("bad.json", `{"schema_version": 3, "operations": [{"name": "include", "params": `+params+`}]}`)
+				_, err := LoadPipeline(path, nil)
+				s.Assert().Error(err, params)
+			}
+
+			path := write("unknown.json", `{"schema_version": 3, "operations": [{"name": "use", "params": {"name": "nope"}}]}`)
+			_, err := LoadPipeline(path, nil)
+			s.Require().Error(err)
+			s.Assert().Contains(err.Error(), `"nope" not found`)
+
+			_, err = NewPipeline().Then("include", map[string]string{"path": "x.json"}).Run(ctx, []byte("data"))
+			s.Require().Error(err)
+			s.Assert().Contains(err.Error(), "not expanded")
+		})
+	})
+}
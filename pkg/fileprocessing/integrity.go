@@ -0,0 +1,255 @@
+package fileprocessing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dzibukalexander/file-processing/internal/integrity"
+	hash_const "github.com/dzibukalexander/file-processing/internal/integrity/constants"
+	"github.com/dzibukalexander/file-processing/internal/logger"
+)
+
+// ErrChecksumMismatch is returned by verify steps and VerifyChecksums when
+// data does not match its expected digest.
+var ErrChecksumMismatch = integrity.ErrMismatch
+
+// hashStep computes the digest of the data and writes it to a sidecar file
+// (sidecar=<path>), embeds it in front of the data (embed=true), or both.
+// The sidecar line names the file given by the name param, defaulting to the
+// sidecar path without its algorithm extension (out.gz.sha256 names out.gz).
+func hashStep(op *Operation) (step, error) {
+	algo, err := hashAlgorithm(op.Params["algo"], hash_const.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	sidecar := op.Params["sidecar"]
+	embed, err := boolParam(op.Params, "embed")
+	if err != nil {
+		return nil, err
+	}
+	if sidecar == "" && !embed {
+		return nil, errors.New("hash requires sidecar=<path> or embed=true")
+	}
+	name := op.Params["name"]
+	if name == "" {
+		name = "-"
+		if trimmed, ok := strings.CutSuffix(sidecar, algo.Extension()); ok && trimmed != "" {
+			name = filepath.Base(trimmed)
+		}
+	}
+	hasher := integrity.NewHasher(algo)
+
+	return func(ctx context.Context, data []byte) ([]byte, error) {
+		digest, err := hasher.Hash(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+		if sidecar != "" {
+			if err := os.WriteFile(sidecar, []byte(integrity.FormatSum(digest, name)), 0644); err != nil {
+				return nil, fmt.Errorf("failed to write checksum file: %w", err)
+			}
+			logger.For("fileprocessing").WithField("path", sidecar).Info("Checksum written")
+		}
+		if embed {
+			return integrity.Embed(algo, digest, data), nil
+		}
+		return data, nil
+	}, nil
+}
+
+// verifyStep checks the data against exactly one of a sidecar file
+// (sidecar=<path>, optionally picking the line for name=<file>), an embedded
+// digest (embed=true, which is stripped) or a literal digest=<hex>. The
+// algorithm is taken from algo=, the embedded header or the sidecar's
+// extension, in that order, and defaults to sha256.
+func verifyStep(op *Operation) (step, error) {
+	params := op.Params
+	embed, err := boolParam(params, "embed")
+	if err != nil {
+		return nil, err
+	}
+	sources := 0
+	for _, set := range []bool{params["sidecar"] != "", embed, params["digest"] != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, errors.New("verify requires exactly one of sidecar=<path>, embed=true or digest=<hex>")
+	}
+
+	fallback := hash_const.SHA256
+	if algo, ok := hash_const.HashAlgorithmFromPath(params["sidecar"]); ok {
+		fallback = algo
+	}
+	algo, err := hashAlgorithm(params["algo"], fallback)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, data []byte) ([]byte, error) {
+		var expected []byte
+		var err error
+		payload, algo := data, algo
+		switch {
+		case embed:
+			var embedded hash_const.HashAlgorithm
+			embedded, expected, payload, err = integrity.Extract(data)
+			if err != nil {
+				return nil, err
+			}
+			if params["algo"] != "" && embedded != algo {
+				return nil, fmt.Errorf("embedded checksum uses %s, not %s", strings.ToLower(string(embedded)), params["algo"])
+			}
+			algo = embedded
+		case params["digest"] != "":
+			expected, err = hex.DecodeString(params["digest"])
+			if err != nil {
+				return nil, fmt.Errorf("invalid digest: %w", err)
+			}
+		default:
+			expected, err = readSidecar(params["sidecar"], params["name"])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		actual, err := integrity.NewHasher(algo).Hash(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(actual, expected) {
+			return nil, fmt.Errorf("%w: expected %x, got %x (%s)", ErrChecksumMismatch, expected, actual, strings.ToLower(string(algo)))
+		}
+		logger.For("fileprocessing").WithField("algo", algo).Info("Checksum verified")
+		return payload, nil
+	}, nil
+}
+
+// readSidecar returns the digest for name from a checksum file, or the only
+// digest in it if name is empty.
+func readSidecar(path, name string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksum file: %w", err)
+	}
+	sums, err := integrity.ParseSums(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if name == "" {
+		if len(sums) != 1 {
+			return nil, fmt.Errorf("%s has %d checksums; choose one with name=<file>", path, len(sums))
+		}
+		return sums[0].Digest, nil
+	}
+	for _, sum := range sums {
+		if sum.Name == name {
+			return sum.Digest, nil
+		}
+	}
+	return nil, fmt.Errorf("%s has no checksum for %s", path, name)
+}
+
+// ChecksumResult is the outcome of checking one line of a checksum file.
+type ChecksumResult struct {
+	Name string
+	Err  error
+}
+
+// OK reports whether the file matched its checksum.
+func (r ChecksumResult) OK() bool {
+	return r.Err == nil
+}
+
+// Checksum returns the digest of the file at path as a line in sha256sum
+// format.
+func Checksum(ctx context.Context, h HashAlgorithm, path string) (string, error) {
+	algo, err := hashAlgorithm(string(h), hash_const.SHA256)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	digest, err := integrity.NewHasher(algo).Hash(ctx, data)
+	if err != nil {
+		return "", err
+	}
+	return integrity.FormatSum(digest, path), nil
+}
+
+// VerifyChecksums checks every file listed in a checksum file such as one
+// written by sha256sum or Checksum. File names are relative to the working
+// directory. The algorithm is h if set, otherwise it is inferred from the
+// checksum file's extension and defaults to sha256.
+func VerifyChecksums(ctx context.Context, h HashAlgorithm, sumsPath string) ([]ChecksumResult, error) {
+	fallback := hash_const.SHA256
+	if algo, ok := hash_const.HashAlgorithmFromPath(sumsPath); ok {
+		fallback = algo
+	}
+	algo, err := hashAlgorithm(string(h), fallback)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(sumsPath)
+	if err != nil {
+		return nil, err
+	}
+	sums, err := integrity.ParseSums(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", sumsPath, err)
+	}
+
+	hasher := integrity.NewHasher(algo)
+	results := make([]ChecksumResult, len(sums))
+	for i, sum := range sums {
+		results[i].Name = sum.Name
+		content, err := os.ReadFile(sum.Name)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		actual, err := hasher.Hash(ctx, content)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(actual, sum.Digest) {
+			results[i].Err = ErrChecksumMismatch
+		}
+	}
+	return results, nil
+}
+
+// hashAlgorithm parses an algo param, using fallback when it is empty.
+func hashAlgorithm(value string, fallback hash_const.HashAlgorithm) (hash_const.HashAlgorithm, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	algo, err := hash_const.HashAlgorithmFromString(strings.ToUpper(value))
+	if err != nil || algo == hash_const.NONE {
+		return hash_const.NONE, fmt.Errorf("unsupported hash algorithm: %s", value)
+	}
+	return algo, nil
+}
+
+// boolParam parses an optional boolean param; a missing param is false.
+func boolParam(params map[string]string, key string) (bool, error) {
+	value, ok := params[key]
+	if !ok || value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %s", key, value)
+	}
+	return b, nil
+}
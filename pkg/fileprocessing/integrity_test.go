@@ -0,0 +1,93 @@
+package fileprocessing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+)
+
+func TestPipeline_Integrity(t *testing.T) {
+	runner.Run(t, "Hash and verify steps", func(t provider.T) {
+		ctx := context.Background()
+
+		t.WithNewStep("sidecar roundtrip", func(s provider.StepCtx) {
+			sidecar := filepath.Join(t.TempDir(), "out.gz.sha512")
+			packed, err := NewPipeline().Compress(Gzip).Hash(SHA512, sidecar).Run(ctx, []byte("data"))
+			s.Require().NoError(err)
+			written, err := os.ReadFile(sidecar)
+			s.Require().NoError(err)
+			s.Assert().Regexp(`^[0-9a-f]{128}  out\.gz\n$`, string(written))
+
+			out, err := NewPipeline().Verify(sidecar).Decompress(Gzip).Run(ctx, packed)
+			s.Require().NoError(err)
+			s.Assert().Equal("data", string(out))
+
+			packed[len(packed)-1] ^= 1
+			_, err = NewPipeline().Verify(sidecar).Run(ctx, packed)
+			s.Assert().ErrorIs(err, ErrChecksumMismatch)
+		})
+
+		t.WithNewStep("embedded digest", func(s provider.StepCtx) {
+			embed := map[string]string{"embed": "true", "algo": "blake2b"}
+			out, err := NewPipeline().Then("hash", embed).Run(ctx, []byte("data"))
+			s.Require().NoError(err)
+			s.Assert().NotEqual("data", string(out))
+
+			plain, err := NewPipeline().Then("verify", map[string]string{"embed": "true"}).Run(ctx, out)
+			s.Require().NoError(err)
+			s.Assert().Equal("data", string(plain))
+
+			_, err = NewPipeline().Then("verify", map[string]string{"embed": "true", "algo": "sha256"}).Run(ctx, out)
+			s.Assert().Error(err)
+			out[len(out)-1] ^= 1
+			_, err = NewPipeline().Then("verify", map[string]string{"embed": "true"}).Run(ctx, out)
+			s.Assert().ErrorIs(err, ErrChecksumMismatch)
+		})
+
+		t.WithNewStep("literal digest", func(s provider.StepCtx) {
+			_, err := NewPipeline().Then("verify", map[string]string{"algo": "crc32", "digest": "352441c2"}).Run(ctx, []byte("abc"))
+			s.Assert().NoError(err)
+			_, err = NewPipeline().Then("verify", map[string]string{"digest": "352441c2"}).Run(ctx, []byte("abc"))
+			s.Assert().ErrorIs(err, ErrChecksumMismatch)
+		})
+
+		t.WithNewStep("invalid params", func(s provider.StepCtx) {
+			for _, op := range []*Operation{
+				NewOperation("hash", nil),
+				NewOperation("hash", map[string]string{"algo": "md5", "embed": "true"}),
+				NewOperation("hash", map[string]string{"embed": "maybe"}),
+				NewOperation("verify", nil),
+				NewOperation("verify", map[string]string{"embed": "true", "digest": "00"}),
+				NewOperation("verify", map[string]string{"digest": "zz"}),
+			} {
+				_, err := FromOperations([]*Operation{op}).Run(ctx, []byte("abc"))
+				s.Assert().Error(err, op.Params)
+			}
+			s.Assert().Error(NewPipeline().Hash(HashAlgorithm("md5"), "x").Err())
+		})
+
+		t.WithNewStep("checksum files", func(s provider.StepCtx) {
+			t.Chdir(t.TempDir())
+			s.Require().NoError(os.WriteFile("a.txt", []byte("abc"), 0644))
+			s.Require().NoError(os.WriteFile("b.txt", []byte("def"), 0644))
+			lineA, err := Checksum(ctx, "", "a.txt")
+			s.Require().NoError(err)
+			s.Assert().Equal("ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad  a.txt\n", lineA)
+			lineB, err := Checksum(ctx, SHA256, "b.txt")
+			s.Require().NoError(err)
+			s.Require().NoError(os.WriteFile("SUMS.sha256", []byte(lineA+lineB+"00  missing.txt\n"), 0644))
+			s.Require().NoError(os.WriteFile("b.txt", []byte("changed"), 0644))
+
+			results, err := VerifyChecksums(ctx, "", "SUMS.sha256")
+			s.Require().NoError(err)
+			s.Require().Len(results, 3)
+			s.Assert().True(results[0].OK())
+			s.Assert().ErrorIs(results[1].Err, ErrChecksumMismatch)
+			s.Assert().ErrorIs(results[2].Err, os.ErrNotExist)
+		})
+	})
+}
@@ -0,0 +1,75 @@
+package fileprocessing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dzibukalexander/file-processing/internal/encryption"
+	enc_const "github.com/dzibukalexander/file-processing/internal/encryption/constants"
+)
+
+// interopStep encrypts or decrypts in the age or OpenPGP format. The key
+// comes from key_file (or memory) or, when encrypting, from the files in
+// recipients=. With passphrase=true the key is a passphrase. armor=true
+// writes ASCII-armored output; armored input is detected when decrypting.
+// key_passphrase_file= unlocks a passphrase-protected OpenPGP private key.
+func interopStep(op *Operation, encType enc_const.EncryptionType, decrypt bool) (step, error) {
+	params := op.Params
+	usePassphrase, err := boolParam(params, "passphrase")
+	if err != nil {
+		return nil, err
+	}
+	armor, err := boolParam(params, "armor")
+	if err != nil {
+		return nil, err
+	}
+	if decrypt && params["armor"] != "" {
+		return nil, fmt.Errorf("armor only applies to encrypt; armored input is detected")
+	}
+	if _, ok := params["key_passphrase_file"]; ok && (encType != enc_const.PGP || !decrypt) {
+		return nil, fmt.Errorf("key_passphrase_file only applies to decrypt with %s, %s, %s, %s or %s keys", RSA, Multi, X25519, P256, PGP)
+	}
+
+	var key []byte
+	if recipients, ok := params["recipients"]; ok {
+		if decrypt || usePassphrase {
+			return nil, fmt.Errorf("recipients only applies to encrypt with public keys")
+		}
+		key, err = readRecipients(recipients)
+	} else {
+		key, err = op.readKey()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if decrypt {
+		var decryptor encryption.Decryptor
+		if encType == enc_const.AGE {
+			decryptor = encryption.NewAgeDecryptor(usePassphrase)
+		} else {
+			keyPassphrase, err := op.readKeyPassphrase(encType)
+			if err != nil {
+				return nil, err
+			}
+			decryptor = encryption.NewPGPDecryptor(usePassphrase, keyPassphrase)
+		}
+		return func(ctx context.Context, data []byte) ([]byte, error) {
+			return decryptor.Decrypt(ctx, data, key)
+		}, nil
+	}
+
+	encryptor := encryption.NewAgeEncryptor(usePassphrase, armor)
+	if encType == enc_const.PGP {
+		encryptor = encryption.NewPGPEncryptor(usePassphrase, armor)
+	}
+	return func(ctx context.Context, data []byte) ([]byte, error) {
+		return encryptor.Encrypt(ctx, data, key)
+	}, nil
+}
+
+// isInteropType reports whether an encryption type param names age or pgp.
+func isInteropType(typ string) bool {
+	return strings.EqualFold(typ, string(Age)) || strings.EqualFold(typ, string(PGP))
+}
@@ -0,0 +1,113 @@
+package fileprocessing
+
+import (
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dzibukalexander/file-processing/internal/encryption/aes"
+	"github.com/dzibukalexander/file-processing/internal/encryption/age"
+	"github.com/dzibukalexander/file-processing/internal/encryption/chacha20"
+	enc_const "github.com/dzibukalexander/file-processing/internal/encryption/constants"
+	"github.com/dzibukalexander/file-processing/internal/encryption/ctrhmac"
+	"github.com/dzibukalexander/file-processing/internal/encryption/ecies"
+	"github.com/dzibukalexander/file-processing/internal/encryption/multi"
+	"github.com/dzibukalexander/file-processing/internal/encryption/pgp"
+	"github.com/dzibukalexander/file-processing/internal/encryption/rsa"
+	"github.com/dzibukalexander/file-processing/internal/encryption/siv"
+	"github.com/dzibukalexander/file-processing/internal/keyfile"
+	"github.com/dzibukalexander/file-processing/internal/signature"
+	sig_const "github.com/dzibukalexander/file-processing/internal/signature/constants"
+)
+
+var (
+	// ErrWrongKey is returned when decrypting AES data with a key other than
+	// the one recorded in its header.
+	ErrWrongKey = enc_const.ErrWrongKey
+	// ErrCorruptedData is returned when encrypted data fails authentication.
+	ErrCorruptedData = enc_const.ErrCorruptedData
+	// ErrPassphraseRequired is returned for an encrypted private key used
+	// without its passphrase (the key_passphrase_file param).
+	ErrPassphraseRequired = keyfile.ErrPassphraseRequired
+	// ErrWrongPassphrase is returned when a passphrase does not unlock a
+	// private key.
+	ErrWrongPassphrase = keyfile.ErrWrongPassphrase
+)
+
+// KeyFingerprint returns the key ID that AES ciphertexts record for key, as
+// shown in ErrWrongKey messages.
+func KeyFingerprint(key []byte) string {
+	return hex.EncodeToString(aes.Fingerprint(key))
+}
+
+// GenerateKey creates a new key for e at path. Symmetric keys are written as
+// raw bytes; RSA key pairs are written as path.pub and path.priv PEM files
+// (3072 bits, unencrypted PKCS#8; see GenerateRSAKey).
+// Age identities are written to path with the recipient in path.pub, and
+// OpenPGP keys to path.priv and path.pub, armored. X25519 and P256 key pairs
+// are written as PKCS#8 path.priv and PKIX path.pub; X25519 keys also work
+// as Multi recipients.
+func GenerateKey(e Encryption, path string) error {
+	switch e {
+	case AES, AESGCMStream:
+		generator := aes.AESEncryptor{}
+		return generator.GenerateKey(path)
+	case RSA:
+		generator := rsa.RSAEncryptor{}
+		return generator.GenerateKey(path)
+	case ChaCha20Poly1305, XChaCha20Poly1305:
+		generator := chacha20.ChaCha20Encryptor{}
+		return generator.GenerateKey(path)
+	case AESSIV:
+		generator := siv.AESSIVEncryptor{}
+		return generator.GenerateKey(path)
+	case AESCTRHMAC:
+		generator := ctrhmac.AESCTRHMACEncryptor{}
+		return generator.GenerateKey(path)
+	case Age:
+		generator := age.AgeEncryptor{}
+		return generator.GenerateKey(path)
+	case PGP:
+		generator := pgp.PGPEncryptor{}
+		return generator.GenerateKey(path, filepath.Base(path))
+	case X25519:
+		generator := ecies.ECIESEncryptor{Curve: ecies.X25519}
+		return generator.GenerateKey(path)
+	case P256:
+		generator := ecies.ECIESEncryptor{Curve: ecies.P256}
+		return generator.GenerateKey(path)
+	case Multi:
+		return fmt.Errorf("%s encrypts for RSA or X25519 key pairs; generate those instead", Multi)
+	default:
+		_, err := ParseEncryption(string(e))
+		return err
+	}
+}
+
+// GenerateRSAKey writes an RSA key pair of bits (3072 or 4096) as path.pub
+// and path.priv, a PKCS#8 file readable only by the owner. Unless passphrase
+// is empty the private key is encrypted with it, and decrypting then needs
+// key_passphrase_file.
+func GenerateRSAKey(path string, bits int, passphrase []byte) error {
+	return rsa.GenerateKeyPair(path, bits, passphrase)
+}
+
+// GenerateSigningKey creates a key pair for s, written as path.priv (PKCS#8
+// PEM, mode 0600) and path.pub (PKIX PEM).
+func GenerateSigningKey(s Signature, path string) error {
+	if _, err := ParseSignature(string(s)); err != nil {
+		return err
+	}
+	sigType, err := sig_const.SignatureTypeFromString(strings.ToUpper(string(s)))
+	if err != nil {
+		return err
+	}
+	return signature.NewKeyGenerator(sigType).GenerateKey(path)
+}
+
+// GenerateX25519Key writes an X25519 key pair as path.priv and path.pub PEM
+// files, for use with Multi encryption alongside RSA key pairs.
+func GenerateX25519Key(path string) error {
+	return multi.GenerateX25519Key(path)
+}
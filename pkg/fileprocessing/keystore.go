@@ -0,0 +1,72 @@
+package fileprocessing
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/dzibukalexander/file-processing/internal/keyring"
+)
+
+// KeyStore looks up keys referred to by name with a key=<name> param. It
+// returns the key's algorithm (an Encryption or Signature name) and the
+// public key if public is set and the key has one, otherwise the secret.
+type KeyStore interface {
+	LoadKey(name string, public bool) (algorithm string, key []byte, err error)
+}
+
+// WithKeyStore resolves key=<name> params from ks instead of the user's
+// keyring in $XDG_CONFIG_HOME/file-processing/keys.
+func WithKeyStore(ks KeyStore) Option {
+	return func(o *options) { o.keyStore = ks }
+}
+
+// usesPublicKey lists the operations that take a key param and whether they
+// need the public half of a key pair.
+var usesPublicKey = map[string]bool{
+	"encrypt":          true,
+	"verify-signature": true,
+	"decrypt":          false,
+	"sign":             false,
+}
+
+// resolveKey returns a copy of op holding the key named by its key param, in
+// keys, with the type param defaulting to the key's algorithm. Operations
+// without a key param are returned as they are.
+func (p *Pipeline) resolveKey(op *Operation, keys *secrets) (*Operation, error) {
+	name := op.Params["key"]
+	public, usesKey := usesPublicKey[op.Name]
+	if name == "" || !usesKey || op.key != nil {
+		return op, nil
+	}
+	if op.Params["key_file"] != "" {
+		return nil, fmt.Errorf("%s: use either key or key_file, not both", op.Name)
+	}
+
+	ks := p.opts.keyStore
+	if ks == nil {
+		kr, err := keyring.Default()
+		if err != nil {
+			return nil, fmt.Errorf("no keyring is available: %w", err)
+		}
+		ks = kr
+	}
+	// The built-in keyring reads a fresh copy of the key for every call, so
+	// that copy is wiped; other stores may hand out keys they keep.
+	_, owned := ks.(*keyring.Keyring)
+	algorithm, key, err := ks.LoadKey(name, public)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := *op
+	resolved.Params = maps.Clone(op.Params)
+	if resolved.Params["type"] == "" {
+		resolved.Params["type"] = algorithm
+	}
+	if owned {
+		resolved.key = keys.take(key)
+	} else {
+		resolved.key = keys.hold(key)
+	}
+	return &resolved, nil
+}
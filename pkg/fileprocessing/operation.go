@@ -0,0 +1,32 @@
+package fileprocessing
+
+// Operation defines a single, serializable processing step.
+type Operation struct {
+	Name   string            `json:"name" yaml:"name"`
+	Params map[string]string `json:"params" yaml:"params"`
+
+	// Steps is the sub-pipeline of a control-flow operation: the branch taken
+	// by "if", the attempt made by "try" or the branch written by "tee". For
+	// "include" and "use" it holds the referenced pipeline once expanded.
+	Steps []*Operation `json:"steps,omitempty" yaml:"steps,omitempty"`
+	// Else runs when an "if" predicate is false or a "try" attempt fails.
+	Else []*Operation `json:"else,omitempty" yaml:"else,omitempty"`
+
+	// key holds key material supplied in memory through the fluent builder.
+	// It is never serialized; saved pipelines refer to keys by key_file.
+	key []byte
+	// expanded is set once an include or use operation has been resolved
+	// by ExpandIncludes.
+	expanded bool
+	// secrets holds the key material read for the step being created, which
+	// is wiped once it has run.
+	secrets *secrets
+}
+
+// NewOperation creates an operation with the given name and parameters.
+func NewOperation(name string, params map[string]string) *Operation {
+	if params == nil {
+		params = map[string]string{}
+	}
+	return &Operation{Name: name, Params: params}
+}
@@ -0,0 +1,102 @@
+package fileprocessing
+
+import (
+	"maps"
+	"time"
+
+	"github.com/dzibukalexander/file-processing/internal/fileio/writer"
+)
+
+// Option configures a Pipeline.
+type Option func(*options)
+
+type options struct {
+	output      writer.Options
+	format      Format
+	stepTimeout time.Duration
+	stepHook    StepHook
+	keyStore    KeyStore
+	defaults    map[string]map[string]string
+}
+
+// StepHook is called with the output of each step after it succeeds.
+// index is zero-based. Returning an error aborts the pipeline. output is
+// wiped once the next step has run, so hooks that keep it must copy it.
+type StepHook func(index int, op *Operation, output []byte) error
+
+// WithIndent indents structured (JSON, YAML, XML) output by n spaces.
+func WithIndent(n int) Option {
+	return func(o *options) { o.output.Indent = n }
+}
+
+// WithSortedKeys sorts object keys (JSON, YAML) or attributes (XML) in structured output.
+func WithSortedKeys() Option {
+	return func(o *options) { o.output.SortKeys = true }
+}
+
+// WithCanonicalJSON writes JSON output in RFC 8785 canonical form.
+func WithCanonicalJSON() Option {
+	return func(o *options) { o.output.Canonical = true }
+}
+
+// WithCompactOutput strips insignificant whitespace from structured output.
+func WithCompactOutput() Option {
+	return func(o *options) { o.output.Compact = true }
+}
+
+// WithOutputFormat sets the format used when writing to a stream, where there
+// is no file extension to infer it from.
+func WithOutputFormat(f Format) Option {
+	return func(o *options) { o.format = f }
+}
+
+// WithStepTimeout bounds every step that has no timeout param of its own.
+func WithStepTimeout(d time.Duration) Option {
+	return func(o *options) { o.stepTimeout = d }
+}
+
+// WithStepHook observes the intermediate output of every step, e.g. to dump
+// it for debugging.
+func WithStepHook(hook StepHook) Option {
+	return func(o *options) { o.stepHook = hook }
+}
+
+// WithDefaultParams sets params for operations named name that leave them
+// unset, e.g. WithDefaultParams("compress", map[string]string{"level": "9"}).
+func WithDefaultParams(name string, params map[string]string) Option {
+	return func(o *options) {
+		if o.defaults == nil {
+			o.defaults = map[string]map[string]string{}
+		}
+		merged := maps.Clone(o.defaults[name])
+		if merged == nil {
+			merged = map[string]string{}
+		}
+		maps.Copy(merged, params)
+		o.defaults[name] = merged
+	}
+}
+
+// withDefaults returns op with the params set by WithDefaultParams filled in,
+// copying it only if any are missing.
+func (p *Pipeline) withDefaults(op *Operation) *Operation {
+	var resolved *Operation
+	for k, v := range p.opts.defaults[op.Name] {
+		if _, ok := op.Params[k]; ok {
+			continue
+		}
+		if resolved == nil {
+			copied := *op
+			copied.Params = maps.Clone(op.Params)
+			if copied.Params == nil {
+				copied.Params = map[string]string{}
+			}
+			resolved = &copied
+		}
+		resolved.Params[k] = v
+	}
+	if resolved == nil {
+		return op
+	}
+	return resolved
+}
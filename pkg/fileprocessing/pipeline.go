@@ -0,0 +1,339 @@
+package fileprocessing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	comp_const "github.com/dzibukalexander/file-processing/internal/compression/constants"
+	"github.com/dzibukalexander/file-processing/internal/fileio"
+	"github.com/dzibukalexander/file-processing/internal/fileio/constants"
+	"github.com/dzibukalexander/file-processing/internal/logger"
+	"github.com/dzibukalexander/file-processing/internal/secmem"
+)
+
+// StdStream is the path that refers to standard input or standard output.
+const StdStream = "-"
+
+// Pipeline is an ordered list of processing steps.
+type Pipeline struct {
+	operations []*Operation
+	opts       options
+	err        error
+}
+
+// NewPipeline creates an empty pipeline.
+func NewPipeline(opts ...Option) *Pipeline {
+	p := &Pipeline{}
+	for _, opt := range opts {
+		opt(&p.opts)
+	}
+	return p
+}
+
+// FromOperations creates a pipeline that runs the given operations in order.
+func FromOperations(ops []*Operation, opts ...Option) *Pipeline {
+	p := NewPipeline(opts...)
+	p.operations = append(p.operations, ops...)
+	return p
+}
+
+// Then appends an operation by name, e.g. Then("encrypt", map[string]string{"type": "aes", "key_file": "k.bin"}).
+func (p *Pipeline) Then(name string, params map[string]string) *Pipeline {
+	p.operations = append(p.operations, NewOperation(name, params))
+	return p
+}
+
+// Compress appends a compression step.
+func (p *Pipeline) Compress(c Compression) *Pipeline {
+	p.checkCompression(c)
+	return p.Then("compress", map[string]string{"type": string(c)})
+}
+
+// Decompress appends a decompression step.
+func (p *Pipeline) Decompress(c Compression) *Pipeline {
+	p.checkCompression(c)
+	return p.Then("decompress", map[string]string{"type": string(c)})
+}
+
+// Encrypt appends an encryption step using key held in memory.
+func (p *Pipeline) Encrypt(e Encryption, key []byte) *Pipeline {
+	_, err := ParseEncryption(string(e))
+	return p.withKey("encrypt", string(e), key, err)
+}
+
+// Decrypt appends a decryption step using key held in memory.
+func (p *Pipeline) Decrypt(e Encryption, key []byte) *Pipeline {
+	_, err := ParseEncryption(string(e))
+	return p.withKey("decrypt", string(e), key, err)
+}
+
+// EncryptFor appends a Multi encryption step for the given PEM public keys
+// (RSA or X25519). Any one of the matching private keys can decrypt with
+// Decrypt(Multi, privateKey).
+func (p *Pipeline) EncryptFor(recipients ...[]byte) *Pipeline {
+	var err error
+	if len(recipients) == 0 {
+		err = errors.New("EncryptFor requires at least one recipient")
+	}
+	return p.withKey("encrypt", string(Multi), bytes.Join(recipients, []byte("\n")), err)
+}
+
+// EncryptWithPassphrase appends an Age or PGP encryption step that uses a
+// passphrase instead of public keys.
+func (p *Pipeline) EncryptWithPassphrase(e Encryption, passphrase []byte) *Pipeline {
+	p.withKey("encrypt", string(e), passphrase, checkPassphraseEncryption(e))
+	p.operations[len(p.operations)-1].Params["passphrase"] = "true"
+	return p
+}
+
+// DecryptWithPassphrase appends an Age or PGP decryption step for data
+// encrypted by EncryptWithPassphrase.
+func (p *Pipeline) DecryptWithPassphrase(e Encryption, passphrase []byte) *Pipeline {
+	p.withKey("decrypt", string(e), passphrase, checkPassphraseEncryption(e))
+	p.operations[len(p.operations)-1].Params["passphrase"] = "true"
+	return p
+}
+
+func checkPassphraseEncryption(e Encryption) error {
+	if e != Age && e != PGP {
+		return fmt.Errorf("%s does not support passphrases", e)
+	}
+	return nil
+}
+
+// EncryptWithAAD appends an AES encryption step that also authenticates aad,
+// such as a file name, so the ciphertext only decrypts in that context.
+func (p *Pipeline) EncryptWithAAD(key, aad []byte) *Pipeline {
+	p.withKey("encrypt", string(AES), key, nil)
+	p.operations[len(p.operations)-1].Params["aad"] = string(aad)
+	return p
+}
+
+// DecryptWithAAD appends an AES decryption step for data encrypted by
+// EncryptWithAAD with the same aad.
+func (p *Pipeline) DecryptWithAAD(key, aad []byte) *Pipeline {
+	p.withKey("decrypt", string(AES), key, nil)
+	p.operations[len(p.operations)-1].Params["aad"] = string(aad)
+	return p
+}
+
+// Sign appends a step that prefixes the data with its signature, made with
+// a PEM private key held in memory.
+func (p *Pipeline) Sign(s Signature, privateKey []byte) *Pipeline {
+	_, err := ParseSignature(string(s))
+	return p.withKey("sign", string(s), privateKey, err)
+}
+
+// VerifySignature appends a step that checks and removes a signature added
+// by Sign, using a PEM public key held in memory.
+func (p *Pipeline) VerifySignature(s Signature, publicKey []byte) *Pipeline {
+	_, err := ParseSignature(string(s))
+	return p.withKey("verify-signature", string(s), publicKey, err)
+}
+
+// Calculate appends a step that evaluates arithmetic expressions in the data.
+func (p *Pipeline) Calculate(m CalculationMethod) *Pipeline {
+	switch m {
+	case Regex, Parser, Library:
+	default:
+		p.setErr(fmt.Errorf("unknown calculation method: %s", m))
+	}
+	return p.Then("calculate", map[string]string{"type": string(m)})
+}
+
+// Hash appends a step that writes the digest of the data to a sidecar file
+// in sha256sum format and passes the data on unchanged.
+func (p *Pipeline) Hash(h HashAlgorithm, sidecar string) *Pipeline {
+	if _, err := ParseHashAlgorithm(string(h)); err != nil {
+		p.setErr(err)
+	}
+	return p.Then("hash", map[string]string{"algo": string(h), "sidecar": sidecar})
+}
+
+// Verify appends a step that fails unless the data matches the digest in a
+// sidecar file. The algorithm is taken from the sidecar's extension.
+func (p *Pipeline) Verify(sidecar string) *Pipeline {
+	return p.Then("verify", map[string]string{"sidecar": sidecar})
+}
+
+// Operations returns the steps of the pipeline.
+func (p *Pipeline) Operations() []*Operation {
+	return append([]*Operation(nil), p.operations...)
+}
+
+// Err returns the first error recorded while building the pipeline.
+func (p *Pipeline) Err() error {
+	return p.err
+}
+
+// Run executes every step over data and returns the result. Cancelling ctx
+// aborts the step in progress; a step whose timeout param (or the
+// WithStepTimeout default) elapses fails with context.DeadlineExceeded.
+func (p *Pipeline) Run(ctx context.Context, data []byte) ([]byte, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	if err := validateOperations(p.operations, ""); err != nil {
+		return nil, err
+	}
+	log := logger.For("fileprocessing")
+	var err error
+	input := data
+
+	for i, op := range p.operations {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		log.WithFields(map[string]interface{}{
+			"step":      i + 1,
+			"operation": op.Name,
+			"params":    op.Params,
+		}).Debug("Executing pipeline step")
+		step, err_step := p.createStep(op)
+		if err_step != nil {
+			log.Errorf("Error creating step %d (%s): %v", i+1, op.Name, err_step)
+			return nil, err_step
+		}
+		prev := data
+		data, err = p.runStep(ctx, op, step, data)
+		if err != nil {
+			log.Errorf("Error processing step %d (%s): %v", i+1, op.Name, err)
+			return nil, fmt.Errorf("error processing step '%s': %w", op.Name, err)
+		}
+		// Intermediate results may be decrypted data; wipe each one once the
+		// next step has replaced it, unless it is still in use as the
+		// caller's input or part of the new result.
+		if !secmem.Overlaps(prev, input) && !secmem.Overlaps(prev, data) {
+			secmem.Wipe(prev)
+		}
+		if p.opts.stepHook != nil {
+			if err := p.opts.stepHook(i, op, data); err != nil {
+				return nil, fmt.Errorf("step hook failed after step '%s': %w", op.Name, err)
+			}
+		}
+	}
+	return data, nil
+}
+
+// runStep executes a single step, bounded by its timeout if one is set.
+func (p *Pipeline) runStep(ctx context.Context, op *Operation, s step, data []byte) ([]byte, error) {
+	timeout := p.opts.stepTimeout
+	if v, ok := op.Params["timeout"]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid timeout: %s", v)
+		}
+		timeout = d
+	}
+	if timeout == 0 {
+		return s(ctx, data)
+	}
+
+	stepCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, err := s(stepCtx, data)
+	if err != nil && ctx.Err() == nil && errors.Is(stepCtx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("timed out after %s: %w", timeout, context.DeadlineExceeded)
+	}
+	return result, err
+}
+
+// Process reads all of r, runs the pipeline and writes the result to w,
+// formatted according to WithOutputFormat and the output options.
+func (p *Pipeline) Process(ctx context.Context, r io.Reader, w io.Writer) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	result, err := p.Run(ctx, data)
+	if err != nil {
+		return err
+	}
+	return p.writeTo(w, result)
+}
+
+// RunFile reads inputPath, runs the pipeline and writes the result to
+// outputPath. File types are inferred from the extensions and "-" refers to
+// standard input or output.
+func (p *Pipeline) RunFile(ctx context.Context, inputPath, outputPath string) error {
+	var data []byte
+	var err error
+	if inputPath == StdStream {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = readFile(inputPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+
+	result, err := p.Run(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	if outputPath == StdStream {
+		return p.writeTo(os.Stdout, result)
+	}
+	outputType, _ := constants.FileTypeFromExtension(outputPath)
+	if err := fileio.NewWriterWithOptions(outputType, p.opts.output).Write(outputPath, result); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// writeTo formats result according to the pipeline options and writes it to w.
+func (p *Pipeline) writeTo(w io.Writer, result []byte) error {
+	format := p.opts.format
+	if format == "" {
+		format = Text
+	}
+	fileType, err := constants.FileTypeFromString(strings.ToUpper(string(format)))
+	if err != nil {
+		return err
+	}
+	result, err = fileio.Format(fileType, p.opts.output, result)
+	if err != nil {
+		return fmt.Errorf("failed to format output: %w", err)
+	}
+	if _, err := w.Write(result); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+func (p *Pipeline) withKey(name, typ string, key []byte, err error) *Pipeline {
+	if err != nil {
+		p.setErr(err)
+	}
+	op := NewOperation(name, map[string]string{"type": typ})
+	op.key = key
+	p.operations = append(p.operations, op)
+	return p
+}
+
+func (p *Pipeline) checkCompression(c Compression) {
+	if _, err := comp_const.CompressionTypeFromString(strings.ToUpper(string(c))); err != nil {
+		p.setErr(err)
+	}
+}
+
+func (p *Pipeline) setErr(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+func readFile(filePath string) ([]byte, error) {
+	fileType, err := constants.FileTypeFromExtension(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine file type: %w", err)
+	}
+	return fileio.NewFileReader(fileType).Read(filePath)
+}
@@ -0,0 +1,315 @@
+package fileprocessing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+)
+
+func TestPipeline_Run(t *testing.T) {
+	runner.Run(t, "Pipeline fluent builder", func(t provider.T) {
+		t.WithNewStep("roundtrip", func(s provider.StepCtx) {
+			key := bytes.Repeat([]byte{7}, 32)
+			p := NewPipeline().
+				Calculate(Library).
+				Compress(Gzip).
+				Encrypt(AES, key).
+				Decrypt(AES, key).
+				Decompress(Gzip)
+			s.Require().NoError(p.Err())
+			s.Assert().Len(p.Operations(), 5)
+
+			out, err := p.Run(context.Background(), []byte("2 + 3"))
+			s.Require().NoError(err)
+			s.Assert().Equal("5", string(out))
+		})
+
+		t.WithNewStep("symmetric ciphers", func(s provider.StepCtx) {
+			for _, e := range []Encryption{ChaCha20Poly1305, XChaCha20Poly1305, AESSIV, AESCTRHMAC} {
+				path := t.TempDir() + "/key"
+				s.Require().NoError(GenerateKey(e, path), string(e))
+				key, err := os.ReadFile(path)
+				s.Require().NoError(err)
+				p := NewPipeline().Encrypt(e, key).Decrypt(e, key)
+				out, err := p.Run(context.Background(), []byte("secret"))
+				s.Require().NoError(err, string(e))
+				s.Assert().Equal("secret", string(out), string(e))
+			}
+		})
+
+		t.WithNewStep("associated data and key IDs", func(s provider.StepCtx) {
+			key := bytes.Repeat([]byte{7}, 32)
+			sealed, err := NewPipeline().EncryptWithAAD(key, []byte("a.txt")).Run(context.Background(), []byte("secret"))
+			s.Require().NoError(err)
+			out, err := NewPipeline().DecryptWithAAD(key, []byte("a.txt")).Run(context.Background(), sealed)
+			s.Require().NoError(err)
+			s.Assert().Equal("secret", string(out))
+
+			_, err = NewPipeline().DecryptWithAAD(key, []byte("b.txt")).Run(context.Background(), sealed)
+			s.Assert().ErrorIs(err, ErrCorruptedData)
+			wrong := bytes.Repeat([]byte{8}, 32)
+			_, err = NewPipeline().DecryptWithAAD(wrong, []byte("a.txt")).Run(context.Background(), sealed)
+			s.Assert().ErrorIs(err, ErrWrongKey)
+			s.Assert().Contains(err.Error(), KeyFingerprint(key))
+
+			_, err = NewPipeline().Then("encrypt", map[string]string{"type": "aes-siv", "aad": "x"}).Run(context.Background(), nil)
+			s.Require().Error(err)
+			s.Assert().Contains(err.Error(), "aad only applies to aes")
+		})
+
+		t.WithNewStep("multiple recipients", func(s provider.StepCtx) {
+			dir := t.TempDir()
+			a, b := dir+"/a", dir+"/b"
+			s.Require().NoError(GenerateKey(RSA, a))
+			s.Require().NoError(GenerateX25519Key(b))
+
+			sealed, err := NewPipeline().
+				Then("encrypt", map[string]string{"type": "rsa", "recipients": a + ".pub, " + b + ".pub"}).
+				Run(context.Background(), []byte("for both teams"))
+			s.Require().NoError(err)
+			for _, op := range []map[string]string{
+				{"type": "rsa", "key_file": a + ".priv"},
+				{"type": "multi", "key_file": b + ".priv"},
+			} {
+				out, err := NewPipeline().Then("decrypt", op).Run(context.Background(), sealed)
+				s.Require().NoError(err, op["key_file"])
+				s.Assert().Equal("for both teams", string(out))
+			}
+
+			pub, err := os.ReadFile(b + ".pub")
+			s.Require().NoError(err)
+			priv, err := os.ReadFile(b + ".priv")
+			s.Require().NoError(err)
+			sealed, err = NewPipeline().EncryptFor(pub).Run(context.Background(), []byte("in memory"))
+			s.Require().NoError(err)
+			out, err := NewPipeline().Decrypt(Multi, priv).Run(context.Background(), sealed)
+			s.Require().NoError(err)
+			s.Assert().Equal("in memory", string(out))
+
+			s.Assert().Error(NewPipeline().EncryptFor().Err())
+			_, err = NewPipeline().Then("encrypt", map[string]string{"type": "aes", "recipients": a + ".pub"}).Run(context.Background(), nil)
+			s.Assert().Error(err)
+		})
+
+		t.WithNewStep("passphrase-protected rsa keys", func(s provider.StepCtx) {
+			dir := t.TempDir()
+			key, passphrase := dir+"/locked", dir+"/passphrase.txt"
+			s.Require().NoError(os.WriteFile(passphrase, []byte("open sesame\n"), 0600))
+			s.Require().NoError(GenerateRSAKey(key, 3072, []byte("open sesame")))
+			s.Assert().Error(GenerateRSAKey(dir+"/small", 1024, nil))
+
+			sealed, err := NewPipeline().
+				Then("encrypt", map[string]string{"type": "rsa", "recipients": key + ".pub"}).
+				Run(context.Background(), []byte("locked away"))
+			s.Require().NoError(err)
+			out, err := NewPipeline().
+				Then("decrypt", map[string]string{"type": "rsa", "key_file": key + ".priv", "key_passphrase_file": passphrase}).
+				Run(context.Background(), sealed)
+			s.Require().NoError(err)
+			s.Assert().Equal("locked away", string(out))
+
+			_, err = NewPipeline().
+				Then("decrypt", map[string]string{"type": "rsa", "key_file": key + ".priv"}).
+				Run(context.Background(), sealed)
+			s.Assert().ErrorIs(err, ErrPassphraseRequired)
+			_, err = NewPipeline().
+				Then("decrypt", map[string]string{"type": "aes", "key_file": key + ".priv", "key_passphrase_file": passphrase}).
+				Run(context.Background(), sealed)
+			s.Assert().Error(err)
+		})
+
+		t.WithNewStep("ecies and rsa padding", func(s provider.StepCtx) {
+			dir := t.TempDir()
+			for _, e := range []Encryption{X25519, P256} {
+				key := dir + "/" + string(e)
+				s.Require().NoError(GenerateKey(e, key))
+				pub, err := os.ReadFile(key + ".pub")
+				s.Require().NoError(err)
+				priv, err := os.ReadFile(key + ".priv")
+				s.Require().NoError(err)
+				out, err := NewPipeline().Encrypt(e, pub).Decrypt(e, priv).Run(context.Background(), []byte("agreed"))
+				s.Require().NoError(err, e)
+				s.Assert().Equal("agreed", string(out))
+			}
+
+			key := dir + "/rsa"
+			s.Require().NoError(GenerateKey(RSA, key))
+			_, err := NewPipeline().
+				Then("encrypt", map[string]string{"type": "rsa", "key_file": key + ".pub", "padding": "pkcs1v15"}).
+				Run(context.Background(), []byte("x"))
+			s.Assert().Error(err)
+			_, err = NewPipeline().
+				Then("decrypt", map[string]string{"type": "aes", "key_file": key + ".priv", "padding": "pkcs1v15"}).
+				Run(context.Background(), []byte("x"))
+			s.Assert().Error(err)
+			_, err = NewPipeline().
+				Then("decrypt", map[string]string{"type": "rsa", "key_file": key + ".priv", "padding": "none"}).
+				Run(context.Background(), []byte("x"))
+			s.Assert().Error(err)
+		})
+
+		t.WithNewStep("age and pgp", func(s provider.StepCtx) {
+			dir := t.TempDir()
+			s.Require().NoError(GenerateKey(Age, dir+"/id"))
+			sealed, err := NewPipeline().
+				Then("encrypt", map[string]string{"type": "age", "recipients": dir + "/id.pub", "armor": "true"}).
+				Run(context.Background(), []byte("interop"))
+			s.Require().NoError(err)
+			out, err := NewPipeline().Then("decrypt", map[string]string{"type": "age", "key_file": dir + "/id"}).Run(context.Background(), sealed)
+			s.Require().NoError(err)
+			s.Assert().Equal("interop", string(out))
+
+			sealed, err = NewPipeline().EncryptWithPassphrase(PGP, []byte("pw")).Run(context.Background(), []byte("interop"))
+			s.Require().NoError(err)
+			out, err = NewPipeline().DecryptWithPassphrase(PGP, []byte("pw")).Run(context.Background(), sealed)
+			s.Require().NoError(err)
+			s.Assert().Equal("interop", string(out))
+
+			s.Assert().Error(NewPipeline().EncryptWithPassphrase(AES, []byte("pw")).Err())
+			_, err = NewPipeline().Then("decrypt", map[string]string{"type": "age", "key_file": dir + "/id", "armor": "true"}).Run(context.Background(), sealed)
+			s.Assert().Error(err)
+		})
+
+		t.WithNewStep("key material is wiped after its step", func(s provider.StepCtx) {
+			keys := &secrets{}
+			read := []byte("key from file")
+			held := keys.take(read)
+			s.Assert().Equal(make([]byte, len(read)), read)
+			s.Assert().Equal("key from file", string(held))
+
+			step := keys.wipeAfter(func(_ context.Context, data []byte) ([]byte, error) {
+				s.Assert().Equal("key from file", string(held), "the key is intact while the step runs")
+				return data, nil
+			})
+			_, err := step(context.Background(), nil)
+			s.Require().NoError(err)
+			s.Assert().Equal(make([]byte, len(held)), held)
+
+			key := bytes.Repeat([]byte{7}, 32)
+			_, err = NewPipeline().Encrypt(AES, key).Run(context.Background(), []byte("x"))
+			s.Require().NoError(err)
+			s.Assert().Equal(bytes.Repeat([]byte{7}, 32), key, "keys supplied by the caller are left alone")
+		})
+
+		t.WithNewStep("intermediate results are wiped", func(s provider.StepCtx) {
+			var seen [][]byte
+			hook := func(_ int, _ *Operation, output []byte) error {
+				seen = append(seen, output)
+				return nil
+			}
+			input := []byte("1 + 1")
+			out, err := NewPipeline(WithStepHook(hook)).
+				Then("compress", map[string]string{"type": "gzip"}).
+				Then("decompress", map[string]string{"type": "gzip"}).
+				Then("calculate", map[string]string{"type": "library"}).
+				Run(context.Background(), input)
+			s.Require().NoError(err)
+			s.Assert().Equal("2", string(out))
+			s.Assert().Equal("1 + 1", string(input), "the caller's input is left alone")
+			s.Require().Len(seen, 3)
+			s.Assert().Equal(make([]byte, len(seen[0])), seen[0])
+			s.Assert().Equal(make([]byte, len(seen[1])), seen[1])
+		})
+
+		t.WithNewStep("keys from a key store are not wiped", func(s provider.StepCtx) {
+			store := mapKeyStore{"team": bytes.Repeat([]byte{9}, 32)}
+			p := NewPipeline(WithKeyStore(store)).
+				Then("encrypt", map[string]string{"key": "team"}).
+				Then("decrypt", map[string]string{"key": "team"})
+			for run := 1; run <= 2; run++ {
+				out, err := p.Run(context.Background(), []byte("twice"))
+				s.Require().NoError(err, "run %d", run)
+				s.Assert().Equal("twice", string(out))
+			}
+			s.Assert().Equal(bytes.Repeat([]byte{9}, 32), store["team"])
+		})
+
+		t.WithNewStep("invalid algorithm", func(s provider.StepCtx) {
+			p := NewPipeline().Compress(Compression("lzma"))
+			s.Assert().Error(p.Err())
+			_, err := p.Run(context.Background(), []byte("data"))
+			s.Assert().Error(err)
+		})
+
+		t.WithNewStep("cancelled context", func(s provider.StepCtx) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := NewPipeline().Compress(Zip).Run(ctx, []byte("data"))
+			s.Assert().ErrorIs(err, context.Canceled)
+		})
+
+		t.WithNewStep("default params and compression level", func(s provider.StepCtx) {
+			p := NewPipeline(WithDefaultParams("calculate", map[string]string{"type": "library"})).
+				Then("calculate", nil)
+			out, err := p.Run(context.Background(), []byte("2 * 3"))
+			s.Require().NoError(err)
+			s.Assert().Equal("6", string(out))
+			_, err = NewPipeline(WithDefaultParams("calculate", map[string]string{"type": "library"})).
+				Then("calculate", map[string]string{"type": "abacus"}).
+				Run(context.Background(), []byte("2 * 3"))
+			s.Assert().Error(err, "explicit params win over defaults")
+
+			var text strings.Builder
+			for i := range 5000 {
+				fmt.Fprintf(&text, "line %d: %d\n", i, i*i%997)
+			}
+			data := []byte(text.String())
+			fast, err := NewPipeline().Then("compress", map[string]string{"type": "gzip", "level": "1"}).Run(context.Background(), data)
+			s.Require().NoError(err)
+			best, err := NewPipeline(WithDefaultParams("compress", map[string]string{"level": "9"})).Compress(Gzip).Run(context.Background(), data)
+			s.Require().NoError(err)
+			s.Assert().Less(len(best), len(fast))
+			out, err = NewPipeline().Decompress(Gzip).Run(context.Background(), best)
+			s.Require().NoError(err)
+			s.Assert().Equal(data, out)
+
+			_, err = NewPipeline().Then("compress", map[string]string{"type": "zip", "level": "10"}).Run(context.Background(), data)
+			s.Require().Error(err)
+			s.Assert().Contains(err.Error(), "invalid level")
+		})
+
+		t.WithNewStep("step timeout", func(s provider.StepCtx) {
+			data := []byte(strings.Repeat("1 + 1\n", 100000))
+			p := NewPipeline().Then("calculate", map[string]string{"type": "parser", "timeout": "1ns"})
+			_, err := p.Run(context.Background(), data)
+			s.Assert().ErrorIs(err, context.DeadlineExceeded)
+			s.Assert().Contains(err.Error(), "timed out")
+
+			_, err = NewPipeline(WithStepTimeout(time.Nanosecond)).Calculate(Parser).Run(context.Background(), data)
+			s.Assert().ErrorIs(err, context.DeadlineExceeded)
+
+			_, err = NewPipeline().Then("compress", map[string]string{"type": "gzip", "timeout": "soon"}).Run(context.Background(), data)
+			s.Require().Error(err)
+			s.Assert().Contains(err.Error(), "invalid timeout")
+		})
+	})
+}
+
+func TestPipeline_Process(t *testing.T) {
+	runner.Run(t, "Pipeline Process", func(t provider.T) {
+		t.WithNewStep("formatted stream output", func(s provider.StepCtx) {
+			p := NewPipeline(WithOutputFormat(JSON), WithSortedKeys())
+			var out bytes.Buffer
+			s.Require().NoError(p.Process(context.Background(), strings.NewReader(`{"b":1,"a":2}`), &out))
+			s.Assert().Equal(`{"a":2,"b":1}`, out.String())
+		})
+	})
+}
+
+// mapKeyStore is an in-memory KeyStore of AES keys.
+type mapKeyStore map[string][]byte
+
+func (m mapKeyStore) LoadKey(name string, public bool) (string, []byte, error) {
+	key, ok := m[name]
+	if !ok {
+		return "", nil, fmt.Errorf("no key %s", name)
+	}
+	return string(AES), key, nil
+}
@@ -0,0 +1,52 @@
+package fileprocessing
+
+import (
+	"context"
+
+	"github.com/dzibukalexander/file-processing/internal/secmem"
+)
+
+// secrets collects the keys and passphrases a step uses, kept in locked
+// memory and wiped as soon as the step has run. Keys supplied in memory
+// through the fluent builder or by a caller's KeyStore belong to the caller
+// and are copied, never wiped.
+type secrets struct {
+	buffers []*secmem.Buffer
+}
+
+// hold copies data into locked memory and returns the locked copy. data is
+// left as it is.
+func (s *secrets) hold(data []byte) []byte {
+	if s == nil {
+		return data
+	}
+	b := secmem.Copy(data)
+	s.buffers = append(s.buffers, b)
+	return b.Bytes()
+}
+
+// take moves data this package read itself into locked memory, wiping the
+// original, and returns the locked copy.
+func (s *secrets) take(data []byte) []byte {
+	if s == nil {
+		return data
+	}
+	held := s.hold(data)
+	secmem.Wipe(data)
+	return held
+}
+
+func (s *secrets) destroy() {
+	for _, b := range s.buffers {
+		b.Destroy()
+	}
+	s.buffers = nil
+}
+
+// wipeAfter returns a step that runs next and then destroys the secrets.
+func (s *secrets) wipeAfter(next step) step {
+	return func(ctx context.Context, data []byte) ([]byte, error) {
+		defer s.destroy()
+		return next(ctx, data)
+	}
+}
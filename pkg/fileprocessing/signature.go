@@ -0,0 +1,88 @@
+package fileprocessing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dzibukalexander/file-processing/internal/logger"
+	"github.com/dzibukalexander/file-processing/internal/signature"
+	sig_const "github.com/dzibukalexander/file-processing/internal/signature/constants"
+)
+
+// ErrInvalidSignature is returned by verify-signature steps when the
+// signature does not match the data and key.
+var ErrInvalidSignature = sig_const.ErrInvalidSignature
+
+// signStep signs the data with the private key in key_file. With a
+// signature=<path> param the raw signature is written to that file and the
+// data passes on unchanged; otherwise the signature is attached in front of
+// the data.
+func signStep(op *Operation) (step, error) {
+	sigType, err := sig_const.SignatureTypeFromString(strings.ToUpper(op.Params["type"]))
+	if err != nil || sigType == sig_const.NONE {
+		return nil, fmt.Errorf("unsupported signature type: %s", op.Params["type"])
+	}
+	key, err := op.readKey()
+	if err != nil {
+		return nil, err
+	}
+	detached := op.Params["signature"]
+	signer := signature.NewSigner(sigType)
+
+	return func(ctx context.Context, data []byte) ([]byte, error) {
+		sig, err := signer.Sign(ctx, data, key)
+		if err != nil {
+			return nil, err
+		}
+		if detached == "" {
+			return signature.Attach(sigType, sig, data)
+		}
+		if err := os.WriteFile(detached, sig, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write signature: %w", err)
+		}
+		logger.For("fileprocessing").WithField("path", detached).Info("Detached signature written")
+		return data, nil
+	}, nil
+}
+
+// verifySignatureStep checks the data against the signature file named by
+// signature=<path>, or against the signature attached by a sign step, which
+// is removed. key_file holds the signer's public key.
+func verifySignatureStep(op *Operation) (step, error) {
+	sigType, err := sig_const.SignatureTypeFromString(strings.ToUpper(op.Params["type"]))
+	if err != nil || sigType == sig_const.NONE {
+		return nil, fmt.Errorf("unsupported signature type: %s", op.Params["type"])
+	}
+	key, err := op.readKey()
+	if err != nil {
+		return nil, err
+	}
+	detached := op.Params["signature"]
+	verifier := signature.NewVerifier(sigType)
+
+	return func(ctx context.Context, data []byte) ([]byte, error) {
+		payload := data
+		var sig []byte
+		if detached != "" {
+			var err error
+			if sig, err = os.ReadFile(detached); err != nil {
+				return nil, fmt.Errorf("failed to read signature: %w", err)
+			}
+		} else {
+			attachedType, attached, rest, err := signature.Detach(data)
+			if err != nil {
+				return nil, err
+			}
+			if attachedType != sigType {
+				return nil, fmt.Errorf("data is signed with %s, not %s", strings.ToLower(string(attachedType)), op.Params["type"])
+			}
+			sig, payload = attached, rest
+		}
+		if err := verifier.Verify(ctx, payload, sig, key); err != nil {
+			return nil, err
+		}
+		return payload, nil
+	}, nil
+}
@@ -0,0 +1,67 @@
+package fileprocessing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+)
+
+func TestPipeline_Signatures(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "signer")
+	if err := GenerateSigningKey(Ed25519, keyPath); err != nil {
+		t.Fatal(err)
+	}
+
+	runner.Run(t, "Sign and verify-signature steps", func(t provider.T) {
+		ctx := context.Background()
+
+		t.WithNewStep("attached signature", func(s provider.StepCtx) {
+			priv, err := os.ReadFile(keyPath + ".priv")
+			s.Require().NoError(err)
+			pub, err := os.ReadFile(keyPath + ".pub")
+			s.Require().NoError(err)
+
+			signed, err := NewPipeline().Compress(Gzip).Sign(Ed25519, priv).Run(ctx, []byte("data"))
+			s.Require().NoError(err)
+			out, err := NewPipeline().VerifySignature(Ed25519, pub).Decompress(Gzip).Run(ctx, signed)
+			s.Require().NoError(err)
+			s.Assert().Equal("data", string(out))
+
+			signed[len(signed)-1] ^= 1
+			_, err = NewPipeline().VerifySignature(Ed25519, pub).Run(ctx, signed)
+			s.Assert().ErrorIs(err, ErrInvalidSignature)
+			_, err = NewPipeline().VerifySignature(ECDSA, pub).Run(ctx, signed)
+			s.Assert().Error(err)
+		})
+
+		t.WithNewStep("detached signature", func(s provider.StepCtx) {
+			sigPath := filepath.Join(dir, "data.sig")
+			out, err := NewPipeline().Then("sign", map[string]string{
+				"type": "ed25519", "key_file": keyPath + ".priv", "signature": sigPath,
+			}).Run(ctx, []byte("data"))
+			s.Require().NoError(err)
+			s.Assert().Equal("data", string(out))
+
+			verify := NewPipeline().Then("verify-signature", map[string]string{
+				"type": "ed25519", "key_file": keyPath + ".pub", "signature": sigPath,
+			})
+			out, err = verify.Run(ctx, []byte("data"))
+			s.Require().NoError(err)
+			s.Assert().Equal("data", string(out))
+			_, err = verify.Run(ctx, []byte("other"))
+			s.Assert().ErrorIs(err, ErrInvalidSignature)
+		})
+
+		t.WithNewStep("invalid params", func(s provider.StepCtx) {
+			s.Assert().Error(NewPipeline().Sign(Signature("dsa"), nil).Err())
+			s.Assert().Error(GenerateSigningKey(Signature("dsa"), keyPath))
+			_, err := NewPipeline().Then("sign", map[string]string{"type": "ed25519", "key_file": filepath.Join(dir, "missing")}).Run(ctx, []byte("data"))
+			s.Assert().Error(err)
+		})
+	})
+}
@@ -0,0 +1,277 @@
+package fileprocessing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dzibukalexander/file-processing/internal/calculation"
+	calc_const "github.com/dzibukalexander/file-processing/internal/calculation/constants"
+	"github.com/dzibukalexander/file-processing/internal/compression"
+	comp_const "github.com/dzibukalexander/file-processing/internal/compression/constants"
+	"github.com/dzibukalexander/file-processing/internal/encryption"
+	enc_const "github.com/dzibukalexander/file-processing/internal/encryption/constants"
+	"github.com/dzibukalexander/file-processing/internal/encryption/multi"
+)
+
+// step transforms the output of the previous step.
+type step func(ctx context.Context, data []byte) ([]byte, error)
+
+// createStep resolves an operation into the function that performs it. The
+// step is run once: key material it reads is wiped afterwards.
+func (p *Pipeline) createStep(op *Operation) (step, error) {
+	keys := &secrets{}
+	op, err := p.resolveKey(p.withDefaults(op), keys)
+	if err != nil {
+		return nil, err
+	}
+	tracked := *op
+	tracked.secrets = keys
+	s, err := p.buildStep(&tracked)
+	if err != nil {
+		keys.destroy()
+		return nil, err
+	}
+	return keys.wipeAfter(s), nil
+}
+
+// buildStep creates the step for op.
+func (p *Pipeline) buildStep(op *Operation) (step, error) {
+	params := op.Params
+	switch op.Name {
+	case "if":
+		return p.ifStep(op)
+
+	case "try":
+		return p.tryStep(op)
+
+	case "tee":
+		return p.teeStep(op)
+
+	case "include", "use":
+		return p.includeStep(op)
+
+	case "hash":
+		return hashStep(op)
+
+	case "verify":
+		return verifyStep(op)
+
+	case "sign":
+		return signStep(op)
+
+	case "verify-signature":
+		return verifySignatureStep(op)
+
+	case "compress":
+		compType, err := comp_const.CompressionTypeFromString(strings.ToUpper(params["type"]))
+		if err != nil {
+			return nil, err
+		}
+		level := 0
+		if v, ok := params["level"]; ok {
+			level, err = strconv.Atoi(v)
+			if err != nil || level < comp_const.MinLevel || level > comp_const.MaxLevel {
+				return nil, fmt.Errorf("invalid level: %s (want %d to %d)", v, comp_const.MinLevel, comp_const.MaxLevel)
+			}
+		}
+		compressor := compression.NewCompressorWithLevel(compType, level)
+		return compressor.Compress, nil
+
+	case "decompress":
+		compType, err := comp_const.CompressionTypeFromString(strings.ToUpper(params["type"]))
+		if err != nil {
+			return nil, err
+		}
+		decompressor := compression.NewDecompressor(compType)
+		return decompressor.Decompress, nil
+
+	case "encrypt":
+		typ := params["type"]
+		if _, ok := params["recipients"]; ok && !isInteropType(typ) {
+			// "type=rsa recipients=..." is accepted as well as type=multi.
+			if typ == "" || strings.EqualFold(typ, string(RSA)) {
+				typ = string(Multi)
+			}
+			if !strings.EqualFold(typ, string(Multi)) {
+				return nil, fmt.Errorf("recipients only applies to %s, %s and %s", Multi, Age, PGP)
+			}
+		}
+		encType, err := enc_const.EncryptionTypeFromString(strings.ToUpper(typ))
+		if err != nil {
+			return nil, err
+		}
+		if encType == enc_const.AGE || encType == enc_const.PGP {
+			return interopStep(op, encType, false)
+		}
+		if _, ok := params["padding"]; ok {
+			return nil, fmt.Errorf("padding only applies to decrypt; %s always encrypts with OAEP", RSA)
+		}
+		encryptor := encryption.NewEncryptor(encType)
+		if size, ok := params["chunk_size"]; ok {
+			if encType != enc_const.AESGCMSTREAM {
+				return nil, fmt.Errorf("chunk_size only applies to %s", AESGCMStream)
+			}
+			chunkSize, err := strconv.Atoi(size)
+			if err != nil || chunkSize < 1 {
+				return nil, fmt.Errorf("invalid chunk_size: %s", size)
+			}
+			encryptor = encryption.NewStreamEncryptor(chunkSize)
+		}
+		if aad, ok := params["aad"]; ok {
+			if encType != enc_const.AES {
+				return nil, fmt.Errorf("aad only applies to %s", AES)
+			}
+			encryptor = encryption.NewAESEncryptor([]byte(aad))
+		}
+		var key []byte
+		if recipients, ok := params["recipients"]; ok {
+			key, err = readRecipients(recipients)
+		} else {
+			key, err = op.readKey()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, data []byte) ([]byte, error) {
+			return encryptor.Encrypt(ctx, data, key)
+		}, nil
+
+	case "decrypt":
+		encType, err := enc_const.EncryptionTypeFromString(strings.ToUpper(params["type"]))
+		if err != nil {
+			return nil, err
+		}
+		if encType == enc_const.AGE || encType == enc_const.PGP {
+			return interopStep(op, encType, true)
+		}
+		decryptor := encryption.NewDecryptor(encType)
+		if aad, ok := params["aad"]; ok {
+			if encType != enc_const.AES {
+				return nil, fmt.Errorf("aad only applies to %s", AES)
+			}
+			decryptor = encryption.NewAESDecryptor([]byte(aad))
+		}
+		keyPassphrase, err := op.readKeyPassphrase(encType)
+		if err != nil {
+			return nil, err
+		}
+		legacyPadding, err := rsaPadding(params, encType)
+		if err != nil {
+			return nil, err
+		}
+		switch encType {
+		case enc_const.RSA:
+			decryptor = encryption.NewRSADecryptor(keyPassphrase, legacyPadding)
+		case enc_const.MULTI:
+			decryptor = encryption.NewMultiDecryptor(keyPassphrase)
+		case enc_const.X25519, enc_const.P256:
+			decryptor = encryption.NewECIESDecryptor(encType, keyPassphrase)
+		}
+		key, err := op.readKey()
+		if err != nil {
+			return nil, err
+		}
+		multiDecryptor := encryption.NewMultiDecryptor(keyPassphrase)
+		return func(ctx context.Context, data []byte) ([]byte, error) {
+			// Data encrypted with "type=rsa recipients=..." decrypts with
+			// type=rsa too, and X25519 keys used to encrypt in the
+			// multi-recipient format, so those files still open with
+			// type=x25519.
+			if (encType == enc_const.RSA || encType == enc_const.X25519) && multi.IsMultiRecipient(data) {
+				return multiDecryptor.Decrypt(ctx, data, key)
+			}
+			return decryptor.Decrypt(ctx, data, key)
+		}, nil
+
+	case "calculate":
+		calcMethod, err := calc_const.CalculationMethodFromString(strings.ToUpper(params["type"]))
+		if err != nil {
+			return nil, err
+		}
+		calculator := calculation.NewCalculator(calcMethod)
+		return func(ctx context.Context, data []byte) ([]byte, error) {
+			res, err := calculator.Calculate(ctx, string(data))
+			return []byte(res), err
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown operation: %s", op.Name)
+	}
+}
+
+// readRecipients reads the comma-separated list of public key files in a
+// recipients param into one PEM bundle.
+func readRecipients(list string) ([]byte, error) {
+	var bundle [][]byte
+	for _, path := range strings.Split(list, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recipient key: %w", err)
+		}
+		bundle = append(bundle, key)
+	}
+	if len(bundle) == 0 {
+		return nil, fmt.Errorf("recipients requires at least one public key file")
+	}
+	return bytes.Join(bundle, []byte("\n")), nil
+}
+
+// readKeyPassphrase reads the key_passphrase_file param, which unlocks an
+// encrypted private key when decrypting with encType.
+func (op *Operation) readKeyPassphrase(encType enc_const.EncryptionType) ([]byte, error) {
+	path, ok := op.Params["key_passphrase_file"]
+	if !ok {
+		return nil, nil
+	}
+	switch encType {
+	case enc_const.RSA, enc_const.MULTI, enc_const.X25519, enc_const.P256, enc_const.PGP:
+	default:
+		return nil, fmt.Errorf("key_passphrase_file only applies to decrypt with %s, %s, %s, %s or %s keys", RSA, Multi, X25519, P256, PGP)
+	}
+	passphrase, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key passphrase file: %w", err)
+	}
+	return op.secrets.take(passphrase), nil
+}
+
+// rsaPadding reads the padding param of an RSA decrypt step and reports
+// whether it selects legacy PKCS#1 v1.5 padding instead of OAEP.
+func rsaPadding(params map[string]string, encType enc_const.EncryptionType) (bool, error) {
+	padding, ok := params["padding"]
+	if !ok {
+		return false, nil
+	}
+	if encType != enc_const.RSA {
+		return false, fmt.Errorf("padding only applies to %s", RSA)
+	}
+	switch strings.ToLower(padding) {
+	case "oaep":
+		return false, nil
+	case "pkcs1v15":
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid padding: %s (use oaep or pkcs1v15)", padding)
+	}
+}
+
+// readKey returns the in-memory key if one was supplied, otherwise the
+// contents of the key_file param, held until the step has run.
+func (op *Operation) readKey() ([]byte, error) {
+	if op.key != nil {
+		return op.key, nil
+	}
+	key, err := os.ReadFile(op.Params["key_file"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	return op.secrets.take(key), nil
+}
@@ -0,0 +1,91 @@
+package fileprocessing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/dzibukalexander/file-processing/internal/ctxio"
+	"github.com/dzibukalexander/file-processing/internal/encryption/stream"
+	"github.com/dzibukalexander/file-processing/internal/logger"
+)
+
+var (
+	// ErrStreamTruncated is returned when an aes-gcm-stream ciphertext ends
+	// before its final chunk.
+	ErrStreamTruncated = stream.ErrTruncated
+	// ErrStreamCorrupted is returned when a chunk of an aes-gcm-stream
+	// ciphertext fails authentication.
+	ErrStreamCorrupted = stream.ErrCorrupted
+)
+
+// EncryptStream encrypts r into w in the aes-gcm-stream format without
+// holding more than one chunk in memory. A chunkSize of 0 uses 64 KiB.
+func EncryptStream(ctx context.Context, w io.Writer, r io.Reader, key []byte, chunkSize int) error {
+	sw, err := stream.NewWriter(w, key, chunkSize)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(sw, ctxio.NewReader(ctx, r)); err != nil {
+		return err
+	}
+	return sw.Close()
+}
+
+// DecryptStream decrypts an aes-gcm-stream ciphertext from r into w. Data is
+// only written once its chunk is authenticated, but if an error is returned
+// w may already hold the plaintext of earlier chunks.
+func DecryptStream(ctx context.Context, w io.Writer, r io.Reader, key []byte) error {
+	sr, err := stream.NewReader(r, key)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, ctxio.NewReader(ctx, sr))
+	return err
+}
+
+// EncryptFile encrypts inputPath into outputPath with EncryptStream.
+func EncryptFile(ctx context.Context, inputPath, outputPath string, key []byte, chunkSize int) error {
+	return streamFile(inputPath, outputPath, func(w io.Writer, r io.Reader) error {
+		return EncryptStream(ctx, w, r, key, chunkSize)
+	})
+}
+
+// DecryptFile decrypts inputPath into outputPath with DecryptStream. The
+// output file is only created once the whole stream has been authenticated.
+func DecryptFile(ctx context.Context, inputPath, outputPath string, key []byte) error {
+	return streamFile(inputPath, outputPath, func(w io.Writer, r io.Reader) error {
+		return DecryptStream(ctx, w, r, key)
+	})
+}
+
+// streamFile runs fn from inputPath into a temporary file next to
+// outputPath and renames it into place if fn succeeds.
+func streamFile(inputPath, outputPath string, fn func(io.Writer, io.Reader) error) error {
+	in, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(outputPath), "."+filepath.Base(outputPath)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	defer os.Remove(out.Name())
+
+	if err := fn(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(out.Name(), outputPath); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	logger.For("fileprocessing").WithField("path", outputPath).Info("Stream written")
+	return nil
+}
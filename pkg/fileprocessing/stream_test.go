@@ -0,0 +1,70 @@
+package fileprocessing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+)
+
+func TestStreamEncryption(t *testing.T) {
+	runner.Run(t, "Chunked stream encryption", func(t provider.T) {
+		ctx := context.Background()
+		dir := t.TempDir()
+		keyPath := filepath.Join(dir, "stream.key")
+		if err := GenerateKey(AESGCMStream, keyPath); err != nil {
+			t.Fatal(err)
+		}
+		key, err := os.ReadFile(keyPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.WithNewStep("files", func(s provider.StepCtx) {
+			plain := bytes.Repeat([]byte("0123456789"), 20000)
+			in := filepath.Join(dir, "in.bin")
+			s.Require().NoError(os.WriteFile(in, plain, 0644))
+			enc := filepath.Join(dir, "in.bin.enc")
+			s.Require().NoError(EncryptFile(ctx, in, enc, key, 4096))
+			out := filepath.Join(dir, "out.bin")
+			s.Require().NoError(DecryptFile(ctx, enc, out, key))
+			got, err := os.ReadFile(out)
+			s.Require().NoError(err)
+			s.Assert().Equal(plain, got)
+
+			sealed, err := os.ReadFile(enc)
+			s.Require().NoError(err)
+			// Drop the final chunk, which holds the last 200000 % 4096 bytes.
+			s.Require().NoError(os.WriteFile(enc, sealed[:len(sealed)-len(plain)%4096-16], 0644))
+			missing := filepath.Join(dir, "missing.bin")
+			err = DecryptFile(ctx, enc, missing, key)
+			s.Assert().True(errors.Is(err, ErrStreamTruncated), "%v", err)
+			_, err = os.Stat(missing)
+			s.Assert().True(os.IsNotExist(err), "no output for a damaged stream")
+		})
+
+		t.WithNewStep("pipeline steps and chunk_size", func(s provider.StepCtx) {
+			plain := bytes.Repeat([]byte("a"), 1000)
+			sealed, err := NewPipeline().
+				Then("encrypt", map[string]string{"type": "aes-gcm-stream", "key_file": keyPath, "chunk_size": "100"}).
+				Run(ctx, plain)
+			s.Require().NoError(err)
+			s.Assert().Len(sealed, 16+1000+10*16)
+
+			var out bytes.Buffer
+			s.Require().NoError(DecryptStream(ctx, &out, bytes.NewReader(sealed), key))
+			s.Assert().Equal(plain, out.Bytes())
+			opened, err := NewPipeline().Decrypt(AESGCMStream, key).Run(ctx, sealed)
+			s.Require().NoError(err)
+			s.Assert().Equal(plain, opened)
+
+			_, err = NewPipeline().Then("encrypt", map[string]string{"type": "aes", "key_file": keyPath, "chunk_size": "100"}).Run(ctx, plain)
+			s.Assert().Error(err)
+		})
+	})
+}
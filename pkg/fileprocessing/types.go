@@ -0,0 +1,117 @@
+package fileprocessing
+
+import "fmt"
+
+// Compression selects a compression algorithm.
+type Compression string
+
+const (
+	Gzip Compression = "gzip"
+	Zip  Compression = "zip"
+)
+
+// Encryption selects an encryption algorithm.
+type Encryption string
+
+const (
+	AES Encryption = "aes"
+	// RSA encrypts with OAEP (SHA-256). Data from older versions, which
+	// used PKCS#1 v1.5, decrypts with the padding=pkcs1v15 param.
+	RSA Encryption = "rsa"
+	// ChaCha20Poly1305 suits hosts without AES hardware support.
+	ChaCha20Poly1305 Encryption = "chacha20-poly1305"
+	// XChaCha20Poly1305 uses extended nonces that are safe to pick at random.
+	XChaCha20Poly1305 Encryption = "xchacha20-poly1305"
+	// AESSIV is deterministic: equal plaintexts give equal ciphertexts.
+	AESSIV Encryption = "aes-siv"
+	// AESCTRHMAC is AES-256-CTR authenticated with HMAC-SHA256.
+	AESCTRHMAC Encryption = "aes-ctr-hmac"
+	// AESGCMStream seals fixed-size chunks with AES-GCM so large inputs can
+	// be processed incrementally; see EncryptStream.
+	AESGCMStream Encryption = "aes-gcm-stream"
+	// Multi encrypts once for several RSA or X25519 public keys, any of
+	// whose private keys can decrypt.
+	Multi Encryption = "multi"
+	// Age writes files that open with the age tool, for X25519 recipients
+	// or a passphrase.
+	Age Encryption = "age"
+	// PGP writes OpenPGP messages that open with gpg, for public keys or a
+	// passphrase.
+	PGP Encryption = "pgp"
+	// X25519 encrypts to an X25519 public key, ECIES style: ephemeral ECDH,
+	// HKDF-SHA256 and ChaCha20-Poly1305.
+	X25519 Encryption = "x25519"
+	// P256 is like X25519 with NIST P-256 keys, which may be ECDSA keys.
+	P256 Encryption = "p256"
+)
+
+// Signature selects a digital signature algorithm.
+type Signature string
+
+const (
+	Ed25519 Signature = "ed25519"
+	// ECDSA uses P-256 keys and SHA-256.
+	ECDSA Signature = "ecdsa"
+	// RSAPSS uses RSA keys and SHA-256.
+	RSAPSS Signature = "rsa-pss"
+)
+
+// CalculationMethod selects how arithmetic expressions are evaluated.
+type CalculationMethod string
+
+const (
+	Regex   CalculationMethod = "regex"
+	Parser  CalculationMethod = "parser"
+	Library CalculationMethod = "library"
+)
+
+// HashAlgorithm selects the digest used by hash and verify steps.
+type HashAlgorithm string
+
+const (
+	SHA256  HashAlgorithm = "sha256"
+	SHA512  HashAlgorithm = "sha512"
+	BLAKE2b HashAlgorithm = "blake2b"
+	CRC32   HashAlgorithm = "crc32"
+)
+
+// Format selects how output written to a stream is formatted.
+type Format string
+
+const (
+	Text Format = "text"
+	JSON Format = "json"
+	XML  Format = "xml"
+	YAML Format = "yaml"
+	HTML Format = "html"
+)
+
+// ParseHashAlgorithm converts a user-supplied algorithm name into a HashAlgorithm.
+func ParseHashAlgorithm(s string) (HashAlgorithm, error) {
+	switch h := HashAlgorithm(s); h {
+	case SHA256, SHA512, BLAKE2b, CRC32:
+		return h, nil
+	default:
+		return "", fmt.Errorf("unsupported hash algorithm: %s", s)
+	}
+}
+
+// ParseSignature converts a user-supplied algorithm name into a Signature.
+func ParseSignature(s string) (Signature, error) {
+	switch sig := Signature(s); sig {
+	case Ed25519, ECDSA, RSAPSS:
+		return sig, nil
+	default:
+		return "", fmt.Errorf("unsupported signature algorithm: %s", s)
+	}
+}
+
+// ParseEncryption converts a user-supplied algorithm name into an Encryption.
+func ParseEncryption(s string) (Encryption, error) {
+	switch e := Encryption(s); e {
+	case AES, RSA, ChaCha20Poly1305, XChaCha20Poly1305, AESSIV, AESCTRHMAC, AESGCMStream, Multi, Age, PGP, X25519, P256:
+		return e, nil
+	default:
+		return "", fmt.Errorf("unsupported encryption algorithm: %s", s)
+	}
+}
@@ -0,0 +1,135 @@
+package fileprocessing
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Variable declares an input of a pipeline template. Operation params refer
+// to it as ${NAME} or ${NAME:-fallback}.
+type Variable struct {
+	Name        string  `json:"name" yaml:"name"`
+	Description string  `json:"description,omitempty" yaml:"description,omitempty"`
+	Default     *string `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// variablePattern matches $$ (a literal $) and ${NAME} or ${NAME:-fallback}.
+var variablePattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// Resolve substitutes variables in every operation param. Values are looked
+// up in values first, then through lookupEnv (which may be nil), then in the
+// variable's declared default and finally the inline fallback. All variables
+// that cannot be resolved are reported together. Params of documents read
+// from schema version 1 or earlier, which predate variables, resolve to the
+// values they were written with.
+func (d *Document) Resolve(values map[string]string, lookupEnv func(string) (string, bool)) error {
+	declared := make(map[string]Variable, len(d.Variables))
+	for _, v := range d.Variables {
+		declared[v.Name] = v
+	}
+
+	missing := map[string]bool{}
+	lookup := func(name, fallback string, hasFallback bool) (string, bool) {
+		if v, ok := values[name]; ok {
+			return v, true
+		}
+		if lookupEnv != nil {
+			if v, ok := lookupEnv(name); ok {
+				return v, true
+			}
+		}
+		if v, ok := declared[name]; ok && v.Default != nil {
+			return *v.Default, true
+		}
+		if hasFallback {
+			return fallback, true
+		}
+		missing[name] = true
+		return "", false
+	}
+
+	for _, op := range allOperations(d.Operations) {
+		for key, value := range op.Params {
+			op.Params[key] = variablePattern.ReplaceAllStringFunc(value, func(ref string) string {
+				if ref == "$$" {
+					return "$"
+				}
+				m := variablePattern.FindStringSubmatch(ref)
+				resolved, ok := lookup(m[1], m[3], m[2] != "")
+				if !ok {
+					return ref
+				}
+				return resolved
+			})
+		}
+	}
+
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unresolved pipeline variables: %s", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// ReadVarsFile loads variable values from a JSON or YAML object, or from
+// NAME=value lines for any other extension (e.g. .env).
+func ReadVarsFile(filePath string) (map[string]string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	switch {
+	case isYAMLPath(filePath):
+		err = yaml.Unmarshal(data, &values)
+	case strings.HasSuffix(strings.ToLower(filePath), ".json"):
+		err = json.Unmarshal(data, &values)
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			name, value, ok := strings.Cut(text, "=")
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: expected NAME=value", filePath, line)
+			}
+			values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+		err = scanner.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid vars file %s: %w", filePath, err)
+	}
+	return values, nil
+}
+
+// allOperations flattens ops and their nested steps into a single list. The
+// steps of expanded include and use operations belong to the included
+// pipeline and are left out.
+func allOperations(ops []*Operation) []*Operation {
+	var all []*Operation
+	for _, op := range ops {
+		all = append(all, op)
+		if op.expanded {
+			continue
+		}
+		all = append(all, allOperations(op.Steps)...)
+		all = append(all, allOperations(op.Else)...)
+	}
+	return all
+}
@@ -0,0 +1,76 @@
+package fileprocessing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/runner"
+)
+
+func TestDocument_Resolve(t *testing.T) {
+	newDoc := func() *Document {
+		def := "/keys/default.key"
+		return &Document{
+			Variables: []Variable{{Name: "KEY_FILE", Default: &def}},
+			Operations: []*Operation{
+				NewOperation("compress", map[string]string{"type": "gzip", "level": "${LEVEL:-6}"}),
+				NewOperation("encrypt", map[string]string{"type": "aes", "key_file": "${KEY_FILE}", "note": "$${KEEP}"}),
+			},
+		}
+	}
+	noEnv := func(string) (string, bool) { return "", false }
+
+	runner.Run(t, "Pipeline variables", func(t provider.T) {
+		t.WithNewStep("defaults", func(s provider.StepCtx) {
+			doc := newDoc()
+			s.Require().NoError(doc.Resolve(nil, noEnv))
+			s.Assert().Equal("6", doc.Operations[0].Params["level"])
+			s.Assert().Equal("/keys/default.key", doc.Operations[1].Params["key_file"])
+			s.Assert().Equal("${KEEP}", doc.Operations[1].Params["note"])
+		})
+
+		t.WithNewStep("precedence", func(s provider.StepCtx) {
+			doc := newDoc()
+			env := func(name string) (string, bool) {
+				if name == "KEY_FILE" || name == "LEVEL" {
+					return "from-env", true
+				}
+				return "", false
+			}
+			s.Require().NoError(doc.Resolve(map[string]string{"LEVEL": "9"}, env))
+			s.Assert().Equal("9", doc.Operations[0].Params["level"])
+			s.Assert().Equal("from-env", doc.Operations[1].Params["key_file"])
+		})
+
+		t.WithNewStep("unresolved", func(s provider.StepCtx) {
+			doc := &Document{Operations: []*Operation{
+				NewOperation("encrypt", map[string]string{"key_file": "${KEY_FILE}", "aad": "${B}-${A}"}),
+			}}
+			err := doc.Resolve(nil, noEnv)
+			s.Require().Error(err)
+			s.Assert().Equal("unresolved pipeline variables: A, B, KEY_FILE", err.Error())
+		})
+
+		t.WithNewStep("vars files", func(s provider.StepCtx) {
+			dir := t.TempDir()
+			envPath := filepath.Join(dir, "prod.env")
+			s.Require().NoError(os.WriteFile(envPath, []byte("# comment\nKEY_FILE=/prod.key\nLEVEL = 3\n"), 0644))
+			values, err := ReadVarsFile(envPath)
+			s.Require().NoError(err)
+			s.Assert().Equal(map[string]string{"KEY_FILE": "/prod.key", "LEVEL": "3"}, values)
+
+			yamlPath := filepath.Join(dir, "vars.yaml")
+			s.Require().NoError(os.WriteFile(yamlPath, []byte("KEY_FILE: /y.key\n"), 0644))
+			values, err = ReadVarsFile(yamlPath)
+			s.Require().NoError(err)
+			s.Assert().Equal("/y.key", values["KEY_FILE"])
+		})
+
+		t.WithNewStep("declarations are validated", func(s provider.StepCtx) {
+			_, err := ParseDocument([]byte(`{"schema_version":2,"variables":[{"name":"bad-name"}],"operations":[]}`), false)
+			s.Assert().Error(err)
+		})
+	})
+}